@@ -0,0 +1,148 @@
+package vcfgo
+
+import (
+	"strings"
+)
+
+// AlleleType is the kind of change an ALT allele makes to REF.
+type AlleleType int
+
+const (
+	// AlleleMissing is the missing allele, ".".
+	AlleleMissing AlleleType = iota // EnumIndex = 0
+	// AlleleRef is an ALT allele that is the same as REF.
+	AlleleRef // EnumIndex = 1
+	// AlleleSNP changes one base.
+	AlleleSNP // EnumIndex = 2
+	// AlleleMNP changes more than one base without changing the length.
+	AlleleMNP // EnumIndex = 3
+	// AlleleInsertion inserts bases.
+	AlleleInsertion // EnumIndex = 4
+	// AlleleDeletion deletes bases.
+	AlleleDeletion // EnumIndex = 5
+	// AlleleComplex changes bases and the length.
+	AlleleComplex // EnumIndex = 6
+	// AlleleSymbolic is a symbolic allele such as <DEL> or <INS:ME:ALU>.
+	AlleleSymbolic // EnumIndex = 7
+	// AlleleBreakend is a breakend, such as G]17:198982], or a single
+	// breakend, such as .G or G.
+	AlleleBreakend // EnumIndex = 8
+	// AlleleSpanningDeletion is the * allele for a deletion that spans
+	// the position.
+	AlleleSpanningDeletion // EnumIndex = 9
+	// AlleleRefBlock is the unspecified allele of gVCF reference blocks,
+	// <*> or <NON_REF>.
+	AlleleRefBlock // EnumIndex = 10
+)
+
+// String - Creating common behaviour - give the type a String function
+func (t AlleleType) String() string {
+	return [...]string{`missing`, `ref`, `snp`, `mnp`, `ins`, `del`, `complex`,
+		`symbolic`, `bnd`, `spanning_deletion`, `ref_block`}[t]
+}
+
+// IsIndel reports whether the allele type is an insertion or a deletion.
+func (t AlleleType) IsIndel() bool {
+	return t == AlleleInsertion || t == AlleleDeletion
+}
+
+// AlleleClass describes the change an ALT allele makes to REF. For a
+// symbolic allele, SVType is the ID of the allele up to the first colon,
+// such as DEL for <DEL:ME:ALU>, and Subtype is the whole ID. Length is
+// the length of ALT less the length of REF for sequence alleles, so it
+// is negative for deletions. Transition and Transversion are set for
+// SNPs of A, C, G and T.
+type AlleleClass struct {
+	Type         AlleleType
+	SVType       string
+	Subtype      string
+	Length       int
+	Transition   bool
+	Transversion bool
+}
+
+// isPurine reports whether a base is A or G.
+func isPurine(b byte) bool {
+	return b == 'A' || b == 'G'
+}
+
+// ClassifyAllele classifies the change ALT allele alt makes to ref.
+// Bases are compared ignoring case. Sequence alleles are compared after
+// removing the bases they share at the end and then at the start, so
+// REF=ACGT ALT=ACTT is a SNP and REF=CAT ALT=GATT is complex.
+func ClassifyAllele(ref, alt string) AlleleClass {
+	switch {
+	case alt == `` || alt == `.`:
+		return AlleleClass{Type: AlleleMissing}
+	case alt == `*`:
+		return AlleleClass{Type: AlleleSpanningDeletion}
+	case IsUnspecifiedAllele(alt):
+		return AlleleClass{Type: AlleleRefBlock}
+	case len(alt) > 2 && alt[0] == '<' && alt[len(alt)-1] == '>':
+		id := alt[1 : len(alt)-1]
+		svtype := id
+		if i := strings.IndexByte(id, ':'); i != -1 {
+			svtype = id[:i]
+		}
+		return AlleleClass{Type: AlleleSymbolic, SVType: svtype, Subtype: id}
+	case strings.ContainsAny(alt, `[]`) || alt[0] == '.' || alt[len(alt)-1] == '.':
+		return AlleleClass{Type: AlleleBreakend}
+	}
+
+	r, a := strings.ToUpper(ref), strings.ToUpper(alt)
+	for len(r) > 0 && len(a) > 0 && r[len(r)-1] == a[len(a)-1] {
+		r, a = r[:len(r)-1], a[:len(a)-1]
+	}
+	for len(r) > 0 && len(a) > 0 && r[0] == a[0] {
+		r, a = r[1:], a[1:]
+	}
+	c := AlleleClass{Length: len(alt) - len(ref)}
+	switch {
+	case r == `` && a == ``:
+		c.Type = AlleleRef
+	case len(r) == 1 && len(a) == 1:
+		c.Type = AlleleSNP
+		if isACGT(r) && isACGT(a) {
+			c.Transition = isPurine(r[0]) == isPurine(a[0])
+			c.Transversion = !c.Transition
+		}
+	case len(r) == len(a):
+		c.Type = AlleleMNP
+	case r == ``:
+		c.Type = AlleleInsertion
+	case a == ``:
+		c.Type = AlleleDeletion
+	default:
+		c.Type = AlleleComplex
+	}
+	return c
+}
+
+// AlleleClasses classifies each ALT allele of the Variant.
+func (v *Variant) AlleleClasses() []AlleleClass {
+	classes := make([]AlleleClass, len(v.Alternate))
+	for i, a := range v.Alternate {
+		classes[i] = ClassifyAllele(v.Reference, a)
+	}
+	return classes
+}
+
+// hasSVLength reports whether a symbolic allele of type svtype has a
+// length given by SVLEN or END.
+func hasSVLength(svtype string) bool {
+	switch svtype {
+	case `DEL`, `DUP`, `INV`, `INS`, `CNV`:
+		return true
+	}
+	return strings.HasPrefix(svtype, `CN`)
+}
+
+// IsSequenceAllele reports whether an ALT allele is made of bases: not
+// missing, symbolic, a breakend or *.
+func IsSequenceAllele(alt string) bool {
+	switch ClassifyAllele(`N`, alt).Type {
+	case AlleleMissing, AlleleSymbolic, AlleleBreakend, AlleleSpanningDeletion, AlleleRefBlock:
+		return false
+	}
+	return true
+}
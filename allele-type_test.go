@@ -0,0 +1,58 @@
+package vcfgo
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type AlleleTypeSuite struct{}
+
+var _ = Suite(&AlleleTypeSuite{})
+
+func (s *AlleleTypeSuite) TestClassify(c *C) {
+	for _, t := range []struct {
+		ref, alt string
+		want     AlleleClass
+	}{
+		{"A", "G", AlleleClass{Type: AlleleSNP, Transition: true}},
+		{"c", "T", AlleleClass{Type: AlleleSNP, Transition: true}},
+		{"A", "C", AlleleClass{Type: AlleleSNP, Transversion: true}},
+		{"A", "N", AlleleClass{Type: AlleleSNP}},
+		{"ACGT", "ACTT", AlleleClass{Type: AlleleSNP, Transversion: true}},
+		{"ACGT", "TCGA", AlleleClass{Type: AlleleMNP}},
+		{"AC", "GT", AlleleClass{Type: AlleleMNP}},
+		{"A", "ATT", AlleleClass{Type: AlleleInsertion, Length: 2}},
+		{"GAC", "GC", AlleleClass{Type: AlleleDeletion, Length: -1}},
+		{"CAT", "GATT", AlleleClass{Type: AlleleComplex, Length: 1}},
+		{"A", "a", AlleleClass{Type: AlleleRef}},
+		{"A", ".", AlleleClass{Type: AlleleMissing}},
+		{"A", "*", AlleleClass{Type: AlleleSpanningDeletion}},
+		{"A", "<*>", AlleleClass{Type: AlleleRefBlock}},
+		{"A", "<NON_REF>", AlleleClass{Type: AlleleRefBlock}},
+		{"A", "<DEL>", AlleleClass{Type: AlleleSymbolic, SVType: "DEL", Subtype: "DEL"}},
+		{"A", "<DUP:TANDEM>", AlleleClass{Type: AlleleSymbolic, SVType: "DUP", Subtype: "DUP:TANDEM"}},
+		{"A", "<INS:ME:ALU>", AlleleClass{Type: AlleleSymbolic, SVType: "INS", Subtype: "INS:ME:ALU"}},
+		{"A", "<CNV>", AlleleClass{Type: AlleleSymbolic, SVType: "CNV", Subtype: "CNV"}},
+		{"G", "G]17:198982]", AlleleClass{Type: AlleleBreakend}},
+		{"T", "[13:123457[T", AlleleClass{Type: AlleleBreakend}},
+		{"G", ".G", AlleleClass{Type: AlleleBreakend}},
+		{"G", "G.", AlleleClass{Type: AlleleBreakend}},
+	} {
+		c.Check(ClassifyAllele(t.ref, t.alt), DeepEquals, t.want, Commentf("%s/%s", t.ref, t.alt))
+	}
+}
+
+func (s *AlleleTypeSuite) TestVariant(c *C) {
+	v := &Variant{Reference: "AT", Alternate: []string{"GT", "A", "<INV>", "*"}}
+	var types []string
+	for _, cl := range v.AlleleClasses() {
+		types = append(types, cl.Type.String())
+	}
+	c.Assert(types, DeepEquals, []string{"snp", "del", "symbolic", "spanning_deletion"})
+	c.Assert(AlleleDeletion.IsIndel(), Equals, true)
+	c.Assert(AlleleComplex.IsIndel(), Equals, false)
+
+	c.Assert(IsSequenceAllele("ACG"), Equals, true)
+	for _, a := range []string{".", "*", "<DEL>", "<*>", "A[1:10[", "A."} {
+		c.Check(IsSequenceAllele(a), Equals, false, Commentf(a))
+	}
+}
@@ -0,0 +1,316 @@
+package vcfgo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TableKey says how the rows of an annotation table match Variants.
+type TableKey int
+
+const (
+	// KeyPosition tables start with CHROM and 1-based POS columns.
+	KeyPosition TableKey = iota
+	// KeyAllele tables start with CHROM, POS, REF and ALT columns. A
+	// row matches a Variant with the same REF that has the ALT.
+	KeyAllele
+	// KeyInterval tables are BED files that start with CHROM and
+	// 0-based, half-open START and END columns. A row matches the
+	// Variants whose Start() to End() span overlaps it.
+	KeyInterval
+)
+
+// A Reducer combines the values of a column from all of the rows that
+// match a Variant. Missing values, . or empty, are skipped.
+type Reducer string
+
+const (
+	// ReduceFirst takes the value of the first row in the table.
+	ReduceFirst Reducer = "first"
+	// ReduceConcat takes the values of all rows as a list.
+	ReduceConcat Reducer = "concat"
+	// ReduceMax takes the largest numeric value.
+	ReduceMax Reducer = "max"
+	// ReduceMean takes the mean of the numeric values.
+	ReduceMean Reducer = "mean"
+	// ReduceCount counts the matching rows.
+	ReduceCount Reducer = "count"
+)
+
+// ColumnField maps a column of a table to an INFO field. Column is
+// 1-based and counts the key columns. If Number, Type or Description
+// are empty, they are chosen to suit the Reducer, which defaults to
+// ReduceFirst.
+type ColumnField struct {
+	Column      int
+	Name        string
+	Number      string
+	Type        string
+	Description string
+	Reduce      Reducer
+}
+
+// ParseColumnField parses COLUMN:NAME[:NUMBER:TYPE[:REDUCER]], for
+// example 5:CURATED or 4:DEPTH_MEAN:1:Float:mean.
+func ParseColumnField(s string) (ColumnField, error) {
+	var f ColumnField
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) == 3 || len(parts) > 5 || parts[1] == "" {
+		return f, fmt.Errorf("%w - %q is not COLUMN:NAME[:NUMBER:TYPE[:REDUCER]]", ErrAnnotate, s)
+	}
+	col, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return f, fmt.Errorf("%w - bad column %q", ErrAnnotate, parts[0])
+	}
+	f.Column, f.Name = col, parts[1]
+	if len(parts) > 3 {
+		f.Number, f.Type = parts[2], parts[3]
+	}
+	if len(parts) > 4 {
+		f.Reduce = Reducer(parts[4])
+	}
+	return f, nil
+}
+
+// TableAnnotator sets INFO fields from the columns of the rows of a
+// tab-delimited table that match a Variant. The table is held in memory.
+type TableAnnotator struct {
+	Key TableKey
+
+	fields    []ColumnField
+	rows      []tableRow
+	keys      map[string][]int              // CHROM and POS to rows
+	intervals *Regions                      // KeyInterval rows
+	rowsOf    map[string]map[Interval][]int // interval to rows
+}
+
+type tableRow struct {
+	ref, alt string
+	cols     []string
+}
+
+// NewTableAnnotator reads a table, which may be gzip or BGZF compressed,
+// and adds an ##INFO line to h for each field. Blank lines and lines
+// starting with # are skipped, as are track and browser lines of BED
+// files.
+func NewTableAnnotator(r io.Reader, key TableKey, h *Header, fields ...ColumnField) (*TableAnnotator, error) {
+	a := &TableAnnotator{Key: key, keys: make(map[string][]int)}
+	nKey := map[TableKey]int{KeyPosition: 2, KeyAllele: 4, KeyInterval: 3}[key]
+	if nKey == 0 {
+		return nil, fmt.Errorf("%w - unknown table key %d", ErrAnnotate, key)
+	}
+	for _, f := range fields {
+		if f.Column < 1 {
+			return nil, fmt.Errorf("%w - bad column %d for %s", ErrAnnotate, f.Column, f.Name)
+		}
+		if f.Reduce == "" {
+			f.Reduce = ReduceFirst
+		}
+		number, typ := "1", "String"
+		switch f.Reduce {
+		case ReduceFirst:
+		case ReduceConcat:
+			number = "."
+		case ReduceMax, ReduceMean:
+			typ = "Float"
+		case ReduceCount:
+			typ = "Integer"
+		default:
+			return nil, fmt.Errorf("%w - unknown reducer %s", ErrAnnotate, f.Reduce)
+		}
+		if f.Number == "" {
+			f.Number = number
+		}
+		if f.Type == "" {
+			f.Type = typ
+		}
+		if f.Type == "Flag" {
+			f.Number = "0"
+		}
+		if f.Description == "" {
+			f.Description = fmt.Sprintf("Column %d of the annotation table", f.Column)
+		}
+		h.AddInfoLine(f.Name, f.Number, f.Type, f.Description)
+		a.fields = append(a.fields, f)
+	}
+	if key == KeyInterval {
+		a.intervals = NewRegions()
+		a.rowsOf = make(map[string]map[Interval][]int)
+	}
+
+	br, err := textReader(r)
+	if err != nil {
+		return nil, err
+	}
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 && line[0] != '#' && !(key == KeyInterval &&
+			(bytes.HasPrefix(line, []byte(`track`)) || bytes.HasPrefix(line, []byte(`browser`)))) {
+			if perr := a.addRow(strings.Split(string(line), "\t"), nKey); perr != nil {
+				return nil, fmt.Errorf("%w - line %d: %v", ErrAnnotate, n, perr)
+			}
+		}
+		if err == io.EOF {
+			return a, nil
+		}
+	}
+}
+
+func (a *TableAnnotator) addRow(cols []string, nKey int) error {
+	if len(cols) < nKey {
+		return fmt.Errorf("fewer than %d columns", nKey)
+	}
+	i := len(a.rows)
+	row := tableRow{cols: cols}
+	if a.Key == KeyInterval {
+		start, err1 := strconv.ParseUint(cols[1], 10, 32)
+		end, err2 := strconv.ParseUint(cols[2], 10, 32)
+		if err1 != nil || err2 != nil || end < start {
+			return fmt.Errorf("bad interval %s-%s", cols[1], cols[2])
+		}
+		iv := Interval{Start: uint32(start), End: uint32(end)}
+		if a.rowsOf[cols[0]] == nil {
+			a.rowsOf[cols[0]] = make(map[Interval][]int)
+		}
+		if a.rowsOf[cols[0]][iv] == nil {
+			a.intervals.Add(cols[0], iv.Start, iv.End, "")
+		}
+		a.rowsOf[cols[0]][iv] = append(a.rowsOf[cols[0]][iv], i)
+		a.rows = append(a.rows, row)
+		return nil
+	}
+	if _, err := strconv.ParseUint(cols[1], 10, 64); err != nil {
+		return fmt.Errorf("bad position %s", cols[1])
+	}
+	if a.Key == KeyAllele {
+		row.ref, row.alt = cols[2], cols[3]
+	}
+	key := cols[0] + "\t" + cols[1]
+	a.keys[key] = append(a.keys[key], i)
+	a.rows = append(a.rows, row)
+	return nil
+}
+
+// Annotate sets the fields on v from the matching rows. For KeyAllele
+// tables, Number=A fields get a value for each ALT of v. The only errors
+// are for values that cannot be represented in the VCF version of v.
+func (a *TableAnnotator) Annotate(v *Variant) error {
+	var matched []int
+	switch a.Key {
+	case KeyInterval:
+		s, e := variantSpan(v)
+		for _, iv := range a.intervals.Overlapping(v.Chromosome, s, e) {
+			matched = append(matched, a.rowsOf[v.Chromosome][iv]...)
+		}
+		sort.Ints(matched)
+	default:
+		for _, i := range a.keys[v.Chromosome+"\t"+strconv.FormatUint(v.Pos, 10)] {
+			if a.Key == KeyPosition || (a.rows[i].ref == v.Reference && hasString(v.Alternate, a.rows[i].alt)) {
+				matched = append(matched, i)
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	for _, f := range a.fields {
+		if f.Type == "Flag" {
+			if err := v.Info().Set(f.Name, true); err != nil {
+				return err
+			}
+			continue
+		}
+		var vals []string
+		if a.Key == KeyAllele && f.Number == "A" {
+			found := false
+			for _, alt := range v.Alternate {
+				var rows []int
+				for _, i := range matched {
+					if a.rows[i].alt == alt {
+						rows = append(rows, i)
+					}
+				}
+				r := a.reduce(f, rows)
+				if len(r) == 0 {
+					vals = append(vals, ".")
+					continue
+				}
+				// One value for each ALT, even for ReduceConcat.
+				found = true
+				vals = append(vals, r[0])
+			}
+			if !found {
+				continue
+			}
+		} else if vals = a.reduce(f, matched); len(vals) == 0 {
+			continue
+		}
+		if err := v.Info().Set(f.Name, vals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reduce returns the values of a field for the rows or nothing if they
+// are all missing.
+func (a *TableAnnotator) reduce(f ColumnField, rows []int) []string {
+	if f.Reduce == ReduceCount {
+		if len(rows) == 0 {
+			return nil
+		}
+		return []string{strconv.Itoa(len(rows))}
+	}
+	var vals []string
+	for _, i := range rows {
+		if cols := a.rows[i].cols; f.Column <= len(cols) && cols[f.Column-1] != "" && cols[f.Column-1] != "." {
+			vals = append(vals, cols[f.Column-1])
+		}
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	switch f.Reduce {
+	case ReduceFirst:
+		return vals[:1]
+	case ReduceMax, ReduceMean:
+		max, sum, n, maxVal := math.Inf(-1), 0.0, 0, ""
+		for _, val := range vals {
+			x, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+			if x > max {
+				max, maxVal = x, val
+			}
+			sum += x
+			n++
+		}
+		if n == 0 {
+			return nil
+		}
+		if f.Reduce == ReduceMax {
+			return []string{maxVal}
+		}
+		return []string{fmtFloat64(sum / float64(n))}
+	}
+	return vals
+}
+
+func hasString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package vcfgo
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var tableTarget = `##fileformat=VCFv4.3
+##contig=<ID=chr1>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+chr1	100	t1	A	T,G	.	PASS	.
+chr1	150	t2	C	G	.	PASS	DP=4
+chr1	300	t3	GAAAA	G	.	PASS	.
+chr1	900	t4	T	C	.	PASS	.
+`
+
+type TableSuite struct{}
+
+var _ = Suite(&TableSuite{})
+
+func tableAnnotate(c *C, table string, key TableKey, fields ...ColumnField) ([]string, *Header) {
+	rdr, err := NewReader(strings.NewReader(tableTarget), false)
+	c.Assert(err, IsNil)
+	a, err := NewTableAnnotator(strings.NewReader(table), key, rdr.Header, fields...)
+	c.Assert(err, IsNil)
+	var infos []string
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		c.Assert(a.Annotate(v), IsNil)
+		infos = append(infos, v.Info().String())
+	}
+	return infos, rdr.Header
+}
+
+func (s *TableSuite) TestAllele(c *C) {
+	table := "#CHROM\tPOS\tREF\tALT\tCLASS\tSCORE\n" +
+		"chr1\t100\tA\tG\tlikely benign; reviewed\t0.5\n" +
+		"chr1\t100\tA\tC\tpathogenic\t0.9\n" +
+		"chr1\t150\tC\tT\tbenign\t0.1\n" +
+		"chr1\t300\tGAAAA\tG\tvus\t.\n"
+	infos, h := tableAnnotate(c, table, KeyAllele,
+		ColumnField{Column: 5, Name: "CLASS"},
+		ColumnField{Column: 6, Name: "SCORE", Number: "A", Type: "Float"})
+	c.Assert(infos, DeepEquals, []string{
+		"CLASS=likely benign%3B reviewed;SCORE=.,0.5",
+		"DP=4",
+		"CLASS=vus",
+		".",
+	})
+	c.Assert(h.Infos["CLASS"].Number, Equals, "1")
+	c.Assert(h.Infos["CLASS"].Type, Equals, "String")
+	c.Assert(h.Infos["SCORE"].Description, Equals, "Column 6 of the annotation table")
+}
+
+func (s *TableSuite) TestPosition(c *C) {
+	table := "chr1\t100\tx\n\nchr1\t100\ty\nchr1\t900\tz\n"
+	infos, _ := tableAnnotate(c, table, KeyPosition,
+		ColumnField{Column: 3, Name: "ALL", Reduce: ReduceConcat},
+		ColumnField{Column: 3, Name: "N", Reduce: ReduceCount},
+		ColumnField{Column: 3, Name: "SEEN", Type: "Flag"})
+	c.Assert(infos, DeepEquals, []string{"ALL=x,y;N=2;SEEN", "DP=4", ".", "ALL=z;N=1;SEEN"})
+}
+
+func (s *TableSuite) TestInterval(c *C) {
+	bed := "track name=x\nchr1\t90\t200\texon1\t10\nchr1\t140\t160\texon2\t30\n" +
+		"chr1\t302\t303\tin_del\t7\nchr1\t140\t160\tdup\t.\n"
+	infos, h := tableAnnotate(c, bed, KeyInterval,
+		ColumnField{Column: 4, Name: "GENE", Reduce: ReduceConcat},
+		ColumnField{Column: 5, Name: "MAX", Number: "1", Type: "Integer", Reduce: ReduceMax},
+		ColumnField{Column: 5, Name: "MEAN", Reduce: ReduceMean},
+		ColumnField{Column: 4, Name: "N", Reduce: ReduceCount},
+		ColumnField{Column: 4, Name: "FIRST"},
+		ColumnField{Column: 5, Name: "TOP", Reduce: ReduceMax})
+	c.Assert(infos, DeepEquals, []string{
+		"GENE=exon1;MAX=10;MEAN=10;N=1;FIRST=exon1;TOP=10",
+		"DP=4;GENE=exon1,exon2,dup;MAX=30;MEAN=20;N=3;FIRST=exon1;TOP=30",
+		"GENE=in_del;MAX=7;MEAN=7;N=1;FIRST=in_del;TOP=7",
+		".",
+	})
+	c.Assert(h.Infos["GENE"].Number, Equals, ".")
+	c.Assert(h.Infos["MEAN"].Type, Equals, "Float")
+	c.Assert(h.Infos["TOP"].Type, Equals, "Float")
+	c.Assert(h.Infos["FIRST"].Type, Equals, "String")
+	c.Assert(h.Infos["N"].Type, Equals, "Integer")
+}
+
+func (s *TableSuite) TestErrors(c *C) {
+	h := NewHeader()
+	_, err := NewTableAnnotator(strings.NewReader("chr1\t1\n"), KeyAllele, h)
+	c.Assert(err, ErrorMatches, ".*line 1: fewer than 4 columns")
+	_, err = NewTableAnnotator(strings.NewReader("chr1\tx\n"), KeyPosition, h)
+	c.Assert(err, ErrorMatches, ".*line 1: bad position x")
+	_, err = NewTableAnnotator(strings.NewReader(""), KeyPosition, h, ColumnField{Column: 3, Name: "X", Reduce: "median"})
+	c.Assert(err, ErrorMatches, ".*unknown reducer median")
+
+	f, err := ParseColumnField("6:SCORE:A:Float:max")
+	c.Assert(err, IsNil)
+	c.Assert(f, DeepEquals, ColumnField{Column: 6, Name: "SCORE", Number: "A", Type: "Float", Reduce: ReduceMax})
+	_, err = ParseColumnField("6:SCORE:A")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,294 @@
+package vcfgo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrAnnotate is returned for an annotation that cannot be set up.
+var ErrAnnotate = errors.New("vcfgo: bad annotation")
+
+// MatchMode says which records of an annotation source match a Variant.
+type MatchMode int
+
+const (
+	// MatchAllele matches records with the same CHROM, POS and REF that
+	// share an ALT allele. Number=A and Number=R values are subset to
+	// the ALT alleles of the Variant. Both files should be normalized
+	// and may hold multiallelic records.
+	MatchAllele MatchMode = iota
+	// MatchPosition matches records with the same CHROM and POS.
+	MatchPosition
+	// MatchOverlap matches records whose Start() to End() span overlaps
+	// that of the Variant.
+	MatchOverlap
+)
+
+// AnnotationField names a source INFO field to copy and the name to give
+// it in the annotated Variants. If Name is empty, Field is used.
+type AnnotationField struct {
+	Field string
+	Name  string
+}
+
+// ParseAnnotationField parses FIELD or FIELD:NAME.
+func ParseAnnotationField(s string) AnnotationField {
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		return AnnotationField{Field: s[:i], Name: s[i+1:]}
+	}
+	return AnnotationField{Field: s}
+}
+
+// VCFAnnotator copies INFO fields from the records of a source VCF onto
+// matching Variants, in the manner of vcfanno. If the source Reader was
+// made by NewIndexedReader, the index is used to find the records for
+// each Variant. Otherwise the source and the Variants must be sorted in
+// the same order, by the ##contig lines of the target and source
+// Headers, and the source is read alongside in a merge-join.
+type VCFAnnotator struct {
+	Mode MatchMode
+
+	src     *Reader
+	fields  []annotationField
+	contigs *ContigDict
+	buf     []*Variant // merge-join records that may match
+	next    *Variant   // the next record of the source
+	eof     bool
+}
+
+type annotationField struct {
+	AnnotationField
+	number string
+	flag   bool
+}
+
+// NewVCFAnnotator returns a VCFAnnotator that adds the fields of src to
+// Variants read with the Header h. An ##INFO line is added to h for each
+// field. In MatchPosition and MatchOverlap modes the Number of A, R and
+// G fields becomes . as the values cannot be subset. ErrContigConflict
+// is returned if src and h disagree on the length or md5 of a contig, as
+// for files from different builds, and an error is returned if the
+// ##contig lines of src are malformed or repeated.
+func NewVCFAnnotator(src *Reader, h *Header, mode MatchMode, fields ...AnnotationField) (*VCFAnnotator, error) {
+	a := &VCFAnnotator{Mode: mode, src: src}
+	var err error
+	if a.contigs, err = NewContigDictFromHeader(h); err != nil {
+		return nil, err
+	}
+	sc, err := NewContigDictFromHeader(src.Header)
+	if err != nil {
+		return nil, fmt.Errorf("%w - source contigs: %v", ErrAnnotate, err)
+	}
+	if err := a.contigs.Merge(sc); err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		info, found := src.Header.Infos[f.Field]
+		if !found {
+			return nil, fmt.Errorf("%w - %s is not an INFO field of the source", ErrAnnotate, f.Field)
+		}
+		if f.Name == "" {
+			f.Name = f.Field
+		}
+		number := info.Number
+		if mode != MatchAllele && (number == "A" || number == "R" || number == "G") {
+			number = "."
+		}
+		h.AddInfoLine(f.Name, number, info.Type, info.Description)
+		a.fields = append(a.fields, annotationField{f, info.Number, info.Type == "Flag"})
+	}
+	return a, nil
+}
+
+// Annotate sets the fields on v from the matching source records. Errors
+// come from seeking in an indexed source or from values that cannot be
+// represented in the VCF version of v. Errors parsing the source are
+// collected by the source Reader.
+func (a *VCFAnnotator) Annotate(v *Variant) error {
+	cands, err := a.candidates(v)
+	if err != nil {
+		return err
+	}
+	var matches []*Variant
+	s, e := variantSpan(v)
+	for _, c := range cands {
+		switch a.Mode {
+		case MatchAllele:
+			if c.Pos != v.Pos || c.Reference != v.Reference {
+				continue
+			}
+		case MatchPosition:
+			if c.Pos != v.Pos {
+				continue
+			}
+		}
+		if cs, ce := variantSpan(c); cs < e && ce > s {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	if a.Mode == MatchAllele {
+		return a.annotateAlleles(v, matches)
+	}
+	for _, f := range a.fields {
+		for _, m := range matches {
+			vals, ok := sourceValues(m, f)
+			if !ok {
+				continue
+			}
+			if err := setAnnotation(v, f, vals); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// annotateAlleles sets the fields from the records that share an ALT
+// allele with v.
+func (a *VCFAnnotator) annotateAlleles(v *Variant, matches []*Variant) error {
+	// For each ALT of v, the record and index of the same ALT.
+	type allele struct {
+		v *Variant
+		i int
+	}
+	alleles := make([]allele, len(v.Alternate))
+	var first *Variant
+	for i, alt := range v.Alternate {
+		alleles[i].i = -1
+		for _, m := range matches {
+			for j, malt := range m.Alternate {
+				if malt == alt && alleles[i].i == -1 {
+					alleles[i] = allele{m, j}
+					if first == nil {
+						first = m
+					}
+				}
+			}
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	for _, f := range a.fields {
+		var out []string
+		switch f.number {
+		case "A", "R":
+			off := 0
+			if f.number == "R" {
+				off = 1
+				vals, ok := sourceValues(first, f)
+				if !ok || len(vals) == 0 {
+					out = append(out, ".")
+				} else {
+					out = append(out, vals[0])
+				}
+			}
+			found := false
+			for _, al := range alleles {
+				val := "."
+				if al.v != nil {
+					if vals, ok := sourceValues(al.v, f); ok && al.i+off < len(vals) {
+						val = vals[al.i+off]
+						found = found || val != "."
+					}
+				}
+				out = append(out, val)
+			}
+			if !found {
+				continue
+			}
+		case "G":
+			// Only copied when the ALT alleles are the same.
+			if len(first.Alternate) != len(v.Alternate) || strings.Join(first.Alternate, ",") != strings.Join(v.Alternate, ",") {
+				continue
+			}
+			fallthrough
+		default:
+			ok := false
+			for _, al := range alleles {
+				if al.v == nil {
+					continue
+				}
+				if out, ok = sourceValues(al.v, f); ok {
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+		}
+		if err := setAnnotation(v, f, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceValues returns the decoded values of a field of a source record
+// or false if the field is absent or missing. A Flag has no values.
+func sourceValues(v *Variant, f annotationField) ([]string, bool) {
+	info := NewInfoByte(v.Info().Bytes(), nil)
+	if f.flag {
+		// Contains only finds fields with values.
+		for _, k := range info.Keys() {
+			if k == f.Field {
+				return nil, true
+			}
+		}
+		return nil, false
+	}
+	return infoValues(v, f.Field)
+}
+
+// setAnnotation sets a field on v through InfoByte.Set, which encodes
+// the values for the VCF version of v.
+func setAnnotation(v *Variant, f annotationField, vals []string) error {
+	if f.flag {
+		return v.Info().Set(f.Name, true)
+	}
+	return v.Info().Set(f.Name, vals)
+}
+
+// candidates returns the source records that may overlap v.
+func (a *VCFAnnotator) candidates(v *Variant) ([]*Variant, error) {
+	s, e := variantSpan(v)
+	if a.src.index != nil {
+		return a.src.Fetch(v.Chromosome, s, e)
+	}
+	// Records that end before v cannot match v or any later Variant.
+	keep := a.buf[:0]
+	for _, c := range a.buf {
+		if _, ce := variantSpan(c); c.Chromosome == v.Chromosome && ce > s {
+			keep = append(keep, c)
+		}
+	}
+	a.buf = keep
+	for {
+		if a.next == nil {
+			if a.eof {
+				break
+			}
+			if a.next = a.src.Read(); a.next == nil {
+				a.eof = true
+				break
+			}
+		}
+		c := a.contigs.Compare(a.next.Chromosome, v.Chromosome)
+		if c > 0 {
+			break
+		}
+		if c == 0 {
+			if ns, _ := variantSpan(a.next); ns >= e {
+				break
+			}
+			a.buf = append(a.buf, a.next)
+		}
+		a.next = nil
+	}
+	return a.buf, nil
+}
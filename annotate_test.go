@@ -0,0 +1,144 @@
+package vcfgo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var annoSourceStr = `##fileformat=VCFv4.3
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency">
+##INFO=<ID=RC,Number=R,Type=Integer,Description="Allele counts">
+##INFO=<ID=SIG,Number=.,Type=String,Description="Significance">
+##INFO=<ID=COMMON,Number=0,Type=Flag,Description="Common">
+##contig=<ID=chr1>
+##contig=<ID=chr2>
+##contig=<ID=chr10>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+chr1	100	s1	A	C,G,T	.	PASS	AF=0.1,0.2,0.3;RC=5,1,2,3;SIG=benign%3Bmaybe;COMMON
+chr1	200	s2	AT	A	.	PASS	AF=0.5;RC=1,1
+chr2	50	s3	G	GCC	.	PASS	AF=0.01;SIG=pathogenic
+chr10	5	s4	C	T	.	PASS	AF=0.4
+`
+
+var annoTargetStr = `##fileformat=VCFv4.3
+##contig=<ID=chr1>
+##contig=<ID=chr2>
+##contig=<ID=chr10>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+chr1	100	t1	A	T,G	.	PASS	.
+chr1	100	t2	A	AG	.	PASS	.
+chr1	201	t3	T	C	.	PASS	.
+chr2	50	t4	G	GCC	.	PASS	DP=3
+chr10	5	t5	C	T	.	PASS	.
+`
+
+type AnnotateSuite struct{}
+
+var _ = Suite(&AnnotateSuite{})
+
+func annotateAll(c *C, src *Reader, mode MatchMode, fields ...AnnotationField) []string {
+	rdr, err := NewReader(strings.NewReader(annoTargetStr), false)
+	c.Assert(err, IsNil)
+	a, err := NewVCFAnnotator(src, rdr.Header, mode, fields...)
+	c.Assert(err, IsNil)
+	var infos []string
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		c.Assert(a.Annotate(v), IsNil)
+		infos = append(infos, v.Info().String())
+	}
+	return infos
+}
+
+func (s *AnnotateSuite) TestAllele(c *C) {
+	src, err := NewReader(strings.NewReader(annoSourceStr), false)
+	c.Assert(err, IsNil)
+	infos := annotateAll(c, src, MatchAllele, AnnotationField{"AF", "src_AF"},
+		AnnotationField{Field: "RC"}, AnnotationField{Field: "SIG"}, AnnotationField{Field: "COMMON"})
+	c.Assert(infos, DeepEquals, []string{
+		"src_AF=0.3,0.2;RC=5,3,2;SIG=benign%3Bmaybe;COMMON",
+		".",
+		".",
+		"DP=3;src_AF=0.01;SIG=pathogenic",
+		"src_AF=0.4",
+	})
+}
+
+func (s *AnnotateSuite) TestHeader(c *C) {
+	src, err := NewReader(strings.NewReader(annoSourceStr), false)
+	c.Assert(err, IsNil)
+	rdr, err := NewReader(strings.NewReader(annoTargetStr), false)
+	c.Assert(err, IsNil)
+	_, err = NewVCFAnnotator(src, rdr.Header, MatchOverlap, ParseAnnotationField("AF:gnomad_AF"), ParseAnnotationField("SIG"))
+	c.Assert(err, IsNil)
+	c.Assert(rdr.Header.Infos["gnomad_AF"].Number, Equals, ".")
+	c.Assert(rdr.Header.Infos["SIG"].Type, Equals, "String")
+	var buf bytes.Buffer
+	_, err = NewWriter(&buf, rdr.Header)
+	c.Assert(err, IsNil)
+	c.Assert(buf.String(), Matches, `(?s).*##INFO=<ID=gnomad_AF,Number=.,Type=Float,Description="Allele frequency">\n.*`)
+
+	_, err = NewVCFAnnotator(src, rdr.Header, MatchAllele, AnnotationField{Field: "XX"})
+	c.Assert(err, ErrorMatches, ".*XX is not an INFO field.*")
+
+	// A source from another build is refused before h is changed.
+	src, err = NewReader(strings.NewReader(strings.Replace(annoSourceStr, "<ID=chr2>", "<ID=chr2,length=243199373>", 1)), false)
+	c.Assert(err, IsNil)
+	rdr, err = NewReader(strings.NewReader(strings.Replace(annoTargetStr, "<ID=chr2>", "<ID=chr2,length=242193529>", 1)), false)
+	c.Assert(err, IsNil)
+	_, err = NewVCFAnnotator(src, rdr.Header, MatchAllele, AnnotationField{Field: "AF"})
+	c.Assert(errors.Is(err, ErrContigConflict), Equals, true)
+	_, found := rdr.Header.Infos["AF"]
+	c.Assert(found, Equals, false)
+
+	// As is a source with a repeated contig, which NewReader reports but
+	// still returns.
+	src, err = NewReader(strings.NewReader(strings.Replace(annoSourceStr, "##contig=<ID=chr2>", "##contig=<ID=chr2>\n##contig=<ID=chr2>", 1)), false)
+	c.Assert(err, NotNil)
+	rdr, err = NewReader(strings.NewReader(annoTargetStr), false)
+	c.Assert(err, IsNil)
+	_, err = NewVCFAnnotator(src, rdr.Header, MatchAllele, AnnotationField{Field: "AF"})
+	c.Assert(err, ErrorMatches, "vcfgo: .*source contigs: .*chr2.*")
+}
+
+func (s *AnnotateSuite) TestPositionAndOverlap(c *C) {
+	src, err := NewReader(strings.NewReader(annoSourceStr), false)
+	c.Assert(err, IsNil)
+	infos := annotateAll(c, src, MatchPosition, AnnotationField{Field: "AF"})
+	c.Assert(infos, DeepEquals, []string{"AF=0.1,0.2,0.3", "AF=0.1,0.2,0.3", ".", "DP=3;AF=0.01", "AF=0.4"})
+
+	src, err = NewReader(strings.NewReader(annoSourceStr), false)
+	c.Assert(err, IsNil)
+	// t3 at 201 is inside the deletion s2.
+	infos = annotateAll(c, src, MatchOverlap, AnnotationField{Field: "AF"})
+	c.Assert(infos, DeepEquals, []string{"AF=0.1,0.2,0.3", "AF=0.1,0.2,0.3", "AF=0.5", "DP=3;AF=0.01", "AF=0.4"})
+}
+
+func (s *AnnotateSuite) TestIndexed(c *C) {
+	lines := strings.SplitAfter(annoSourceStr, "\n")
+	header := strings.Join(lines[:9], "")
+	data, offsets := writeBGZF(append([]string{header}, lines[9:len(lines)-1]...))
+	recs := map[string][]tabixRec{
+		"chr1":  {{99, 100, offsets[1], offsets[2]}, {199, 201, offsets[2], offsets[3]}},
+		"chr2":  {{49, 50, offsets[3], offsets[4]}},
+		"chr10": {{4, 5, offsets[4], offsets[5]}},
+	}
+	idx, err := ReadTabix(bytes.NewReader(writeTabix([]string{"chr1", "chr2", "chr10"}, recs)))
+	c.Assert(err, IsNil)
+	src, err := NewIndexedReader(bytes.NewReader(data), idx, false)
+	c.Assert(err, IsNil)
+
+	vs, err := src.Fetch("chr1", 150, 250)
+	c.Assert(err, IsNil)
+	c.Assert(variantIds(vs), Equals, "s2")
+
+	infos := annotateAll(c, src, MatchAllele, AnnotationField{Field: "AF"})
+	c.Assert(infos, DeepEquals, []string{"AF=0.3,0.2", ".", ".", "DP=3;AF=0.01", "AF=0.4"})
+
+	rdr, err := NewReader(strings.NewReader(regionStr), false)
+	c.Assert(err, IsNil)
+	_, err = rdr.Fetch("chr1", 0, 10)
+	c.Assert(err, Equals, ErrNoIndex)
+}
@@ -0,0 +1,112 @@
+package vcfgo
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrBGZF is returned for data that is not valid BGZF.
+var ErrBGZF = errors.New("vcfgo: bad BGZF block")
+
+// BGZF is the blocked gzip format used for indexed VCF and FASTA files.
+// Each block is a gzip member of at most 64KiB whose extra field holds
+// the size of the block. A position in the uncompressed data is a
+// virtual offset: the file offset of the start of a block shifted left
+// 16 bits plus the offset within the uncompressed block.
+
+// bgzfReader reads BGZF data and can seek to a virtual offset.
+type bgzfReader struct {
+	r     io.ReadSeeker
+	block []byte // uncompressed data of the current block
+	pos   int    // read position within block
+	addr  int64  // file offset of the current block
+	next  int64  // file offset of the next block
+	head  [18]byte
+	comp  []byte
+}
+
+// newBGZFReader returns a reader positioned at the start of r.
+func newBGZFReader(r io.ReadSeeker) *bgzfReader {
+	return &bgzfReader{r: r}
+}
+
+// readBlock reads the block at the file offset addr.
+func (b *bgzfReader) readBlock(addr int64) error {
+	if _, err := b.r.Seek(addr, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(b.r, b.head[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w - truncated header at %d", ErrBGZF, addr)
+		}
+		return err
+	}
+	h := b.head[:]
+	if h[0] != 0x1f || h[1] != 0x8b || h[2] != 8 || h[3]&4 == 0 {
+		return fmt.Errorf("%w - no gzip header at %d", ErrBGZF, addr)
+	}
+	xlen := int(binary.LittleEndian.Uint16(h[10:12]))
+	// The BC subfield is normally the only one.
+	if xlen != 6 || h[12] != 'B' || h[13] != 'C' {
+		return fmt.Errorf("%w - no BC field at %d", ErrBGZF, addr)
+	}
+	size := int(binary.LittleEndian.Uint16(h[16:18])) + 1
+	if cap(b.comp) < size-18 {
+		b.comp = make([]byte, size-18)
+	}
+	b.comp = b.comp[:size-18]
+	if _, err := io.ReadFull(b.r, b.comp); err != nil {
+		return fmt.Errorf("%w - truncated block at %d", ErrBGZF, addr)
+	}
+	isize := int(binary.LittleEndian.Uint32(b.comp[len(b.comp)-4:]))
+	if cap(b.block) < isize {
+		b.block = make([]byte, isize)
+	}
+	b.block = b.block[:isize]
+	fr := flate.NewReader(bytes.NewReader(b.comp[:len(b.comp)-8]))
+	if _, err := io.ReadFull(fr, b.block); err != nil {
+		return fmt.Errorf("%w - %v at %d", ErrBGZF, err, addr)
+	}
+	b.addr, b.next, b.pos = addr, addr+int64(size), 0
+	return nil
+}
+
+// Read reads uncompressed data, moving on to the next block as needed.
+// Empty blocks, such as the end-of-file marker, are skipped.
+func (b *bgzfReader) Read(p []byte) (int, error) {
+	for b.pos >= len(b.block) {
+		if err := b.readBlock(b.next); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, b.block[b.pos:])
+	b.pos += n
+	return n, nil
+}
+
+// Seek moves to a virtual offset.
+func (b *bgzfReader) Seek(voffset uint64) error {
+	addr, pos := int64(voffset>>16), int(voffset&0xffff)
+	if addr != b.addr || b.block == nil {
+		if err := b.readBlock(addr); err != nil {
+			return err
+		}
+	}
+	if pos > len(b.block) {
+		return fmt.Errorf("%w - offset %d is beyond the block at %d", ErrBGZF, pos, addr)
+	}
+	b.pos = pos
+	return nil
+}
+
+// Close closes the underlying reader if it is an io.Closer.
+func (b *bgzfReader) Close() error {
+	if c, ok := b.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package vcfgo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrBreakend is returned for a breakend ALT allele that cannot be parsed.
+var ErrBreakend = errors.New("vcfgo: bad breakend")
+
+// A breakend ALT allele joins the REF base, t, to a piece of sequence at
+// the mate position p in one of four ways:
+//
+//  t[p[  the sequence to the right of p follows t
+//  t]p]  the reverse complement of the sequence to the left of p follows t
+//  ]p]t  the sequence to the left of p comes before t
+//  [p[t  the reverse complement of the sequence to the right of p comes
+//        before t
+//
+// A single breakend, t. or .t, has no mate. In BEDPE terms the strand of
+// a breakend is + if the sequence it is joined to follows it and - if
+// that sequence comes before it.
+
+// Breakend is a breakend ALT allele. Strand is + if the join is to the
+// right of POS and - if it is to the left, and MateStrand is the same for
+// the mate position. Inserted holds any bases between REF and the join.
+// MateAssembly is true if the mate contig was in angle brackets, as for
+// a contig in an assembly file. Single breakends have no mate.
+type Breakend struct {
+	Chrom        string
+	Pos          uint64
+	Strand       byte
+	Inserted     string
+	Single       bool
+	MateChrom    string
+	MatePos      uint64
+	MateStrand   byte
+	MateAssembly bool
+}
+
+// ParseBreakend parses a breakend ALT allele. Chrom and Pos are left
+// for the caller to set.
+func ParseBreakend(ref, alt string) (*Breakend, error) {
+	bad := fmt.Errorf("%w - %s", ErrBreakend, alt)
+	if len(alt) < 2 {
+		return nil, bad
+	}
+	b := &Breakend{}
+	var t string
+	s, e := breakendMatePositions(alt)
+	switch {
+	case s != -1:
+		open, bracket := s-1, alt[s-1]
+		end := strings.IndexByte(alt[e+1:], bracket)
+		if end == -1 {
+			return nil, bad
+		}
+		end += e + 1
+		pos, err := strconv.ParseUint(alt[e+1:end], 10, 64)
+		if err != nil {
+			return nil, bad
+		}
+		b.MateChrom, b.MatePos = alt[s:e], pos
+		if strings.HasPrefix(b.MateChrom, `<`) && strings.HasSuffix(b.MateChrom, `>`) {
+			b.MateChrom, b.MateAssembly = b.MateChrom[1:len(b.MateChrom)-1], true
+		}
+		b.MateStrand = '+'
+		if bracket == '[' {
+			b.MateStrand = '-'
+		}
+		switch {
+		case open == 0:
+			t, b.Strand = alt[end+1:], '-'
+		case end == len(alt)-1:
+			t, b.Strand = alt[:open], '+'
+		default:
+			return nil, bad
+		}
+	case strings.ContainsAny(alt, `[]`):
+		return nil, bad
+	case alt[0] == '.':
+		t, b.Strand, b.Single = alt[1:], '-', true
+	case alt[len(alt)-1] == '.':
+		t, b.Strand, b.Single = alt[:len(alt)-1], '+', true
+	default:
+		return nil, bad
+	}
+	if t == `` || strings.ContainsAny(t, `.[]`) {
+		return nil, bad
+	}
+	if len(t) > len(ref) {
+		if b.Strand == '+' {
+			b.Inserted = t[len(ref):]
+		} else {
+			b.Inserted = t[:len(t)-len(ref)]
+		}
+	}
+	return b, nil
+}
+
+// Breakends parses the breakend ALT alleles of the Variant. The slice
+// has an entry for each ALT allele, which is nil if the allele is not a
+// breakend.
+func (v *Variant) Breakends() ([]*Breakend, error) {
+	bnds := make([]*Breakend, len(v.Alternate))
+	for i, a := range v.Alternate {
+		if ClassifyAllele(v.Reference, a).Type != AlleleBreakend {
+			continue
+		}
+		b, err := ParseBreakend(v.Reference, a)
+		if err != nil {
+			return bnds, fmt.Errorf("%s:%d %w", v.Chromosome, v.Pos, err)
+		}
+		b.Chrom, b.Pos = v.Chromosome, v.Pos
+		bnds[i] = b
+	}
+	return bnds, nil
+}
+
+// breakend returns the first breakend ALT allele of v or nil.
+func (v *Variant) breakend() (*Breakend, error) {
+	bnds, err := v.Breakends()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bnds {
+		if b != nil {
+			return b, nil
+		}
+	}
+	return nil, nil
+}
+
+// BreakendPair is a breakend record and its mate. Mate is nil for a
+// single breakend or if the mate record was not found.
+type BreakendPair struct {
+	First *Variant
+	Mate  *Variant
+}
+
+// BreakendPairer reads Variants from a Reader and pairs breakend records
+// with their mates. A record is paired with an earlier record whose ID is
+// its INFO/MATEID, or whose MATEID is its ID, or failing those with an
+// earlier record with the same INFO/EVENT. Records that are not
+// breakends are skipped. Records are held until their mate is read, so
+// unpaired records are returned at the end of the input.
+type BreakendPairer struct {
+	rdr     *Reader
+	pending []*Variant
+	out     []*BreakendPair
+	eof     bool
+	verr    *VCFError
+}
+
+// NewBreakendPairer returns a BreakendPairer.
+func NewBreakendPairer(rdr *Reader) *BreakendPairer {
+	return &BreakendPairer{rdr: rdr, verr: NewVCFError()}
+}
+
+// breakendKeys returns the INFO/MATEID and INFO/EVENT of v.
+func breakendKeys(v *Variant) (string, string) {
+	var mate, event string
+	if vals, ok := infoValues(v, `MATEID`); ok {
+		mate = vals[0]
+	}
+	if vals, ok := infoValues(v, `EVENT`); ok {
+		event = vals[0]
+	}
+	return mate, event
+}
+
+// Read returns the next BreakendPair or nil at the end of the input.
+// Records whose ALT alleles cannot be parsed are skipped and the errors
+// added to those returned by Error.
+func (p *BreakendPairer) Read() *BreakendPair {
+	for len(p.out) == 0 && !p.eof {
+		v := p.rdr.Read()
+		if v == nil {
+			p.eof = true
+			for _, u := range p.pending {
+				p.out = append(p.out, &BreakendPair{First: u})
+			}
+			p.pending = nil
+			break
+		}
+		b, err := v.breakend()
+		p.verr.Add(err, v.LineNumber)
+		if b == nil {
+			continue
+		}
+		if b.Single {
+			p.out = append(p.out, &BreakendPair{First: v})
+			continue
+		}
+		if i := p.mateIndex(v); i != -1 {
+			p.out = append(p.out, &BreakendPair{First: p.pending[i], Mate: v})
+			p.pending = append(p.pending[:i], p.pending[i+1:]...)
+			continue
+		}
+		p.pending = append(p.pending, v)
+	}
+	if len(p.out) == 0 {
+		return nil
+	}
+	pair := p.out[0]
+	p.out = p.out[1:]
+	return pair
+}
+
+// mateIndex returns the index in pending of the mate of v or -1.
+func (p *BreakendPairer) mateIndex(v *Variant) int {
+	mate, event := breakendKeys(v)
+	id := v.Id()
+	if id == `.` {
+		id = ``
+	}
+	for i, u := range p.pending {
+		umate, _ := breakendKeys(u)
+		if (mate != `` && u.Id() == mate) || (id != `` && umate == id) {
+			return i
+		}
+	}
+	if event == `` {
+		return -1
+	}
+	for i, u := range p.pending {
+		umate, uevent := breakendKeys(u)
+		if uevent == event && (mate == `` || umate == ``) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Error returns the errors parsing breakends. Errors reading the input
+// are returned by the Reader.
+func (p *BreakendPairer) Error() error {
+	if p.verr.IsEmpty() {
+		return nil
+	}
+	return p.verr
+}
+
+// breakendCI returns the 0-based, half-open interval of a breakend
+// widened by INFO/CIPOS.
+func breakendCI(v *Variant, pos uint64) (int64, int64) {
+	start, end := int64(pos)-1, int64(pos)
+	if vals, ok := infoValues(v, `CIPOS`); ok && len(vals) == 2 {
+		l, err1 := strconv.ParseInt(vals[0], 10, 64)
+		r, err2 := strconv.ParseInt(vals[1], 10, 64)
+		if err1 == nil && err2 == nil {
+			start, end = start+l, end+r
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	return start, end
+}
+
+// BEDPE returns the pair as a line of BEDPE: the two breakend intervals,
+// widened by CIPOS, the name, the score and the two strands. The name is
+// INFO/EVENT or the ID of the first record, and the score is its QUAL.
+// If the mate record was not found, the mate is taken from the ALT
+// allele. A single breakend has a mate of ".", -1 and -1 and a strand
+// of ".".
+func (p *BreakendPair) BEDPE() (string, error) {
+	b, err := p.First.breakend()
+	if err != nil {
+		return ``, err
+	}
+	if b == nil {
+		return ``, fmt.Errorf("%w - %s:%d has no breakend ALT allele", ErrBreakend, p.First.Chromosome, p.First.Pos)
+	}
+	start, end := breakendCI(p.First, b.Pos)
+	chrom2, start2, end2, strand2 := `.`, int64(-1), int64(-1), `.`
+	switch {
+	case p.Mate != nil:
+		m, err := p.Mate.breakend()
+		if err != nil {
+			return ``, err
+		}
+		chrom2 = p.Mate.Chromosome
+		start2, end2 = breakendCI(p.Mate, p.Mate.Pos)
+		if m != nil {
+			strand2 = string(m.Strand)
+		}
+	case !b.Single:
+		chrom2, start2, end2, strand2 = b.MateChrom, int64(b.MatePos)-1, int64(b.MatePos), string(b.MateStrand)
+	}
+	_, event := breakendKeys(p.First)
+	name := event
+	if name == `` {
+		name = p.First.Id()
+	}
+	score := `.`
+	if p.First.Quality != MISSING_VAL {
+		score = fmtFloat32(p.First.Quality)
+	}
+	return fmt.Sprintf("%s\t%d\t%d\t%s\t%d\t%d\t%s\t%s\t%c\t%s",
+		p.First.Chromosome, start, end, chrom2, start2, end2, name, score, b.Strand, strand2), nil
+}
+
+// WriteBEDPE pairs the breakend records read from rdr and writes them
+// to w as BEDPE.
+func WriteBEDPE(w io.Writer, rdr *Reader) error {
+	p := NewBreakendPairer(rdr)
+	for pair := p.Read(); pair != nil; pair = p.Read() {
+		line, err := pair.BEDPE()
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return p.Error()
+}
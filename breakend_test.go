@@ -0,0 +1,99 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var breakendStr = `##fileformat=VCFv4.2
+##INFO=<ID=SVTYPE,Number=1,Type=String,Description="Type of structural variant">
+##INFO=<ID=MATEID,Number=.,Type=String,Description="ID of mate breakends">
+##INFO=<ID=EVENT,Number=1,Type=String,Description="ID of event associated to breakend">
+##INFO=<ID=CIPOS,Number=2,Type=Integer,Description="Confidence interval around POS">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+2	321681	bnd_W	G	G]17:198982]	6	PASS	SVTYPE=BND;MATEID=bnd_Y
+2	321682	bnd_V	T	]13:123456]T	6	PASS	SVTYPE=BND;MATEID=bnd_U
+13	123456	bnd_U	C	C[2:321682[	6	PASS	SVTYPE=BND;MATEID=bnd_V
+13	123457	bnd_X	A	[17:198983[A	6	PASS	SVTYPE=BND;MATEID=bnd_Z
+17	198982	bnd_Y	A	A]2:321681]	6	PASS	SVTYPE=BND;MATEID=bnd_W
+17	198983	bnd_Z	C	[13:123457[C	6	PASS	SVTYPE=BND;MATEID=bnd_X
+20	1000	e1	A	A[21:2000[	.	PASS	SVTYPE=BND;EVENT=ev1;CIPOS=-5,5
+20	1500	snv	G	T	.	PASS	.
+20	3000	sb	G	G.	10	PASS	SVTYPE=BND
+21	2000	e2	T	]20:1000]T	.	PASS	SVTYPE=BND;EVENT=ev1;CIPOS=-5,5
+22	100	lone	A	A]X:500]	.	PASS	SVTYPE=BND;MATEID=missing
+`
+
+type BreakendSuite struct{}
+
+var _ = Suite(&BreakendSuite{})
+
+func (s *BreakendSuite) TestParse(c *C) {
+	for _, t := range []struct {
+		ref, alt string
+		want     Breakend
+	}{
+		{"T", "T[2:100[", Breakend{Strand: '+', MateChrom: "2", MatePos: 100, MateStrand: '-'}},
+		{"T", "T]2:100]", Breakend{Strand: '+', MateChrom: "2", MatePos: 100, MateStrand: '+'}},
+		{"T", "]2:100]T", Breakend{Strand: '-', MateChrom: "2", MatePos: 100, MateStrand: '+'}},
+		{"T", "[2:100[T", Breakend{Strand: '-', MateChrom: "2", MatePos: 100, MateStrand: '-'}},
+		{"G", "GAAC[HLA-A*01:01:01:01:5[", Breakend{Strand: '+', Inserted: "AAC", MateChrom: "HLA-A*01:01:01:01", MatePos: 5, MateStrand: '-'}},
+		{"G", "]<ctg1>:7]TTG", Breakend{Strand: '-', Inserted: "TT", MateChrom: "ctg1", MatePos: 7, MateStrand: '+', MateAssembly: true}},
+		{"G", "GCC.", Breakend{Strand: '+', Inserted: "CC", Single: true}},
+		{"G", ".G", Breakend{Strand: '-', Single: true}},
+	} {
+		b, err := ParseBreakend(t.ref, t.alt)
+		c.Assert(err, IsNil, Commentf(t.alt))
+		c.Check(*b, DeepEquals, t.want, Commentf(t.alt))
+	}
+	for _, alt := range []string{"G[2:x[", "G[2:100", "G]2:100[", "[2:100[", "G[2:100[T", "ACG", "."} {
+		_, err := ParseBreakend("G", alt)
+		c.Check(err, ErrorMatches, "vcfgo: bad breakend - .*", Commentf(alt))
+	}
+
+	v := &Variant{Chromosome: "2", Pos: 7, Reference: "A", Alternate: []string{"C", "A[3:9["}}
+	bnds, err := v.Breakends()
+	c.Assert(err, IsNil)
+	c.Assert(bnds[0], IsNil)
+	c.Assert(*bnds[1], DeepEquals, Breakend{Chrom: "2", Pos: 7, Strand: '+', MateChrom: "3", MatePos: 9, MateStrand: '-'})
+	v.Alternate[1] = "A[3:x["
+	_, err = v.Breakends()
+	c.Assert(err, ErrorMatches, "2:7 vcfgo: bad breakend - A\\[3:x\\[")
+}
+
+func (s *BreakendSuite) TestPairs(c *C) {
+	rdr, err := NewReader(strings.NewReader(breakendStr), true)
+	c.Assert(err, IsNil)
+	p := NewBreakendPairer(rdr)
+	var pairs []string
+	for pair := p.Read(); pair != nil; pair = p.Read() {
+		s := pair.First.Id() + ":"
+		if pair.Mate != nil {
+			s += pair.Mate.Id()
+		}
+		pairs = append(pairs, s)
+	}
+	c.Assert(p.Error(), IsNil)
+	c.Assert(pairs, DeepEquals, []string{"bnd_V:bnd_U", "bnd_W:bnd_Y", "bnd_X:bnd_Z", "sb:", "e1:e2", "lone:"})
+}
+
+func (s *BreakendSuite) TestBEDPE(c *C) {
+	rdr, err := NewReader(strings.NewReader(breakendStr), true)
+	c.Assert(err, IsNil)
+	var b bytes.Buffer
+	c.Assert(WriteBEDPE(&b, rdr), IsNil)
+	c.Assert(strings.Split(strings.TrimSpace(b.String()), "\n"), DeepEquals, []string{
+		"2\t321681\t321682\t13\t123455\t123456\tbnd_V\t6\t-\t+",
+		"2\t321680\t321681\t17\t198981\t198982\tbnd_W\t6\t+\t+",
+		"13\t123456\t123457\t17\t198982\t198983\tbnd_X\t6\t-\t-",
+		"20\t2999\t3000\t.\t-1\t-1\tsb\t10\t+\t.",
+		"20\t994\t1005\t21\t1994\t2005\tev1\t.\t+\t-",
+		"22\t99\t100\tX\t499\t500\tlone\t.\t+\t+",
+	})
+
+	pair := &BreakendPair{First: &Variant{Chromosome: "1", Pos: 5, Reference: "A", Alternate: []string{"T"}}}
+	_, err = pair.BEDPE()
+	c.Assert(err, ErrorMatches, ".*1:5 has no breakend ALT allele")
+}
@@ -0,0 +1,277 @@
+package vcfgo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Reference build detection works by comparing the lengths of a handful
+// of well-known contigs in the header's contig dictionary against the
+// lengths for each known build. Contig names are compared without any
+// "chr" prefix and with M and MT treated as the same contig so that UCSC
+// and Ensembl style names both work. The ##reference and ##assembly
+// meta-information lines (and the assembly key on ##contig lines) are
+// used as hints but the lengths always win if they disagree with a hint.
+//
+// GRCh37 and hg19 have identical nuclear contigs and differ only in the
+// mitochondrion - GRCh37 uses the 16569bp rCRS while hg19 chrM is the
+// older 16571bp NC_001807 - so a file without a mitochondrial contig can
+// only be assigned to one or the other by its naming style.
+
+// Names of the builds that DetectBuild can report.
+const (
+	BuildGRCh37   = `GRCh37`
+	BuildHg19     = `hg19`
+	BuildGRCh38   = `GRCh38`
+	BuildT2TCHM13 = `T2T-CHM13`
+	BuildGRCm38   = `GRCm38`
+	BuildGRCm39   = `GRCm39`
+	BuildBDGP6    = `BDGP6`
+	BuildWBcel235 = `WBcel235`
+	BuildR64      = `R64`
+	BuildUnknown  = ``
+)
+
+// A build needs at least buildMinHigh matching contig lengths to be
+// reported with HighConfidence. Each conflicting length costs
+// conflictWeight matches when scoring builds.
+const (
+	buildMinHigh   = 3
+	conflictWeight = 2
+)
+
+// Confidence - Create enum for the confidence of a build detection.
+type Confidence int
+
+// Declare related constants for each Confidence starting with index 0
+const (
+	NoConfidence     Confidence = iota // EnumIndex = 0
+	LowConfidence                      // EnumIndex = 1
+	MediumConfidence                   // EnumIndex = 2
+	HighConfidence                     // EnumIndex = 3
+)
+
+// String - Creating common behaviour - give the type a String function
+func (c Confidence) String() string {
+	return [...]string{"None", "Low", "Medium", "High"}[c]
+}
+
+// BuildGuess holds the result of a reference build detection. Matched
+// and Conflicts count the contigs whose lengths agreed and disagreed
+// with the reported Build. Hint is the build suggested by the header
+// meta-information lines, if any, and Notes explains anything that
+// lowered the Confidence.
+type BuildGuess struct {
+	Build      string
+	Confidence Confidence
+	Matched    int
+	Conflicts  int
+	Hint       string
+	Notes      []string
+}
+
+type buildSignature struct {
+	name    string
+	ucsc    bool // true if the build is normally seen with chr prefixes
+	hint    *regexp.Regexp
+	lengths map[string]uint64
+}
+
+var grch37Lengths = map[string]uint64{`1`: 249250621, `2`: 243199373,
+	`3`: 198022430, `X`: 155270560, `Y`: 59373566}
+
+var buildSignatures = []*buildSignature{
+	{BuildGRCh37, false, regexp.MustCompile(`(?i)GRCh37|b37|hs37|g1k_v37|GCA_000001405\.1\b`),
+		withContig(grch37Lengths, `MT`, 16569)},
+	{BuildHg19, true, regexp.MustCompile(`(?i)hg19|ucsc\.hg19`),
+		withContig(grch37Lengths, `MT`, 16571)},
+	{BuildGRCh38, false, regexp.MustCompile(`(?i)GRCh38|hg38|hs38|GCA_000001405\.15`),
+		map[string]uint64{`1`: 248956422, `2`: 242193529, `3`: 198295559,
+			`X`: 156040895, `Y`: 57227415, `MT`: 16569}},
+	{BuildT2TCHM13, false, regexp.MustCompile(`(?i)CHM13|T2T|\bhs1\b`),
+		map[string]uint64{`1`: 248387328, `2`: 242696752, `3`: 201105948,
+			`X`: 154259566, `Y`: 62460029, `MT`: 16569}},
+	{BuildGRCm38, false, regexp.MustCompile(`(?i)GRCm38|mm10`),
+		map[string]uint64{`1`: 195471971, `2`: 182113224, `X`: 171031299}},
+	{BuildGRCm39, false, regexp.MustCompile(`(?i)GRCm39|mm39`),
+		map[string]uint64{`1`: 195154279, `2`: 181755017, `X`: 169476592}},
+	{BuildBDGP6, false, regexp.MustCompile(`(?i)BDGP6|dm6`),
+		map[string]uint64{`2L`: 23513712, `2R`: 25286936, `3L`: 28110227,
+			`3R`: 32079331, `X`: 23542271}},
+	{BuildWBcel235, false, regexp.MustCompile(`(?i)WBcel235|ce11`),
+		map[string]uint64{`I`: 15072434, `II`: 15279421, `III`: 13783801,
+			`IV`: 17493829, `V`: 20924180, `X`: 17718942}},
+	{BuildR64, false, regexp.MustCompile(`(?i)R64|sacCer3|S288C`),
+		map[string]uint64{`I`: 230218, `II`: 813184, `III`: 316620, `IV`: 1531933}},
+}
+
+func withContig(m map[string]uint64, name string, length uint64) map[string]uint64 {
+	out := make(map[string]uint64, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	out[name] = length
+	return out
+}
+
+// normalizeContigName strips any chr prefix and maps M to MT.
+func normalizeContigName(name string) string {
+	if len(name) > 3 && strings.EqualFold(name[:3], `chr`) {
+		name = name[3:]
+	}
+	if name == `M` {
+		return `MT`
+	}
+	return name
+}
+
+// DetectBuild reports the most likely reference build for the Header
+// based on the lengths in Header.Contigs and any ##reference, ##assembly
+// or ##contig assembly values.
+func DetectBuild(h *Header) *BuildGuess {
+	var hints []string
+	for _, m := range h.Lines {
+		switch {
+		case m.LineKey == `reference` || m.LineKey == `assembly`:
+			if m.MetaType == Unstructured {
+				hints = append(hints, m.Value)
+			} else {
+				hints = append(hints, m.GetValue(`ID`))
+			}
+		case m.LineKey == `contig`:
+			if a := m.GetValue(`assembly`); a != `` {
+				hints = append(hints, a)
+			}
+		}
+	}
+	d := h.Contigs
+	if d == nil {
+		d, _ = NewContigDictFromHeader(h)
+	}
+	return DetectBuildFromContigs(d, hints...)
+}
+
+// DetectBuildFromContigs reports the most likely reference build for a
+// ContigDict. Any hints (file names, assembly names etc) are matched
+// against the known names for each build.
+func DetectBuildFromContigs(d *ContigDict, hints ...string) *BuildGuess {
+	g := &BuildGuess{Build: BuildUnknown}
+	g.Hint = buildHint(hints)
+
+	// Work out naming style and normalised lengths.
+	lengths := make(map[string]uint64)
+	var ucsc, plain int
+	for _, c := range d.contigs {
+		if strings.HasPrefix(strings.ToLower(c.Name), `chr`) {
+			ucsc++
+		} else {
+			plain++
+		}
+		if c.Length > 0 {
+			lengths[normalizeContigName(c.Name)] = c.Length
+		}
+	}
+	isUcsc := ucsc > plain
+
+	var best []*buildSignature
+	bestScore := 0
+	matches := make(map[string][2]int)
+	for _, b := range buildSignatures {
+		var m, x int
+		for name, l := range b.lengths {
+			if ol, found := lengths[name]; found {
+				if ol == l {
+					m++
+				} else {
+					x++
+				}
+			}
+		}
+		matches[b.name] = [2]int{m, x}
+		score := m - conflictWeight*x
+		if m == 0 || score <= 0 {
+			continue
+		}
+		if score > bestScore {
+			bestScore = score
+			best = []*buildSignature{b}
+		} else if score == bestScore {
+			best = append(best, b)
+		}
+	}
+
+	if len(best) == 0 {
+		if g.Hint != `` {
+			g.Build = g.Hint
+			g.Confidence = LowConfidence
+			g.Notes = append(g.Notes, `no contig lengths matched a known build so only the header hint was used`)
+		}
+		return g
+	}
+
+	winner := best[0]
+	if len(best) > 1 {
+		// Ties are broken by naming style and then by hint.
+		var byStyle []*buildSignature
+		for _, b := range best {
+			if b.ucsc == isUcsc {
+				byStyle = append(byStyle, b)
+			}
+		}
+		if len(byStyle) == 1 {
+			winner = byStyle[0]
+		} else {
+			for _, b := range best {
+				if b.name == g.Hint {
+					winner = b
+				}
+			}
+		}
+		names := make([]string, len(best))
+		for i, b := range best {
+			names[i] = b.name
+		}
+		g.Notes = append(g.Notes, fmt.Sprintf("contig lengths fit %s equally well", strings.Join(names, `, `)))
+	}
+
+	g.Build = winner.name
+	g.Matched = matches[winner.name][0]
+	g.Conflicts = matches[winner.name][1]
+
+	switch {
+	case g.Matched >= buildMinHigh && g.Conflicts == 0 && len(best) == 1:
+		g.Confidence = HighConfidence
+	case g.Conflicts == 0:
+		g.Confidence = MediumConfidence
+	default:
+		g.Confidence = LowConfidence
+		g.Notes = append(g.Notes, fmt.Sprintf("%d contig lengths disagree with %s", g.Conflicts, g.Build))
+	}
+
+	if g.Build == BuildGRCh37 && isUcsc && matches[BuildGRCh37][0] > 0 {
+		if _, found := lengths[`MT`]; found {
+			g.Notes = append(g.Notes, `UCSC style names with the GRCh37 (rCRS) mitochondrion`)
+		}
+	}
+
+	if g.Hint != `` && g.Hint != g.Build {
+		g.Notes = append(g.Notes, fmt.Sprintf("header suggests %s but contig lengths indicate %s", g.Hint, g.Build))
+		if g.Confidence > LowConfidence {
+			g.Confidence--
+		}
+	}
+	return g
+}
+
+// buildHint returns the first build whose names match any of the hints.
+func buildHint(hints []string) string {
+	for _, h := range hints {
+		for _, b := range buildSignatures {
+			if b.hint.MatchString(h) {
+				return b.name
+			}
+		}
+	}
+	return ``
+}
@@ -0,0 +1,121 @@
+package vcfgo
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type BuildSuite struct{}
+
+var _ = Suite(&BuildSuite{})
+
+func buildHeader(c *C, lines ...string) *Header {
+	str := "##fileformat=VCFv4.2\n" + strings.Join(lines, "\n") +
+		"\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"
+	rdr, err := NewReader(strings.NewReader(str), false)
+	c.Assert(err, IsNil)
+	return rdr.Header
+}
+
+func (s *BuildSuite) TestGRCh38(c *C) {
+	h := buildHeader(c,
+		`##contig=<ID=chr1,length=248956422>`,
+		`##contig=<ID=chr2,length=242193529>`,
+		`##contig=<ID=chrX,length=156040895>`,
+		`##contig=<ID=chrM,length=16569>`)
+	g := DetectBuild(h)
+	c.Assert(g.Build, Equals, BuildGRCh38)
+	c.Assert(g.Confidence, Equals, HighConfidence)
+	c.Assert(g.Matched, Equals, 4)
+	c.Assert(g.Conflicts, Equals, 0)
+}
+
+func (s *BuildSuite) TestHg19Quirk(c *C) {
+	// hg19 has the NC_001807 chrM
+	h := buildHeader(c,
+		`##contig=<ID=chr1,length=249250621>`,
+		`##contig=<ID=chr2,length=243199373>`,
+		`##contig=<ID=chr3,length=198022430>`,
+		`##contig=<ID=chrM,length=16571>`)
+	g := DetectBuild(h)
+	c.Assert(g.Build, Equals, BuildHg19)
+	c.Assert(g.Confidence, Equals, HighConfidence)
+
+	// Same nuclear contigs with the rCRS is GRCh37 even with UCSC names.
+	h = buildHeader(c,
+		`##contig=<ID=chr1,length=249250621>`,
+		`##contig=<ID=chr2,length=243199373>`,
+		`##contig=<ID=chr3,length=198022430>`,
+		`##contig=<ID=chrM,length=16569>`)
+	g = DetectBuild(h)
+	c.Assert(g.Build, Equals, BuildGRCh37)
+	c.Assert(len(g.Notes), Equals, 1)
+
+	// Without a mitochondrion the naming style decides.
+	h = buildHeader(c,
+		`##contig=<ID=1,length=249250621>`,
+		`##contig=<ID=2,length=243199373>`,
+		`##contig=<ID=X,length=155270560>`)
+	g = DetectBuild(h)
+	c.Assert(g.Build, Equals, BuildGRCh37)
+	c.Assert(g.Confidence, Equals, MediumConfidence)
+}
+
+func (s *BuildSuite) TestHints(c *C) {
+	h := buildHeader(c,
+		`##reference=file:///refs/hs37d5.fa`,
+		`##contig=<ID=1>`)
+	g := DetectBuild(h)
+	c.Assert(g.Build, Equals, BuildGRCh37)
+	c.Assert(g.Hint, Equals, BuildGRCh37)
+	c.Assert(g.Confidence, Equals, LowConfidence)
+
+	// Lengths win over a disagreeing hint.
+	h = buildHeader(c,
+		`##assembly=GRCh37`,
+		`##contig=<ID=chr1,length=248387328>`,
+		`##contig=<ID=chr2,length=242696752>`,
+		`##contig=<ID=chrX,length=154259566>`,
+		`##contig=<ID=chrY,length=62460029>`)
+	g = DetectBuild(h)
+	c.Assert(g.Build, Equals, BuildT2TCHM13)
+	c.Assert(g.Confidence, Equals, MediumConfidence)
+	c.Assert(g.Notes, DeepEquals, []string{"header suggests GRCh37 but contig lengths indicate T2T-CHM13"})
+}
+
+func (s *BuildSuite) TestModelOrganisms(c *C) {
+	d := NewContigDict()
+	d.Add(&Contig{Name: "chrI", Length: 15072434})
+	d.Add(&Contig{Name: "chrII", Length: 15279421})
+	d.Add(&Contig{Name: "chrIII", Length: 13783801})
+	g := DetectBuildFromContigs(d)
+	c.Assert(g.Build, Equals, BuildWBcel235)
+	c.Assert(g.Confidence, Equals, HighConfidence)
+
+	d = NewContigDict()
+	d.Add(&Contig{Name: "1", Length: 195154279})
+	d.Add(&Contig{Name: "2", Length: 181755017})
+	g = DetectBuildFromContigs(d, "mm39")
+	c.Assert(g.Build, Equals, BuildGRCm39)
+	c.Assert(g.Confidence, Equals, MediumConfidence)
+}
+
+func (s *BuildSuite) TestUnknown(c *C) {
+	d := NewContigDict()
+	d.Add(&Contig{Name: "scaffold_1", Length: 12345})
+	g := DetectBuildFromContigs(d)
+	c.Assert(g.Build, Equals, BuildUnknown)
+	c.Assert(g.Confidence, Equals, NoConfidence)
+
+	// One wrong length amongst several good ones lowers the confidence.
+	d = NewContigDict()
+	d.Add(&Contig{Name: "chr1", Length: 248956422})
+	d.Add(&Contig{Name: "chr2", Length: 242193529})
+	d.Add(&Contig{Name: "chr3", Length: 198295559})
+	d.Add(&Contig{Name: "chrX", Length: 1})
+	g = DetectBuildFromContigs(d)
+	c.Assert(g.Build, Equals, BuildGRCh38)
+	c.Assert(g.Confidence, Equals, LowConfidence)
+	c.Assert(g.Conflicts, Equals, 1)
+}
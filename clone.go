@@ -0,0 +1,159 @@
+package vcfgo
+
+// Transforms that return changed records, such as Split, Join and
+// Decompose, work on copies so that the records they are given are not
+// changed. A Variant is copied with Clone, which shares the Header, or
+// with CloneWithHeader, which may be given a copy of the Header from
+// Header.Clone.
+
+// Clone returns a copy of the InfoByte that shares the Header.
+func (i *InfoByte) Clone() *InfoByte {
+	if i == nil {
+		return nil
+	}
+	return &InfoByte{Info: append([]byte(nil), i.Info...), header: i.header}
+}
+
+// Clone returns a copy of the SampleGenotype.
+func (g *SampleGenotype) Clone() *SampleGenotype {
+	if g == nil {
+		return nil
+	}
+	c := *g
+	if g.GT != nil {
+		c.GT = append(make([]int, 0, len(g.GT)), g.GT...)
+	}
+	if g.GL != nil {
+		c.GL = append(make([]float64, 0, len(g.GL)), g.GL...)
+	}
+	if g.Fields != nil {
+		c.Fields = make(map[string]string, len(g.Fields))
+		for k, f := range g.Fields {
+			c.Fields[k] = f
+		}
+	}
+	return &c
+}
+
+// Clone returns a copy of the Variant that shares only the Header. INFO,
+// ALT, FORMAT and the samples, parsed or not, are copied.
+func (v *Variant) Clone() *Variant {
+	return v.CloneWithHeader(v.Header)
+}
+
+// CloneWithHeader returns a copy of the Variant, as for Clone, that uses
+// h as its Header. h would usually be v.Header.Clone() or a Header with
+// the same samples and definitions.
+func (v *Variant) CloneWithHeader(h *Header) *Variant {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	c.Header = h
+	if v.Alternate != nil {
+		c.Alternate = append(make([]string, 0, len(v.Alternate)), v.Alternate...)
+	}
+	if v.Format != nil {
+		c.Format = append(make([]string, 0, len(v.Format)), v.Format...)
+	}
+	switch info := v.Info_.(type) {
+	case nil:
+	case *InfoByte:
+		ib := info.Clone()
+		ib.header = h
+		c.Info_ = ib
+	default:
+		c.Info_ = NewInfoByte(append([]byte(nil), info.Bytes()...), h)
+	}
+	if v.Samples != nil {
+		c.Samples = make([]*SampleGenotype, len(v.Samples))
+		for i, g := range v.Samples {
+			c.Samples[i] = g.Clone()
+		}
+	}
+	return &c
+}
+
+// Clone returns a copy of the Header that shares no pointers with h, so
+// that either can be changed without changing the other.
+func (h *Header) Clone() *Header {
+	h.RLock()
+	defer h.RUnlock()
+	nh := NewHeader()
+	nh.FileFormat = h.FileFormat
+	nh.SampleNames = append(nh.SampleNames, h.SampleNames...)
+	for _, m := range h.Lines {
+		nh.Lines = append(nh.Lines, m.Clone())
+	}
+	for k, i := range h.Infos {
+		nh.Infos[k] = i.clone()
+	}
+	for k, f := range h.SampleFormats {
+		nh.SampleFormats[k] = (*SampleFormat)((*Info)(f).clone())
+	}
+	for k, f := range h.Filters {
+		nh.Filters[k] = f
+	}
+	nh.Extras = append(nh.Extras, h.Extras...)
+	if h.Contigs != nil {
+		for _, c := range h.Contigs.Contigs() {
+			nc := *c
+			nc.Extra = cloneKVs(c.Extra)
+			nh.Contigs.Add(&nc)
+		}
+	} else {
+		nh.Contigs = nil
+	}
+	for k, s := range h.Samples {
+		ns := *s
+		ns.Genomes = append([]string(nil), s.Genomes...)
+		ns.Mixture = append([]float64(nil), s.Mixture...)
+		ns.Description = append([]string(nil), s.Description...)
+		ns.Extra = cloneKVs(s.Extra)
+		nh.Samples[k] = &ns
+	}
+	if h.Pedigree != nil {
+		for _, e := range h.Pedigree.Entries {
+			ne := *e
+			ne.Extra = cloneKVs(e.Extra)
+			nh.Pedigree.Add(&ne)
+		}
+	} else {
+		nh.Pedigree = nil
+	}
+	nh.Pedigrees = append([]string(nil), h.Pedigrees...)
+	nh.percentDecode = h.percentDecode
+	return nh
+}
+
+// clone returns a copy of the Info.
+func (i *Info) clone() *Info {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	if i.fields != nil {
+		c.fields = make(map[string]*KV, len(i.fields))
+		for k, kv := range i.fields {
+			nkv := *kv
+			c.fields[k] = &nkv
+		}
+	}
+	if i.order != nil {
+		c.order = append(make([]string, 0, len(i.order)), i.order...)
+	}
+	return &c
+}
+
+// cloneKVs returns a copy of a slice of KVs.
+func cloneKVs(kvs []*KV) []*KV {
+	if kvs == nil {
+		return nil
+	}
+	c := make([]*KV, len(kvs))
+	for i, kv := range kvs {
+		nkv := *kv
+		c[i] = &nkv
+	}
+	return c
+}
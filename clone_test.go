@@ -0,0 +1,105 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var cloneStr = `##fileformat=VCFv4.2
+##contig=<ID=chr1,length=1000>
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Total depth">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled likelihoods">
+##SAMPLE=<ID=s1,Genomes=Germline,Mixture=1.0,Description="Blood">
+##PEDIGREE=<ID=s1,Father=f1,Mother=m1>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	s1
+chr1	10	.	A	G	.	PASS	DP=5	GT:PL	0/1:30,0,90
+`
+
+type CloneSuite struct{}
+
+func headerText(c *C, h *Header) string {
+	var b bytes.Buffer
+	_, err := NewWriter(&b, h)
+	c.Assert(err, IsNil)
+	return b.String()
+}
+
+var _ = Suite(&CloneSuite{})
+
+func (s *CloneSuite) read(c *C, lazy bool) *Variant {
+	rdr, err := NewReader(strings.NewReader(cloneStr), lazy)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	c.Assert(v, NotNil)
+	return v
+}
+
+func (s *CloneSuite) TestVariant(c *C) {
+	v := s.read(c, false)
+	orig := v.String()
+	cl := v.Clone()
+	c.Assert(cl.String(), Equals, orig)
+	c.Assert(cl.Header, Equals, v.Header)
+
+	c.Assert(cl.Info().Set("DP", 9), IsNil)
+	cl.Alternate[0] = "T"
+	cl.Format[1] = "GL"
+	cl.Samples[0].Fields["GT"] = "1/1"
+	cl.Samples[0].GT[0] = 1
+	cl.Samples[0].GL[0] = -1
+	c.Assert(v.String(), Equals, orig)
+	c.Assert(v.Samples[0].GT, DeepEquals, []int{0, 1})
+	c.Assert(v.Samples[0].GL, DeepEquals, []float64{-3, 0, -9})
+
+	// Samples that have not been parsed are parsed separately.
+	v = s.read(c, true)
+	cl = v.Clone()
+	c.Assert(v.Header.ParseSamples(cl), IsNil)
+	c.Assert(cl.Samples, HasLen, 1)
+	c.Assert(v.Samples, IsNil)
+	c.Assert(cl.String(), Equals, v.String())
+}
+
+func (s *CloneSuite) TestHeader(c *C) {
+	v := s.read(c, false)
+	h := v.Header
+	before := headerText(c, h)
+	nh := h.Clone()
+	c.Assert(headerText(c, nh), Equals, before)
+
+	cl := v.CloneWithHeader(nh)
+	c.Assert(cl.Header, Equals, nh)
+	c.Assert(cl.Info().(*InfoByte).header, Equals, nh)
+
+	nh.Infos["DP"].Description = "changed"
+	nh.SampleFormats["PL"].Number = "."
+	nh.AddInfoLine("AF", "A", "Float", "Allele frequency")
+	nh.Lines[0].KVs["length"].Value = "2000"
+	nh.Contigs.Add(&Contig{Name: "chr2"})
+	nh.Samples["s1"].Genomes[0] = "Tumor"
+	nh.Pedigree.Entries[0].Father = "f2"
+	nh.SampleNames[0] = "x"
+	c.Assert(headerText(c, h), Equals, before)
+	c.Assert(h.Infos["DP"].Description, Equals, "Total depth")
+	c.Assert(h.Contigs.Len(), Equals, 1)
+	c.Assert(h.Samples["s1"].Genomes, DeepEquals, []string{"Germline"})
+	c.Assert(h.Pedigree.Entries[0].Father, Equals, "f1")
+	father, _, err := nh.Pedigree.Parents("s1")
+	c.Assert(err, IsNil)
+	c.Assert(father, Equals, "f1")
+}
+
+func (s *CloneSuite) TestNil(c *C) {
+	var ib *InfoByte
+	c.Assert(ib.Clone(), IsNil)
+	var g *SampleGenotype
+	c.Assert(g.Clone(), IsNil)
+	v := &Variant{Chromosome: "chr1", Pos: 1, Reference: "A", Alternate: []string{"C"}}
+	cl := v.Clone()
+	c.Assert(cl.Info_, IsNil)
+	c.Assert(cl.Samples, IsNil)
+	c.Assert(cl.Alt(), DeepEquals, []string{"C"})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+var matchModes = map[string]vcfgo.MatchMode{
+	"allele":   vcfgo.MatchAllele,
+	"position": vcfgo.MatchPosition,
+	"overlap":  vcfgo.MatchOverlap,
+}
+
+var tableKeys = map[string]vcfgo.TableKey{
+	"position": vcfgo.KeyPosition,
+	"allele":   vcfgo.KeyAllele,
+	"interval": vcfgo.KeyInterval,
+}
+
+// annotator is implemented by VCFAnnotator and TableAnnotator.
+type annotator interface {
+	Annotate(v *vcfgo.Variant) error
+}
+
+// runAnnotate copies INFO fields from a source VCF, or the columns of a
+// table, onto matching records. A bgzipped source VCF with a .tbi index
+// is read through the index; any other source VCF must be sorted in the
+// same order as the input.
+func runAnnotate(args []string) error {
+	fs := flag.NewFlagSet("annotate", flag.ExitOnError)
+	source := fs.String("s", "", "source VCF, bgzipped and indexed or sorted like the input")
+	fields := fs.String("f", "", "comma-separated INFO fields to copy, each FIELD or FIELD:NAME")
+	mode := fs.String("m", "allele", "match by allele, position or overlap")
+	table := fs.String("t", "", "tab-delimited table or BED file to annotate from")
+	key := fs.String("k", "allele", "table columns start with the position, allele or interval (BED)")
+	columns := fs.String("c", "", "comma-separated COLUMN:NAME[:NUMBER:TYPE[:REDUCER]] for -t")
+	fs.Parse(args)
+	if (*source == "") == (*table == "") {
+		return errors.New("one of -s or -t is required")
+	}
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+	var a annotator
+	if *source != "" {
+		a, err = vcfAnnotator(*source, *fields, *mode, rdr.Header)
+	} else {
+		a, err = tableAnnotator(*table, *columns, *key, rdr.Header)
+	}
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	wtr, err := vcfgo.NewWriter(out, rdr.Header)
+	if err != nil {
+		return err
+	}
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		if err := a.Annotate(v); err != nil {
+			return err
+		}
+		wtr.WriteVariant(v)
+	}
+	return rdr.Error()
+}
+
+// vcfAnnotator opens the source VCF. It is not closed as it is read
+// until the command ends.
+func vcfAnnotator(source, fields, mode string, h *vcfgo.Header) (annotator, error) {
+	if fields == "" {
+		return nil, errors.New("-f is required with -s")
+	}
+	m, found := matchModes[mode]
+	if !found {
+		return nil, fmt.Errorf("unknown match mode %s", mode)
+	}
+
+	var src *vcfgo.Reader
+	var err error
+	if _, serr := os.Stat(source + ".tbi"); serr == nil {
+		src, err = vcfgo.OpenIndexed(source, false)
+	} else {
+		var f *os.File
+		if f, err = os.Open(source); err == nil {
+			src, err = vcfgo.NewReader(f, false)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var afs []vcfgo.AnnotationField
+	for _, f := range strings.Split(fields, ",") {
+		afs = append(afs, vcfgo.ParseAnnotationField(f))
+	}
+	return vcfgo.NewVCFAnnotator(src, h, m, afs...)
+}
+
+// tableAnnotator reads a table into memory.
+func tableAnnotator(table, columns, key string, h *vcfgo.Header) (annotator, error) {
+	if columns == "" {
+		return nil, errors.New("-c is required with -t")
+	}
+	k, found := tableKeys[key]
+	if !found {
+		return nil, fmt.Errorf("unknown table key %s", key)
+	}
+	var cfs []vcfgo.ColumnField
+	for _, c := range strings.Split(columns, ",") {
+		cf, err := vcfgo.ParseColumnField(c)
+		if err != nil {
+			return nil, err
+		}
+		cfs = append(cfs, cf)
+	}
+	f, err := os.Open(table)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return vcfgo.NewTableAnnotator(f, k, h, cfs...)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+// runBEDPE pairs breakend records with their mates and writes them as
+// BEDPE.
+func runBEDPE(args []string) error {
+	fs := flag.NewFlagSet("bedpe", flag.ExitOnError)
+	fs.Parse(args)
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	if err := vcfgo.WriteBEDPE(out, rdr); err != nil {
+		return err
+	}
+	return rdr.Error()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+// runCheckRef compares REF with a reference FASTA and writes a count of
+// each outcome to stderr. With -fix, records whose REF is an ALT allele
+// are swapped back and with -d records that are still wrong are dropped.
+func runCheckRef(args []string) error {
+	fs := flag.NewFlagSet("checkref", flag.ExitOnError)
+	fasta := fs.String("f", "", "reference FASTA, indexed with a .fai or indexed in memory")
+	fix := fs.Bool("fix", false, "swap REF and ALT when an ALT matches the reference")
+	drop := fs.Bool("d", false, "drop records whose REF does not match the reference")
+	fs.Parse(args)
+	if *fasta == "" {
+		return errors.New("-f is required")
+	}
+	ref, err := vcfgo.OpenFasta(*fasta)
+	if err != nil {
+		return err
+	}
+	defer ref.Close()
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	wtr, err := vcfgo.NewWriter(out, rdr.Header)
+	if err != nil {
+		return err
+	}
+	rc := vcfgo.NewRefChecker(rdr, ref)
+	rc.Fix = *fix
+	for v, status := rc.Read(); v != nil; v, status = rc.Read() {
+		if *drop && status != vcfgo.RefOK && !(status == vcfgo.RefSwapped && *fix) {
+			continue
+		}
+		wtr.WriteVariant(v)
+	}
+	for s := vcfgo.RefOK; s <= vcfgo.RefSwapped; s++ {
+		fmt.Fprintf(os.Stderr, "%s\t%d\n", s, rc.Counts[s])
+	}
+	if err := rdr.Error(); err != nil {
+		return err
+	}
+	return rc.Error()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := fs.String("to", "4.2", "target VCF version")
+	strict := fs.Bool("strict", false, "exit with an error if the conversion is lossy")
+	fs.Parse(args)
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+	vc, err := vcfgo.NewVersionConverter(rdr.Header, *to)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	wtr, err := vcfgo.NewWriter(out, vc.Header)
+	if err != nil {
+		return err
+	}
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		keep, err := vc.Convert(v)
+		if err != nil {
+			return err
+		}
+		if keep {
+			wtr.WriteVariant(v)
+		}
+	}
+
+	// Every change is reported so the lossy ones are never silent.
+	for _, n := range vc.Notes() {
+		fmt.Fprintf(os.Stderr, "vcfgo convert: %s\n", n)
+	}
+	if *strict && vc.Lossy() {
+		return fmt.Errorf("conversion from VCFv%s to VCFv%s is lossy", vc.From, vc.To)
+	}
+	return rdr.Error()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+var fieldPolicies = map[string]vcfgo.FieldPolicy{
+	"copy":       vcfgo.CopyFields,
+	"nonallelic": vcfgo.CopyNonAllelic,
+	"drop":       vcfgo.DropFields,
+}
+
+// runDecompose breaks MNPs and complex records into SNPs and indels.
+func runDecompose(args []string) error {
+	fs := flag.NewFlagSet("decompose", flag.ExitOnError)
+	info := fs.String("i", "copy", "INFO fields to copy: copy, nonallelic (not Number=A, R or G) or drop")
+	format := fs.String("g", "copy", "FORMAT fields other than GT and PS to copy: copy, nonallelic or drop")
+	fs.Parse(args)
+	ip, found := fieldPolicies[*info]
+	if !found {
+		return fmt.Errorf("unknown policy %s", *info)
+	}
+	fp, found := fieldPolicies[*format]
+	if !found {
+		return fmt.Errorf("unknown policy %s", *format)
+	}
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	wtr, err := vcfgo.NewWriter(out, rdr.Header)
+	if err != nil {
+		return err
+	}
+	d := vcfgo.NewDecomposer(rdr)
+	d.Info, d.Format = ip, fp
+	for v := d.Read(); v != nil; v = d.Read() {
+		wtr.WriteVariant(v)
+	}
+	if err := rdr.Error(); err != nil {
+		return err
+	}
+	return d.Error()
+}
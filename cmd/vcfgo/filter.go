@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+// descEscaper escapes a header Description value.
+var descEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// runFilter writes the records that match, or with -e do not match, an
+// expression. With -s, every record is written and those that would
+// have been removed have the named filter added to FILTER.
+func runFilter(args []string) error {
+	fs := flag.NewFlagSet("filter", flag.ExitOnError)
+	include := fs.String("i", "", "write records that match this expression")
+	exclude := fs.String("e", "", "write records that do not match this expression")
+	soft := fs.String("s", "", "add this FILTER to records rather than removing them")
+	fs.Parse(args)
+	if (*include == "") == (*exclude == "") {
+		return errors.New("one of -i or -e is required")
+	}
+	src := *include + *exclude
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+	e, err := vcfgo.CompileExpr(src, rdr.Header)
+	if err != nil {
+		return err
+	}
+	if *soft != "" {
+		desc := "Set if true: " + src
+		if *include != "" {
+			desc = "Set if not true: " + src
+		}
+		rdr.Header.AddFilterLine(*soft, descEscaper.Replace(desc))
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	wtr, err := vcfgo.NewWriter(out, rdr.Header)
+	if err != nil {
+		return err
+	}
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		match, err := e.Eval(v)
+		if err != nil {
+			return err
+		}
+		if match == (*include != "") {
+			wtr.WriteVariant(v)
+		} else if *soft != "" {
+			if v.Filter == "." || v.Filter == "PASS" || v.Filter == "" {
+				v.Filter = *soft
+			} else {
+				v.Filter += ";" + *soft
+			}
+			wtr.WriteVariant(v)
+		}
+	}
+	return rdr.Error()
+}
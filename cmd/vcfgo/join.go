@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+var joinModes = map[string]vcfgo.JoinMode{
+	"any":    vcfgo.JoinAny,
+	"both":   vcfgo.JoinBoth,
+	"snps":   vcfgo.JoinSNPs,
+	"indels": vcfgo.JoinIndels,
+}
+
+// runJoin joins records at the same position into multiallelic records.
+func runJoin(args []string) error {
+	fs := flag.NewFlagSet("join", flag.ExitOnError)
+	mode := fs.String("m", "both", "records to join: any, both (SNPs and indels separately), snps or indels")
+	fs.Parse(args)
+	m, found := joinModes[*mode]
+	if !found {
+		return fmt.Errorf("unknown mode %s", *mode)
+	}
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	wtr, err := vcfgo.NewWriter(out, rdr.Header)
+	if err != nil {
+		return err
+	}
+	j := vcfgo.NewJoiner(rdr)
+	j.Mode = m
+	for v := j.Read(); v != nil; v = j.Read() {
+		wtr.WriteVariant(v)
+	}
+	if err := rdr.Error(); err != nil {
+		return err
+	}
+	return j.Error()
+}
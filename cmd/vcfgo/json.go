@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+// runJSON writes VCF as JSON lines or, with -r, JSON lines as VCF.
+func runJSON(args []string) error {
+	fs := flag.NewFlagSet("json", flag.ExitOnError)
+	reverse := fs.Bool("r", false, "read JSON lines and write VCF")
+	fs.Parse(args)
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	if *reverse {
+		jr, err := vcfgo.NewJSONReader(in)
+		if err != nil {
+			return err
+		}
+		wtr, err := vcfgo.NewWriter(out, jr.Header)
+		if err != nil {
+			return err
+		}
+		for v := jr.Read(); v != nil; v = jr.Read() {
+			wtr.WriteVariant(v)
+		}
+		return jr.Error()
+	}
+
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+	jw, err := vcfgo.NewJSONWriter(out, rdr.Header)
+	if err != nil {
+		return err
+	}
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		jw.WriteVariant(v)
+	}
+	if err := jw.Error(); err != nil {
+		return err
+	}
+	return rdr.Error()
+}
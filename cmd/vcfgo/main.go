@@ -0,0 +1,82 @@
+// Command vcfgo provides command line access to some of the operations
+// in the vcfgo package. Each operation is a subcommand:
+//
+//  vcfgo annotate -s source.vcf -f fields [-m mode] [in.vcf]
+//  vcfgo annotate -t table.tsv -c columns [-k key] [in.vcf]
+//  vcfgo bedpe [in.vcf]
+//  vcfgo checkref -f ref.fa [-fix] [-d] [in.vcf]
+//  vcfgo convert [options] [in.vcf]
+//  vcfgo decompose [-i policy] [-g policy] [in.vcf]
+//  vcfgo filter -i|-e expression [-s name] [in.vcf]
+//  vcfgo join [-m mode] [in.vcf]
+//  vcfgo json [-r] [in.vcf]
+//  vcfgo norm -f ref.fa [-w window] [in.vcf]
+//  vcfgo query -f format [options] [in.vcf]
+//  vcfgo reheader [options] [in.vcf]
+//
+// Input is read from the named file or from stdin if no file is given
+// and output is written to stdout.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]*command{
+	"annotate":  {"copy INFO fields from another VCF or a table", runAnnotate},
+	"bedpe":     {"write breakend records paired with their mates as BEDPE", runBEDPE},
+	"checkref":  {"check REF against a reference and fix swapped alleles", runCheckRef},
+	"convert":   {"convert between VCF versions", runConvert},
+	"decompose": {"break MNPs and complex records into SNPs and indels", runDecompose},
+	"filter":    {"include or exclude records with an expression", runFilter},
+	"join":      {"join records at the same position into multiallelic records", runJoin},
+	"json":      {"convert VCF to JSON lines or back with -r", runJSON},
+	"norm":      {"left-align and trim alleles against a reference", runNorm},
+	"query":     {"write fields as text using a template", runQuery},
+	"reheader":  {"rename, reorder or replace the samples and header", runReheader},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, found := commands[os.Args[1]]
+	if !found {
+		fmt.Fprintf(os.Stderr, "vcfgo: unknown command %s\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "vcfgo %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: vcfgo <command> [options] [in.vcf]")
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", n, commands[n].usage)
+	}
+}
+
+// openInput opens the first positional argument or returns stdin.
+func openInput(args []string) (io.ReadCloser, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(args[0])
+}
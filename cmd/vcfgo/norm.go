@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+// runNorm left-aligns and trims the alleles of each record against an
+// indexed FASTA and keeps the records sorted within a window.
+func runNorm(args []string) error {
+	fs := flag.NewFlagSet("norm", flag.ExitOnError)
+	fasta := fs.String("f", "", "reference FASTA, indexed with a .fai or indexed in memory")
+	window := fs.Uint64("w", 1000, "bases within which records are re-sorted")
+	fs.Parse(args)
+	if *fasta == "" {
+		return errors.New("-f is required")
+	}
+	ref, err := vcfgo.OpenFasta(*fasta)
+	if err != nil {
+		return err
+	}
+	defer ref.Close()
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	wtr, err := vcfgo.NewWriter(out, rdr.Header)
+	if err != nil {
+		return err
+	}
+	n := vcfgo.NewNormalizer(rdr, ref, *window)
+	for v := n.Read(); v != nil; v = n.Read() {
+		wtr.WriteVariant(v)
+	}
+	if err := rdr.Error(); err != nil {
+		return err
+	}
+	return n.Error()
+}
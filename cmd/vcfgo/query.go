@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+// runQuery writes fields from each record as text using a template in
+// the style of bcftools query.
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	format := fs.String("f", "", `template, e.g. "%CHROM\t%POS\t%REF\t%ALT[\t%SAMPLE=%GT]\n"`)
+	header := fs.Bool("H", false, "write a header row")
+	perAlt := fs.Bool("a", false, "write a line for each ALT allele")
+	csv := fs.Bool("csv", false, "quote values for CSV")
+	missing := fs.String("m", ".", "text for missing values")
+	fs.Parse(args)
+	if *format == "" {
+		return errors.New("-f is required")
+	}
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+	q, err := vcfgo.NewQuery(*format, rdr.Header)
+	if err != nil {
+		return err
+	}
+	q.PerAlt, q.CSV, q.Missing = *perAlt, *csv, *missing
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	if *header {
+		fmt.Fprint(out, q.HeaderRow())
+	}
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		if err := q.Write(out, v); err != nil {
+			return err
+		}
+	}
+	return rdr.Error()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/grendeloz/vcfgo"
+)
+
+func runReheader(args []string) error {
+	fs := flag.NewFlagSet("reheader", flag.ExitOnError)
+	samples := fs.String("samples", "", "two-column file of old and new sample names")
+	order := fs.String("order", "", "comma-separated sample names giving the new column order")
+	template := fs.String("header", "", "VCF whose header replaces the input header")
+	fs.Parse(args)
+
+	in, err := openInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rdr, err := vcfgo.NewReader(in, true)
+	if err != nil {
+		return err
+	}
+
+	rh := vcfgo.NewReheader(rdr.Header)
+	if *template != "" {
+		f, err := os.Open(*template)
+		if err != nil {
+			return err
+		}
+		trdr, err := vcfgo.NewReader(f, true)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if rh, err = vcfgo.NewReheaderFromTemplate(rdr.Header, trdr.Header); err != nil {
+			return err
+		}
+	}
+	if *samples != "" {
+		f, err := os.Open(*samples)
+		if err != nil {
+			return err
+		}
+		m, err := vcfgo.NewSampleMapFromReader(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if err = rh.Rename(m); err != nil {
+			return err
+		}
+	}
+	if *order != "" {
+		if err = rh.Reorder(strings.Split(*order, ",")); err != nil {
+			return err
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	wtr, err := vcfgo.NewWriter(out, rh.Header)
+	if err != nil {
+		return err
+	}
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		if err = rh.Apply(v); err != nil {
+			return err
+		}
+		wtr.WriteVariant(v)
+	}
+	return rdr.Error()
+}
@@ -0,0 +1,350 @@
+package vcfgo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The contig dictionary is the single source of truth for contig order
+// within vcfgo. VCF records should appear in the order that contigs are
+// declared in the ##contig lines of the header, and the same order is
+// used by sorting, validation and merging. A dictionary can be built
+// from the header, from a FASTA index (.fai) or from a Picard/SAM
+// sequence dictionary (.dict) and it can be written back into the
+// header with Header.SetContigs().
+
+var (
+	ErrContigNotFound  = errors.New("vcfgo: contig not found")
+	ErrDuplicateContig = errors.New("vcfgo: contig cannot be added multiple times")
+	ErrContigConflict  = errors.New("vcfgo: conflicting contig definitions")
+)
+
+// Contig holds the information from a single ##contig meta-information
+// line. Length is 0 if the length is not known. Any keys other than ID,
+// length, assembly, md5 and URL are kept in Extra in the order in which
+// they were observed.
+type Contig struct {
+	Name     string
+	Length   uint64
+	MD5      string
+	Assembly string
+	URL      string
+	Extra    []*KV
+}
+
+// ContigDict is an ordered collection of Contigs. The rank of a contig is
+// its 0-based position in the dictionary.
+type ContigDict struct {
+	contigs []*Contig
+	ranks   map[string]int
+}
+
+// NewContigDict returns an empty *ContigDict.
+func NewContigDict() *ContigDict {
+	return &ContigDict{contigs: make([]*Contig, 0, 64),
+		ranks: make(map[string]int)}
+}
+
+// NewContigFromMetaLine creates a Contig from a ##contig MetaLine.
+func NewContigFromMetaLine(m *MetaLine) (*Contig, error) {
+	if m.MetaType != Structured || m.LineKey != `contig` {
+		return nil, fmt.Errorf("%w - not a contig line: %s", ErrLinePattern, m.OgString)
+	}
+	c := &Contig{}
+	for _, k := range m.Order {
+		kv := m.KVs[k]
+		switch k {
+		case `ID`:
+			c.Name = kv.Value
+		case `length`:
+			l, err := strconv.ParseUint(kv.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad length for contig %s: %w", m.GetValue(`ID`), err)
+			}
+			c.Length = l
+		case `assembly`:
+			c.Assembly = kv.Value
+		case `md5`:
+			c.MD5 = kv.Value
+		case `URL`:
+			c.URL = kv.Value
+		default:
+			c.Extra = append(c.Extra, &KV{Key: kv.Key, Value: kv.Value, Quote: kv.Quote})
+		}
+	}
+	if c.Name == `` {
+		return nil, fmt.Errorf("contig line has no ID: %s", m.OgString)
+	}
+	return c, nil
+}
+
+// MetaLine returns a ##contig MetaLine for the Contig. Keys are written
+// in the order ID, length, assembly, md5, URL and then any Extra keys.
+func (c *Contig) MetaLine() *MetaLine {
+	m := NewMetaLine()
+	m.LineKey = `contig`
+	m.AddKV(`ID`, c.Name, 0)
+	if c.Length > 0 {
+		m.AddKV(`length`, strconv.FormatUint(c.Length, 10), 0)
+	}
+	if c.Assembly != `` {
+		m.AddKV(`assembly`, c.Assembly, 0)
+	}
+	if c.MD5 != `` {
+		m.AddKV(`md5`, c.MD5, 0)
+	}
+	if c.URL != `` {
+		m.AddKV(`URL`, c.URL, 0)
+	}
+	for _, kv := range c.Extra {
+		m.AddKV(kv.Key, kv.Value, kv.Quote)
+	}
+	return m
+}
+
+// Add appends a Contig to the end of the dictionary. Names must be
+// unique so ErrDuplicateContig is returned if the name is already
+// present.
+func (d *ContigDict) Add(c *Contig) error {
+	if _, found := d.ranks[c.Name]; found {
+		return fmt.Errorf("%w - %s", ErrDuplicateContig, c.Name)
+	}
+	d.ranks[c.Name] = len(d.contigs)
+	d.contigs = append(d.contigs, c)
+	return nil
+}
+
+// Get returns the Contig with the supplied name.
+func (d *ContigDict) Get(name string) (*Contig, bool) {
+	if r, found := d.ranks[name]; found {
+		return d.contigs[r], true
+	}
+	return nil, false
+}
+
+// Rank returns the 0-based position of the named contig in the
+// dictionary.
+func (d *ContigDict) Rank(name string) (int, bool) {
+	r, found := d.ranks[name]
+	return r, found
+}
+
+// Len returns the number of contigs in the dictionary.
+func (d *ContigDict) Len() int {
+	return len(d.contigs)
+}
+
+// Names returns the contig names in dictionary order.
+func (d *ContigDict) Names() []string {
+	names := make([]string, len(d.contigs))
+	for i, c := range d.contigs {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Contigs returns the Contigs in dictionary order. Note that the
+// pointers are to the Contigs held by the dictionary so if you change
+// them, you change the originals.
+func (d *ContigDict) Contigs() []*Contig {
+	out := make([]*Contig, len(d.contigs))
+	copy(out, d.contigs)
+	return out
+}
+
+// Compare returns -1, 0 or 1 depending on whether contig a sorts before,
+// with or after contig b. Contigs in the dictionary sort by rank and
+// contigs not in the dictionary sort after all known contigs in
+// lexical order.
+func (d *ContigDict) Compare(a, b string) int {
+	ra, oka := d.ranks[a]
+	rb, okb := d.ranks[b]
+	switch {
+	case oka && okb:
+		return compareInts(ra, rb)
+	case oka:
+		return -1
+	case okb:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// CompareVariants orders two Variants by contig rank and then by
+// position.
+func (d *ContigDict) CompareVariants(a, b *Variant) int {
+	if c := d.Compare(a.Chromosome, b.Chromosome); c != 0 {
+		return c
+	}
+	switch {
+	case a.Pos < b.Pos:
+		return -1
+	case a.Pos > b.Pos:
+		return 1
+	}
+	return 0
+}
+
+// Merge appends any contigs from other that are not already in the
+// dictionary. Contigs present in both must agree on length and md5 (when
+// both are known) otherwise ErrContigConflict is returned and the
+// dictionary is left unchanged.
+func (d *ContigDict) Merge(other *ContigDict) error {
+	var add []*Contig
+	for _, oc := range other.contigs {
+		c, found := d.Get(oc.Name)
+		if !found {
+			add = append(add, oc)
+			continue
+		}
+		if c.Length != 0 && oc.Length != 0 && c.Length != oc.Length {
+			return fmt.Errorf("%w - %s has length %d and %d", ErrContigConflict, c.Name, c.Length, oc.Length)
+		}
+		if c.MD5 != `` && oc.MD5 != `` && !strings.EqualFold(c.MD5, oc.MD5) {
+			return fmt.Errorf("%w - %s has md5 %s and %s", ErrContigConflict, c.Name, c.MD5, oc.MD5)
+		}
+	}
+	for _, c := range add {
+		d.Add(c)
+	}
+	return nil
+}
+
+// NewContigDictFromHeader builds a ContigDict from the ##contig lines in
+// Header.Lines.
+func NewContigDictFromHeader(h *Header) (*ContigDict, error) {
+	return newContigDictFromLines(h.Lines)
+}
+
+func newContigDictFromLines(lines []*MetaLine) (*ContigDict, error) {
+	d := NewContigDict()
+	for _, m := range lines {
+		if m.MetaType != Structured || m.LineKey != `contig` {
+			continue
+		}
+		c, err := NewContigFromMetaLine(m)
+		if err != nil {
+			return d, err
+		}
+		if err = d.Add(c); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+// NewContigDictFromFai builds a ContigDict from a samtools FASTA index
+// (.fai). Only the name and length columns are used.
+func NewContigDictFromFai(r io.Reader) (*ContigDict, error) {
+	d := NewContigDict()
+	scanner := bufio.NewScanner(r)
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == `` {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			return d, fmt.Errorf("fai error at line %d: too few fields", n)
+		}
+		l, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return d, fmt.Errorf("fai error at line %d: %w", n, err)
+		}
+		if err = d.Add(&Contig{Name: fields[0], Length: l}); err != nil {
+			return d, err
+		}
+	}
+	return d, scanner.Err()
+}
+
+// NewContigDictFromSamDict builds a ContigDict from the @SQ lines of a
+// Picard/SAM sequence dictionary (.dict). The SN, LN, M5, AS and UR tags
+// are mapped onto the Contig and SP (species) is kept in Extra.
+func NewContigDictFromSamDict(r io.Reader) (*ContigDict, error) {
+	d := NewContigDict()
+	scanner := bufio.NewScanner(r)
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if !strings.HasPrefix(line, "@SQ\t") {
+			continue
+		}
+		c := &Contig{}
+		for _, tag := range strings.Split(line, "\t")[1:] {
+			if len(tag) < 3 || tag[2] != ':' {
+				return d, fmt.Errorf("dict error at line %d: bad tag %s", n, tag)
+			}
+			v := tag[3:]
+			switch tag[:2] {
+			case `SN`:
+				c.Name = v
+			case `LN`:
+				l, err := strconv.ParseUint(v, 10, 64)
+				if err != nil {
+					return d, fmt.Errorf("dict error at line %d: %w", n, err)
+				}
+				c.Length = l
+			case `M5`:
+				c.MD5 = v
+			case `AS`:
+				c.Assembly = v
+			case `UR`:
+				c.URL = v
+			case `SP`:
+				c.Extra = append(c.Extra, &KV{Key: `species`, Value: v, Quote: '"'})
+			}
+		}
+		if c.Name == `` {
+			return d, fmt.Errorf("dict error at line %d: @SQ has no SN tag", n)
+		}
+		if err := d.Add(c); err != nil {
+			return d, err
+		}
+	}
+	return d, scanner.Err()
+}
+
+// SetContigs replaces the ##contig lines in Header.Lines with lines
+// generated from the ContigDict and makes it the Header's Contigs. The
+// new lines are placed where the first existing ##contig line was or,
+// if there were none, at the start of Lines.
+func (h *Header) SetContigs(d *ContigDict) {
+	pos := -1
+	lines := make([]*MetaLine, 0, len(h.Lines)+d.Len())
+	for _, m := range h.Lines {
+		if m.LineKey == `contig` {
+			if pos == -1 {
+				pos = len(lines)
+			}
+			continue
+		}
+		lines = append(lines, m)
+	}
+	if pos == -1 {
+		pos = 0
+	}
+	contigs := make([]*MetaLine, 0, d.Len())
+	for _, c := range d.contigs {
+		contigs = append(contigs, c.MetaLine())
+	}
+	h.Lines = append(lines[:pos], append(contigs, lines[pos:]...)...)
+	h.Contigs = d
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
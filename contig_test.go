@@ -0,0 +1,118 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var contigStr = `##fileformat=VCFv4.3
+##contig=<ID=chr2,length=242193529,assembly=GRCh38,md5=f98db672eb0993dcfdabafe2a882905c>
+##contig=<ID=chr1,length=248956422,assembly=GRCh38,species="Homo sapiens">
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Total Depth">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+chr2	100	.	A	G	.	PASS	DP=3
+`
+
+var faiStr = "chr1\t248956422\t112\t70\t71\nchr2\t242193529\t252513167\t70\t71\nchrM\t16569\t3099750718\t70\t71\n"
+
+var samDictStr = `@HD	VN:1.6	SO:unsorted
+@SQ	SN:chr1	LN:248956422	M5:6aef897c3d6ff0c78aff06ac189178dd	AS:GRCh38	UR:file:/ref/hg38.fa	SP:Homo sapiens
+@SQ	SN:chr2	LN:242193529	M5:f98db672eb0993dcfdabafe2a882905c	AS:GRCh38
+`
+
+type ContigSuite struct{}
+
+var _ = Suite(&ContigSuite{})
+
+func (s *ContigSuite) TestReaderContigs(c *C) {
+	rdr, err := NewReader(strings.NewReader(contigStr), false)
+	c.Assert(err, IsNil)
+	d := rdr.Header.Contigs
+	c.Assert(d.Names(), DeepEquals, []string{"chr2", "chr1"})
+
+	chr1, ok := d.Get("chr1")
+	c.Assert(ok, Equals, true)
+	c.Assert(chr1.Length, Equals, uint64(248956422))
+	c.Assert(chr1.Assembly, Equals, "GRCh38")
+	c.Assert(chr1.Extra, DeepEquals, []*KV{{Key: "species", Value: "Homo sapiens", Quote: '"'}})
+
+	r, ok := d.Rank("chr1")
+	c.Assert(ok, Equals, true)
+	c.Assert(r, Equals, 1)
+	c.Assert(d.Compare("chr2", "chr1"), Equals, -1)
+	c.Assert(d.Compare("chr1", "chrUn"), Equals, -1)
+	c.Assert(d.Compare("chrY", "chrX"), Equals, 1)
+	c.Assert(d.Compare("chr1", "chr1"), Equals, 0)
+}
+
+func (s *ContigSuite) TestMetaLineRoundTrip(c *C) {
+	str := `##contig=<ID=20,length=62435964,assembly=B36,md5=f126cdf8a6e0c7f379d618ff66beb2da,species="Homo sapiens",taxonomy=x>`
+	m, err := NewMetaLineFromString(str)
+	c.Assert(err, IsNil)
+	ctg, err := NewContigFromMetaLine(m)
+	c.Assert(err, IsNil)
+	out, err := ctg.MetaLine().String()
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, str)
+
+	m, err = NewMetaLineFromString(`##contig=<ID=20,length=abc>`)
+	c.Assert(err, IsNil)
+	_, err = NewContigFromMetaLine(m)
+	c.Assert(err, NotNil)
+}
+
+func (s *ContigSuite) TestFai(c *C) {
+	d, err := NewContigDictFromFai(strings.NewReader(faiStr))
+	c.Assert(err, IsNil)
+	c.Assert(d.Names(), DeepEquals, []string{"chr1", "chr2", "chrM"})
+	m, _ := d.Get("chrM")
+	c.Assert(m.Length, Equals, uint64(16569))
+
+	_, err = NewContigDictFromFai(strings.NewReader("chr1\tx\n"))
+	c.Assert(err, NotNil)
+	_, err = NewContigDictFromFai(strings.NewReader("chr1\t10\nchr1\t10\n"))
+	c.Assert(err, ErrorMatches, ".*cannot be added multiple times.*")
+}
+
+func (s *ContigSuite) TestSamDict(c *C) {
+	d, err := NewContigDictFromSamDict(strings.NewReader(samDictStr))
+	c.Assert(err, IsNil)
+	c.Assert(d.Len(), Equals, 2)
+	chr1, _ := d.Get("chr1")
+	c.Assert(chr1.MD5, Equals, "6aef897c3d6ff0c78aff06ac189178dd")
+	c.Assert(chr1.URL, Equals, "file:/ref/hg38.fa")
+	out, _ := chr1.MetaLine().String()
+	c.Assert(out, Equals, `##contig=<ID=chr1,length=248956422,assembly=GRCh38,md5=6aef897c3d6ff0c78aff06ac189178dd,URL=file:/ref/hg38.fa,species="Homo sapiens">`)
+}
+
+func (s *ContigSuite) TestMerge(c *C) {
+	d, _ := NewContigDictFromSamDict(strings.NewReader(samDictStr))
+	fai, _ := NewContigDictFromFai(strings.NewReader(faiStr))
+	c.Assert(d.Merge(fai), IsNil)
+	c.Assert(d.Names(), DeepEquals, []string{"chr1", "chr2", "chrM"})
+
+	bad := NewContigDict()
+	bad.Add(&Contig{Name: "chr1", Length: 10})
+	c.Assert(d.Merge(bad), ErrorMatches, ".*conflicting contig definitions.*")
+}
+
+func (s *ContigSuite) TestSetContigs(c *C) {
+	rdr, err := NewReader(strings.NewReader(contigStr), false)
+	c.Assert(err, IsNil)
+	fai, _ := NewContigDictFromFai(strings.NewReader(faiStr))
+	rdr.Header.SetContigs(fai)
+	c.Assert(rdr.Header.Contigs.Names(), DeepEquals, []string{"chr1", "chr2", "chrM"})
+
+	var buf bytes.Buffer
+	_, err = NewWriter(&buf, rdr.Header)
+	c.Assert(err, IsNil)
+	c.Assert(buf.String(), Equals, `##fileformat=VCFv4.3
+##contig=<ID=chr1,length=248956422>
+##contig=<ID=chr2,length=242193529>
+##contig=<ID=chrM,length=16569>
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Total Depth">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT
+`)
+}
@@ -0,0 +1,456 @@
+package vcfgo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Downstream tools disagree about which versions of VCF they accept so a
+// VersionConverter rewrites a stream of Variants from the version in the
+// input Header.FileFormat to a target version. Changes that do not lose
+// information (e.g. upgrading Number=3 to Number=G for PL) and changes
+// that do (e.g. downgrading Number=R to Number=.) are both recorded as
+// ConversionNotes so the caller can report them.
+//
+// The main differences handled are:
+//
+//  4.1 - adds Number=A and Number=G
+//  4.2 - adds Number=R, the * allele and the Source and Version keys
+//        in ##INFO lines
+//  4.3 - adds percent-encoding of INFO and FORMAT values, ##META lines
+//        and the <*> allele
+//  4.4 - adds Number=P, LA, LR and LG, INFO/SVCLAIM and makes SVLEN
+//        a positive Number=A length
+//  4.5 - nothing that needs converting
+
+// The VCF versions that a VersionConverter understands in order.
+var vcfVersions = []string{`4.0`, `4.1`, `4.2`, `4.3`, `4.4`, `4.5`}
+
+var ErrVCFVersion = errors.New("vcfgo: unsupported VCF version")
+
+// versionIndex returns the position of a version in vcfVersions or -1.
+// A leading VCFv is ignored.
+func versionIndex(v string) int {
+	v = strings.TrimPrefix(v, `VCFv`)
+	for i, s := range vcfVersions {
+		if s == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// Positions in vcfVersions of the versions that introduced features.
+var (
+	v41 = versionIndex(`4.1`)
+	v42 = versionIndex(`4.2`)
+	v43 = versionIndex(`4.3`)
+	v44 = versionIndex(`4.4`)
+)
+
+// reservedNumber is the Number of a reserved field and the version
+// from which files are expected to use it.
+type reservedNumber struct {
+	number string
+	since  string
+}
+
+// Reserved fields whose Number was not always A, G or R in older files.
+var (
+	reservedInfoNumbers = map[string]reservedNumber{
+		`AC`: {`A`, `4.1`}, `AF`: {`A`, `4.1`}, `AD`: {`R`, `4.2`},
+		`ADF`: {`R`, `4.2`}, `ADR`: {`R`, `4.2`}, `SVLEN`: {`A`, `4.4`},
+	}
+	reservedFormatNumbers = map[string]reservedNumber{
+		`GL`: {`G`, `4.1`}, `PL`: {`G`, `4.1`}, `GP`: {`G`, `4.1`},
+		`AD`: {`R`, `4.2`}, `ADF`: {`R`, `4.2`}, `ADR`: {`R`, `4.2`},
+	}
+)
+
+// Canonical spellings of the Type values, keyed by lower case.
+var canonicalTypes = map[string]string{
+	`integer`:   `Integer`,
+	`float`:     `Float`,
+	`flag`:      `Flag`,
+	`character`: `Character`,
+	`char`:      `Character`,
+	`string`:    `String`,
+}
+
+// ConversionNote describes one kind of change made by a VersionConverter
+// and how many times it was made.
+type ConversionNote struct {
+	Lossy   bool
+	Message string
+	Count   int
+}
+
+// String returns a string representation.
+func (n *ConversionNote) String() string {
+	s := n.Message
+	if n.Lossy {
+		s = `lossy: ` + s
+	}
+	if n.Count > 1 {
+		s += fmt.Sprintf(" (%d times)", n.Count)
+	}
+	return s
+}
+
+// VersionConverter converts Variants from one VCF version to another.
+// Header is the output Header - the input Header is not modified.
+type VersionConverter struct {
+	Header *Header
+	From   string
+	To     string
+
+	from, to    int
+	infoTypes   map[string]string
+	formatTypes map[string]string
+	notes       []*ConversionNote
+	noteIndex   map[string]*ConversionNote
+}
+
+// NewVersionConverter returns a *VersionConverter from the version of
+// the input Header to the target version, e.g. 4.2 or VCFv4.2. The
+// output Header is built immediately so any header changes are
+// available from Notes() before the first Variant is converted.
+func NewVersionConverter(in *Header, target string) (*VersionConverter, error) {
+	from := versionIndex(in.FileFormat)
+	if from == -1 {
+		return nil, fmt.Errorf("%w - %s", ErrVCFVersion, in.FileFormat)
+	}
+	to := versionIndex(target)
+	if to == -1 {
+		return nil, fmt.Errorf("%w - %s", ErrVCFVersion, target)
+	}
+
+	vc := &VersionConverter{
+		From:        vcfVersions[from],
+		To:          vcfVersions[to],
+		from:        from,
+		to:          to,
+		infoTypes:   make(map[string]string),
+		formatTypes: make(map[string]string),
+		noteIndex:   make(map[string]*ConversionNote),
+	}
+
+	out := in.shallowCopy()
+	out.FileFormat = vc.To
+	out.Lines = out.Lines[:0]
+	for _, m := range in.Lines {
+		if m = vc.convertLine(m); m != nil {
+			out.Lines = append(out.Lines, m)
+		}
+	}
+	vc.Header = out
+
+	// Infos and SampleFormats that have a line take the converted Number
+	// and Type from the line so that changes are only noted once.
+	out.Infos = make(map[string]*Info, len(in.Infos))
+	for id, i := range in.Infos {
+		ni := *i
+		ni.Number, ni.Type = vc.fieldNumberType(`INFO`, id, i.Number, i.Type)
+		out.Infos[id] = &ni
+	}
+	out.SampleFormats = make(map[string]*SampleFormat, len(in.SampleFormats))
+	for id, f := range in.SampleFormats {
+		nf := *f
+		nf.Number, nf.Type = vc.fieldNumberType(`FORMAT`, id, f.Number, f.Type)
+		out.SampleFormats[id] = &nf
+	}
+	return vc, nil
+}
+
+// Notes returns the changes made so far in the order they were first
+// made.
+func (vc *VersionConverter) Notes() []*ConversionNote {
+	return vc.notes
+}
+
+// Lossy reports whether any of the changes made so far lost
+// information.
+func (vc *VersionConverter) Lossy() bool {
+	for _, n := range vc.notes {
+		if n.Lossy {
+			return true
+		}
+	}
+	return false
+}
+
+func (vc *VersionConverter) note(lossy bool, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if n, found := vc.noteIndex[msg]; found {
+		n.Count++
+		return
+	}
+	n := &ConversionNote{Lossy: lossy, Message: msg, Count: 1}
+	vc.noteIndex[msg] = n
+	vc.notes = append(vc.notes, n)
+}
+
+// convertLine returns a converted copy of a MetaLine or nil if the line
+// should be dropped.
+func (vc *VersionConverter) convertLine(m *MetaLine) *MetaLine {
+	if m.LineKey == `META` && vc.to < v43 {
+		s, _ := m.String()
+		vc.note(true, "dropped %s - ##META lines need VCFv4.3", s)
+		return nil
+	}
+	if m.LineKey == `INFO` && m.GetValue(`ID`) == `SVCLAIM` && vc.to < v44 {
+		vc.note(true, "dropped INFO/SVCLAIM - needs VCFv4.4")
+		return nil
+	}
+	if m.MetaType != Structured || (m.LineKey != `INFO` && m.LineKey != `FORMAT`) {
+		return m
+	}
+
+	m = m.Clone()
+	id := m.GetValue(`ID`)
+	number, typ := vc.convertedNumberType(m.LineKey, id, m.GetValue(`Number`), m.GetValue(`Type`))
+	if kv, found := m.KVs[`Number`]; found {
+		kv.Value = number
+	}
+	if kv, found := m.KVs[`Type`]; found {
+		kv.Value = typ
+	}
+	if m.LineKey == `INFO` {
+		vc.infoTypes[id] = typ
+		if vc.to < v42 {
+			for _, k := range []string{`Source`, `Version`} {
+				if _, found := m.KVs[k]; found {
+					delete(m.KVs, k)
+					vc.note(true, "dropped %s from INFO/%s - needs VCFv4.2", k, id)
+				}
+			}
+			order := m.Order[:0]
+			for _, k := range m.Order {
+				if _, found := m.KVs[k]; found {
+					order = append(order, k)
+				}
+			}
+			m.Order = order
+		}
+	} else {
+		vc.formatTypes[id] = typ
+	}
+	return m
+}
+
+func (vc *VersionConverter) fieldNumberType(key, id, number, typ string) (string, string) {
+	if m, err := vc.Header.GetLineByTypeAndId(key, id); err == nil {
+		return m.GetValue(`Number`), m.GetValue(`Type`)
+	}
+	return vc.convertedNumberType(key, id, number, typ)
+}
+
+// convertedNumberType returns the Number and Type that a field should
+// have in the target version and notes any changes.
+func (vc *VersionConverter) convertedNumberType(key, id, number, typ string) (string, string) {
+	field := key + `/` + id
+
+	if t, found := canonicalTypes[strings.ToLower(typ)]; found && t != typ {
+		vc.note(false, "changed %s Type=%s to Type=%s", field, typ, t)
+		typ = t
+	}
+	if typ == `Flag` && number != `0` && number != `` {
+		vc.note(false, "changed %s Number=%s to Number=0 for Type=Flag", field, number)
+		number = `0`
+	}
+
+	// Upgrade reserved fields from files that predate their Number.
+	reserved := reservedInfoNumbers
+	if key == `FORMAT` {
+		reserved = reservedFormatNumbers
+	}
+	if r, found := reserved[id]; found && number != r.number && typ != `Flag` {
+		since := versionIndex(r.since)
+		if vc.from < since && vc.to >= since {
+			vc.note(false, "changed %s Number=%s to Number=%s", field, number, r.number)
+			number = r.number
+		}
+	}
+
+	// Downgrade Numbers the target lacks.
+	if versionIndex(numberSince(number)) > vc.to {
+		vc.note(true, "changed %s Number=%s to Number=. - VCFv%s has no Number=%s", field, number, vc.To, number)
+		number = `.`
+	}
+	return number, typ
+}
+
+// Convert converts a Variant to the target version and points it at the
+// output Header. It returns false if the Variant cannot be represented
+// in the target version and should be dropped.
+func (vc *VersionConverter) Convert(v *Variant) (bool, error) {
+	for i, a := range v.Alternate {
+		switch {
+		case a == `*` && vc.to < v42:
+			vc.note(true, "dropped records with a * allele - needs VCFv4.2")
+			return false, nil
+		case a == `<*>` && vc.to < v43:
+			vc.note(false, "changed <*> allele to <NON_REF>")
+			v.Alternate[i] = `<NON_REF>`
+		}
+	}
+	v.Header = vc.Header
+	if ib, ok := v.Info_.(*InfoByte); ok {
+		ib.header = vc.Header
+	}
+
+	if v.Info_ != nil && (vc.from < v44) != (vc.to < v44) {
+		if err := vc.convertSVLEN(v); err != nil {
+			return true, fmt.Errorf("%s:%d %v", v.Chromosome, v.Pos, err)
+		}
+	}
+	if v.Info_ != nil && vc.to < v44 {
+		if val, _ := v.Info_.Get(`SVCLAIM`); val != nil {
+			v.Info_.Delete(`SVCLAIM`)
+			vc.note(true, "dropped INFO/SVCLAIM values - needs VCFv4.4")
+		}
+	}
+
+	if !vc.percentChange() {
+		return true, nil
+	}
+
+	if v.Info_ != nil {
+		info, err := vc.convertInfo(v.Info_.Bytes())
+		if err != nil {
+			return true, fmt.Errorf("%s:%d %v", v.Chromosome, v.Pos, err)
+		}
+		v.Info_ = NewInfoByte(info, vc.Header)
+	}
+
+	for _, s := range v.Samples {
+		for _, f := range v.Format {
+			val, found := s.Fields[f]
+			if !found {
+				continue
+			}
+			nval, err := vc.convertValue(`FORMAT`, f, vc.formatTypes[f], val)
+			if err != nil {
+				return true, fmt.Errorf("%s:%d %v", v.Chromosome, v.Pos, err)
+			}
+			s.Fields[f] = nval
+		}
+	}
+	if v.sampleString != `` {
+		cols := strings.Split(v.sampleString, "\t")
+		for i, col := range cols {
+			vals := strings.Split(col, `:`)
+			for j, val := range vals {
+				if j >= len(v.Format) {
+					break
+				}
+				nval, err := vc.convertValue(`FORMAT`, v.Format[j], vc.formatTypes[v.Format[j]], val)
+				if err != nil {
+					return true, fmt.Errorf("%s:%d %v", v.Chromosome, v.Pos, err)
+				}
+				vals[j] = nval
+			}
+			cols[i] = strings.Join(vals, `:`)
+		}
+		v.sampleString = strings.Join(cols, "\t")
+	}
+	return true, nil
+}
+
+// convertSVLEN converts between the SVLEN of VCFv4.3 and earlier, which
+// is the signed difference in length between REF and ALT, and the SVLEN
+// of VCFv4.4 which is always a positive length.
+func (vc *VersionConverter) convertSVLEN(v *Variant) error {
+	val, _ := v.Info_.Get(`SVLEN`)
+	if val == nil {
+		return nil
+	}
+	vals := strings.Split(ItoS(`SVLEN`, val), `,`)
+	for i, s := range vals {
+		if s == `.` {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("bad SVLEN: %s", s)
+		}
+		if n < 0 {
+			n = -n
+		}
+		alt := v.Alternate[0]
+		if i < len(v.Alternate) {
+			alt = v.Alternate[i]
+		}
+		if vc.to < v44 && strings.HasPrefix(alt, `<DEL`) {
+			n = -n
+		}
+		vals[i] = strconv.Itoa(n)
+	}
+	v.Info_.Set(`SVLEN`, strings.Join(vals, `,`))
+	vc.note(false, "changed SVLEN to VCFv%s semantics", vc.To)
+	return nil
+}
+
+// percentChange reports whether the conversion crosses VCFv4.3 where
+// percent-encoding was introduced.
+func (vc *VersionConverter) percentChange() bool {
+	return (vc.from < v43) != (vc.to < v43)
+}
+
+func (vc *VersionConverter) convertInfo(info []byte) ([]byte, error) {
+	if len(info) == 0 {
+		return info, nil
+	}
+	pairs := strings.Split(string(info), `;`)
+	for i, pair := range pairs {
+		kv := strings.SplitN(pair, `=`, 2)
+		if len(kv) != 2 {
+			continue
+		}
+		val, err := vc.convertValue(`INFO`, kv[0], vc.infoTypes[kv[0]], kv[1])
+		if err != nil {
+			return info, err
+		}
+		pairs[i] = kv[0] + `=` + val
+	}
+	return []byte(strings.Join(pairs, `;`)), nil
+}
+
+// convertValue percent-encodes a value when upgrading to VCFv4.3 and
+// decodes it when downgrading. Numeric values and GT never need
+// changing. When downgrading, encoded characters that would break the
+// older format are left encoded and reported. A Character value that
+// stays encoded is no longer a single character so it is set to missing.
+func (vc *VersionConverter) convertValue(key, id, typ, val string) (string, error) {
+	if typ == `Integer` || typ == `Float` || typ == `Flag` || id == `GT` {
+		return val, nil
+	}
+	special := infoSpecialChars
+	if key == `FORMAT` {
+		special = formatSpecialChars
+	}
+
+	if vc.to >= v43 {
+		// In older versions only % can be present in a value without
+		// breaking the record.
+		return PercentEncode(val, `%`), nil
+	}
+
+	// The list separator is left encoded in every value because an
+	// older reader would split the value on it.
+	keep := strings.Replace(special, `%`, ``, 1)
+	nval, kept, err := percentDecodeOnly(val, keep)
+	if err != nil {
+		return val, fmt.Errorf("%s/%s: %v", key, id, err)
+	}
+	if kept {
+		if typ == `Character` {
+			vc.note(true, "set %s/%s to missing - encoded character cannot be represented in VCFv%s", key, id, vc.To)
+			return `.`, nil
+		}
+		vc.note(true, "left %s/%s value percent-encoded - decoded value cannot be represented in VCFv%s", key, id, vc.To)
+	}
+	return nval, nil
+}
@@ -0,0 +1,125 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var convert40Str = `##fileformat=VCFv4.0
+##INFO=<ID=AF,Number=.,Type=Float,Description="Allele Frequency">
+##INFO=<ID=NOTE,Number=1,Type=STRING,Description="Free text">
+##INFO=<ID=DB,Number=1,Type=Flag,Description="dbSNP membership">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=PL,Number=3,Type=Integer,Description="Phred-scaled genotype likelihoods">
+##FORMAT=<ID=AD,Number=.,Type=Integer,Description="Allelic depths">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1
+1	100	.	A	G	50	PASS	AF=0.5;NOTE=50%;DB	GT:PL:AD	0/1:10,0,20:5,5
+`
+
+var convert43Str = `##fileformat=VCFv4.3
+##META=<ID=Assay,Type=String,Number=.,Values=[WGS, WES]>
+##INFO=<ID=AD,Number=R,Type=Integer,Description="Allelic depths",Source="caller",Version="1">
+##INFO=<ID=NOTE,Number=1,Type=String,Description="Free text">
+##INFO=<ID=C,Number=1,Type=Character,Description="A character">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled genotype likelihoods">
+##FORMAT=<ID=FT,Number=1,Type=String,Description="Sample filter">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1
+1	100	.	A	G,<*>	50	PASS	AD=5,5,0;NOTE=a%3Bb%25;C=%3B	GT:PL:FT	0/1:10,0,20,30,30,30:x%3Ay
+1	200	.	A	G,*	50	PASS	AD=5,5,0	GT:PL:FT	0/1:10,0,20,30,30,30:PASS
+`
+
+type ConvertSuite struct{}
+
+var _ = Suite(&ConvertSuite{})
+
+func (s *ConvertSuite) convert(c *C, in string, target string, lazy bool) (*VersionConverter, string) {
+	rdr, err := NewReader(strings.NewReader(in), lazy)
+	c.Assert(err, IsNil)
+	vc, err := NewVersionConverter(rdr.Header, target)
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	wtr, err := NewWriter(&buf, vc.Header)
+	c.Assert(err, IsNil)
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		keep, err := vc.Convert(v)
+		c.Assert(err, IsNil)
+		if keep {
+			wtr.WriteVariant(v)
+		}
+	}
+	return vc, buf.String()
+}
+
+func (s *ConvertSuite) TestUpgrade(c *C) {
+	for _, lazy := range []bool{false, true} {
+		vc, out := s.convert(c, convert40Str, "VCFv4.3", lazy)
+		c.Assert(out, Equals, `##fileformat=VCFv4.3
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele Frequency">
+##INFO=<ID=NOTE,Number=1,Type=String,Description="Free text">
+##INFO=<ID=DB,Number=0,Type=Flag,Description="dbSNP membership">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled genotype likelihoods">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1
+1	100	.	A	G	50.0	PASS	AF=0.5;NOTE=50%25;DB	GT:PL:AD	0/1:10,0,20:5,5
+`)
+		c.Assert(vc.Lossy(), Equals, false)
+		c.Assert(len(vc.Notes()), Equals, 5)
+		c.Assert(vc.Notes()[1].String(), Equals, "changed INFO/NOTE Type=STRING to Type=String")
+	}
+}
+
+func (s *ConvertSuite) TestDowngrade(c *C) {
+	for _, lazy := range []bool{false, true} {
+		vc, out := s.convert(c, convert43Str, "4.1", lazy)
+		c.Assert(out, Equals, `##fileformat=VCFv4.1
+##INFO=<ID=AD,Number=.,Type=Integer,Description="Allelic depths">
+##INFO=<ID=NOTE,Number=1,Type=String,Description="Free text">
+##INFO=<ID=C,Number=1,Type=Character,Description="A character">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled genotype likelihoods">
+##FORMAT=<ID=FT,Number=1,Type=String,Description="Sample filter">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1
+1	100	.	A	G,<NON_REF>	50.0	PASS	AD=5,5,0;NOTE=a%3Bb%;C=.	GT:PL:FT	0/1:10,0,20,30,30,30:x%3Ay
+`)
+		c.Assert(vc.Lossy(), Equals, true)
+		var notes []string
+		for _, n := range vc.Notes() {
+			notes = append(notes, n.String())
+		}
+		c.Assert(notes, DeepEquals, []string{
+			"lossy: dropped ##META=<ID=Assay,Type=String,Number=.,Values=[WGS, WES]> - ##META lines need VCFv4.3",
+			"lossy: changed INFO/AD Number=R to Number=. - VCFv4.1 has no Number=R",
+			"lossy: dropped Source from INFO/AD - needs VCFv4.2",
+			"lossy: dropped Version from INFO/AD - needs VCFv4.2",
+			"changed <*> allele to <NON_REF>",
+			"lossy: left INFO/NOTE value percent-encoded - decoded value cannot be represented in VCFv4.1",
+			"lossy: set INFO/C to missing - encoded character cannot be represented in VCFv4.1",
+			"lossy: left FORMAT/FT value percent-encoded - decoded value cannot be represented in VCFv4.1",
+			"lossy: dropped records with a * allele - needs VCFv4.2",
+		})
+	}
+}
+
+func (s *ConvertSuite) TestVersions(c *C) {
+	rdr, err := NewReader(strings.NewReader(convert40Str), true)
+	c.Assert(err, IsNil)
+	_, err = NewVersionConverter(rdr.Header, "3.3")
+	c.Assert(err, ErrorMatches, ".*unsupported VCF version - 3.3")
+
+	vc, err := NewVersionConverter(rdr.Header, "4.0")
+	c.Assert(err, IsNil)
+	c.Assert(vc.From, Equals, "4.0")
+	c.Assert(rdr.Header.FileFormat, Equals, "4.0")
+	c.Assert(rdr.Header.Lines[1].GetValue("Type"), Equals, "STRING")
+
+	d, err := PercentDecode("a%3Bb%3d")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, "a;b=")
+	_, err = PercentDecode("50%")
+	c.Assert(err, NotNil)
+	c.Assert(PercentEncode("a:b;c", formatSpecialChars), Equals, "a%3Ab%3Bc")
+}
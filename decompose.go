@@ -0,0 +1,329 @@
+package vcfgo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OldComplex is the INFO field that Decompose adds to each record
+// decomposed from an MNP or complex record. It holds CHROM:POS:REF/ALT of
+// the original record.
+const OldComplex = `OLD_COMPLEX`
+
+// FieldPolicy controls which INFO or FORMAT fields of a decomposed
+// record are copied to its primitives.
+type FieldPolicy int
+
+const (
+	CopyFields     FieldPolicy = iota // EnumIndex = 0
+	CopyNonAllelic                    // EnumIndex = 1
+	DropFields                        // EnumIndex = 2
+)
+
+// String - Creating common behaviour - give the type a String function
+func (p FieldPolicy) String() string {
+	return [...]string{"CopyFields", "CopyNonAllelic", "DropFields"}[p]
+}
+
+// keep reports whether a field with Number number is copied.
+func (p FieldPolicy) keep(number string) bool {
+	switch p {
+	case CopyNonAllelic:
+		return number != `A` && number != `R` && number != `G`
+	case DropFields:
+		return false
+	}
+	return true
+}
+
+// primitive is a SNP or an indel at offset off in a REF allele.
+type primitive struct {
+	off      int
+	ref, alt string
+}
+
+// alignAlleles aligns REF and ALT with unit costs for a mismatch and a
+// gap and returns the SNPs and indels that turn REF into ALT. Indels are
+// anchored on the REF base before them or, at the start of REF, on the
+// REF base after them. An indel next to a SNP is anchored on the REF
+// base, so the primitives may overlap. Bases are compared ignoring case.
+func alignAlleles(ref, alt string) []primitive {
+	r, a := strings.ToUpper(ref), strings.ToUpper(alt)
+	n, m := len(r), len(a)
+	cost := make([][]int, n+1)
+	for i := range cost {
+		cost[i] = make([]int, m+1)
+		cost[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		cost[0][j] = j
+	}
+	sub := func(i, j int) int {
+		if r[i-1] == a[j-1] {
+			return 0
+		}
+		return 1
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			c := cost[i-1][j-1] + sub(i, j)
+			if d := cost[i-1][j] + 1; d < c {
+				c = d
+			}
+			if d := cost[i][j-1] + 1; d < c {
+				c = d
+			}
+			cost[i][j] = c
+		}
+	}
+
+	// Trace back, preferring matches and mismatches to gaps, and collect
+	// the operations in reverse: 'M', 'X', 'D' (a REF base) or 'I' (an
+	// ALT base), with the REF and ALT index of each.
+	type op struct {
+		kind byte
+		i, j int
+	}
+	var ops []op
+	for i, j := n, m; i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && cost[i][j] == cost[i-1][j-1]+sub(i, j):
+			kind := byte('M')
+			if sub(i, j) == 1 {
+				kind = 'X'
+			}
+			i, j = i-1, j-1
+			ops = append(ops, op{kind, i, j})
+		case i > 0 && cost[i][j] == cost[i-1][j]+1:
+			i--
+			ops = append(ops, op{'D', i, j})
+		default:
+			j--
+			ops = append(ops, op{'I', i, j})
+		}
+	}
+	for x, y := 0, len(ops)-1; x < y; x, y = x+1, y-1 {
+		ops[x], ops[y] = ops[y], ops[x]
+	}
+
+	var prims []primitive
+	for k := 0; k < len(ops); {
+		o := ops[k]
+		end := k + 1
+		for end < len(ops) && ops[end].kind == o.kind {
+			end++
+		}
+		switch o.kind {
+		case 'M':
+		case 'X':
+			for _, x := range ops[k:end] {
+				prims = append(prims, primitive{x.i, ref[x.i : x.i+1], alt[x.j : x.j+1]})
+			}
+		case 'D':
+			del := ref[o.i : o.i+end-k]
+			if o.i > 0 {
+				prims = append(prims, primitive{o.i - 1, ref[o.i-1:o.i] + del, ref[o.i-1 : o.i]})
+			} else if len(del) < len(ref) {
+				next := ref[len(del) : len(del)+1]
+				prims = append(prims, primitive{0, del + next, next})
+			}
+		case 'I':
+			ins := alt[o.j : o.j+end-k]
+			if o.i > 0 {
+				prims = append(prims, primitive{o.i - 1, ref[o.i-1 : o.i], ref[o.i-1:o.i] + ins})
+			} else {
+				prims = append(prims, primitive{0, ref[:1], ins + ref[:1]})
+			}
+		}
+		k = end
+	}
+	sort.SliceStable(prims, func(x, y int) bool { return prims[x].off < prims[y].off })
+	return prims
+}
+
+// Decompose breaks an MNP or a complex record, such as REF=ACGT
+// ALT=TCGA, into SNPs and indels found by aligning REF with each ALT
+// allele. Multiallelic records are split first. Each primitive is a copy
+// of its record with the OLD_COMPLEX INFO field set; the field is added
+// to the Header. A record that is already a SNP or an indel, or that has
+// a symbolic, breakend or * allele, is returned as it is.
+//
+// INFO and FORMAT fields other than GT and PS are copied to each
+// primitive as info and format say. As the primitives of a record are on
+// the same haplotype, PS is set to POS for samples with a phased GT that
+// have no PS, and is added to the Header if needed.
+func (v *Variant) Decompose(info, format FieldPolicy) ([]*Variant, error) {
+	var first error
+	vars := []*Variant{v}
+	if len(v.Alternate) > 1 {
+		vars, first = v.Split()
+	}
+	var out []*Variant
+	for _, s := range vars {
+		prims, err := s.decompose(info, format)
+		if err != nil && first == nil {
+			first = err
+		}
+		out = append(out, prims...)
+	}
+	return out, first
+}
+
+// decompose decomposes a record with one ALT allele.
+func (v *Variant) decompose(info, format FieldPolicy) ([]*Variant, error) {
+	if len(v.Alternate) != 1 {
+		return []*Variant{v}, nil
+	}
+	alt := v.Alternate[0]
+	if !IsSequenceAllele(alt) || v.Reference == `` {
+		return []*Variant{v}, nil
+	}
+	prims := alignAlleles(v.Reference, alt)
+	if len(prims) == 0 || (len(prims) == 1 && prims[0].off == 0 && prims[0].ref == v.Reference && prims[0].alt == alt) {
+		return []*Variant{v}, nil
+	}
+
+	var first error
+	if v.Header != nil {
+		first = v.Header.ParseSamples(v)
+	}
+	old := fmt.Sprintf("%s:%d:%s/%s", v.Chromosome, v.Pos, v.Reference, alt)
+	tmpl := v.Clone()
+	if tmpl.Header != nil {
+		tmpl.Header.AddInfoLine(OldComplex, `1`, `String`,
+			`Original CHROM:POS:REF/ALT of a decomposed MNP or complex record`)
+		tmpl.decomposeFields(info, format)
+		if err := tmpl.Info().Set(OldComplex, old); err != nil && first == nil {
+			first = err
+		}
+	}
+	out := make([]*Variant, len(prims))
+	for i, p := range prims {
+		c := tmpl.Clone()
+		c.Pos = v.Pos + uint64(p.off)
+		c.Reference, c.Alternate = p.ref, []string{p.alt}
+		out[i] = c
+	}
+	return out, first
+}
+
+// decomposeFields drops the INFO and FORMAT fields that the policies do
+// not copy and sets PS for phased samples.
+func (v *Variant) decomposeFields(info, format FieldPolicy) {
+	for _, k := range v.Info().Keys() {
+		if k == OldMultiallelic {
+			continue
+		}
+		number := `.`
+		if def, found := v.Header.Infos[k]; found {
+			number = def.Number
+		}
+		if !info.keep(number) {
+			v.Info().Delete(k)
+		}
+	}
+
+	var fields []string
+	for _, f := range v.Format {
+		number := `.`
+		if def, found := v.Header.SampleFormats[f]; found {
+			number = def.Number
+		}
+		if f == `GT` || f == `PS` || format.keep(number) {
+			fields = append(fields, f)
+			continue
+		}
+		for _, g := range v.Samples {
+			delete(g.Fields, f)
+			if f == `PL` || f == `GL` {
+				g.GL = g.GL[:0]
+			}
+		}
+	}
+	v.Format = fields
+
+	hasPS := false
+	for _, f := range v.Format {
+		hasPS = hasPS || f == `PS`
+	}
+	for _, g := range v.Samples {
+		if !phasedGT(g.Fields[`GT`]) || (g.Fields[`PS`] != `` && g.Fields[`PS`] != `.`) {
+			continue
+		}
+		if !hasPS {
+			v.Header.AddFormatLine(`PS`, `1`, `Integer`, `Phase set`)
+			v.Format = append(v.Format, `PS`)
+			for _, o := range v.Samples {
+				o.Fields[`PS`] = `.`
+			}
+			hasPS = true
+		}
+		g.Fields[`PS`] = strconv.FormatUint(v.Pos, 10)
+	}
+}
+
+// phasedGT reports whether a GT with more than one allele is phased.
+func phasedGT(gt string) bool {
+	return strings.Contains(gt, `|`) && !strings.Contains(gt, `/`)
+}
+
+// Decomposer reads Variants from a Reader and decomposes them with
+// Decompose. As the primitives of a record can be to the right of the
+// next record, Variants are held until a record further on is read, and
+// are returned sorted by position.
+type Decomposer struct {
+	Info   FieldPolicy
+	Format FieldPolicy
+
+	rdr   *Reader
+	buf   []*Variant
+	chrom string // of the last Variant read
+	pos   uint64 // of the last Variant read
+	eof   bool
+	verr  *VCFError
+}
+
+// NewDecomposer returns a Decomposer that copies all fields.
+func NewDecomposer(rdr *Reader) *Decomposer {
+	return &Decomposer{rdr: rdr, verr: NewVCFError()}
+}
+
+// Read returns the next Variant or nil at the end of the input. Errors
+// decomposing a Variant are added to those returned by Error.
+func (d *Decomposer) Read() *Variant {
+	for !d.eof && (len(d.buf) == 0 || (d.buf[0].Chromosome == d.chrom && d.buf[0].Pos >= d.pos)) {
+		v := d.rdr.Read()
+		if v == nil {
+			d.eof = true
+			break
+		}
+		d.chrom, d.pos = v.Chromosome, v.Pos
+		prims, err := v.Decompose(d.Info, d.Format)
+		d.verr.Add(err, v.LineNumber)
+		for _, p := range prims {
+			i := sort.Search(len(d.buf), func(i int) bool {
+				return d.buf[i].Chromosome == p.Chromosome && d.buf[i].Pos > p.Pos
+			})
+			d.buf = append(d.buf, nil)
+			copy(d.buf[i+1:], d.buf[i:])
+			d.buf[i] = p
+		}
+	}
+	if len(d.buf) == 0 {
+		return nil
+	}
+	v := d.buf[0]
+	d.buf = d.buf[1:]
+	return v
+}
+
+// Error returns the errors decomposing Variants. Errors reading the
+// input are returned by the Reader.
+func (d *Decomposer) Error() error {
+	if d.verr.IsEmpty() {
+		return nil
+	}
+	return d.verr
+}
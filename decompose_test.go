@@ -0,0 +1,83 @@
+package vcfgo
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var decomposeStr = `##fileformat=VCFv4.2
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Total depth">
+##INFO=<ID=AC,Number=A,Type=Integer,Description="Allele count">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths">
+##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Read depth">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	s1	s2
+chr1	100	mnp	ACGT	TCGA	.	PASS	DP=10;AC=1	GT:AD:DP	0|1:5,5:10	0/1:4,6:10
+chr1	102	snp	G	C	.	PASS	DP=8;AC=1	GT:AD:DP	0/1:4,4:8	0/0:8,0:8
+chr1	200	cplx	CAT	GATT,C	.	PASS	DP=9;AC=1,1	GT:AD:DP	1|2:0,4,5:9	0/0:9,0,0:9
+`
+
+type DecomposeSuite struct{}
+
+var _ = Suite(&DecomposeSuite{})
+
+func (s *DecomposeSuite) TestAlign(c *C) {
+	for _, t := range []struct {
+		ref, alt string
+		want     []primitive
+	}{
+		{"ACGT", "TCGA", []primitive{{0, "A", "T"}, {3, "T", "A"}}},
+		{"ACGT", "AGT", []primitive{{0, "AC", "A"}}},
+		{"CAT", "GATT", []primitive{{0, "C", "G"}, {1, "A", "AT"}}},
+		{"CCAT", "AT", []primitive{{0, "CCA", "A"}}},
+		{"A", "CA", []primitive{{0, "A", "CA"}}},
+		{"acgt", "ACGT", nil},
+	} {
+		c.Check(alignAlleles(t.ref, t.alt), DeepEquals, t.want, Commentf("%s/%s", t.ref, t.alt))
+	}
+}
+
+func (s *DecomposeSuite) TestDecomposer(c *C) {
+	rdr, err := NewReader(strings.NewReader(decomposeStr), true)
+	c.Assert(err, IsNil)
+	d := NewDecomposer(rdr)
+	var out []string
+	for v := d.Read(); v != nil; v = d.Read() {
+		out = append(out, v.String())
+	}
+	c.Assert(d.Error(), IsNil)
+	c.Assert(out, DeepEquals, []string{
+		"chr1\t100\tmnp\tA\tT\t.\tPASS\tDP=10;AC=1;OLD_COMPLEX=chr1:100:ACGT/TCGA\tGT:AD:DP:PS\t0|1:5,5:10:100\t0/1:4,6:10:.",
+		"chr1\t102\tsnp\tG\tC\t.\tPASS\tDP=8;AC=1\tGT:AD:DP\t0/1:4,4:8\t0/0:8,0:8",
+		"chr1\t103\tmnp\tT\tA\t.\tPASS\tDP=10;AC=1;OLD_COMPLEX=chr1:100:ACGT/TCGA\tGT:AD:DP:PS\t0|1:5,5:10:100\t0/1:4,6:10:.",
+		"chr1\t200\tcplx\tC\tG\t.\tPASS\tDP=9;AC=1;OLD_MULTIALLELIC=chr1:200:CAT/GATT/C;OLD_COMPLEX=chr1:200:CAT/GATT" +
+			"\tGT:AD:DP:PS\t1|.:0,4:9:200\t0/0:9,0:9:.",
+		"chr1\t200\tcplx\tCAT\tC\t.\tPASS\tDP=9;AC=1;OLD_MULTIALLELIC=chr1:200:CAT/GATT/C\tGT:AD:DP\t.|1:0,5:9\t0/0:9,0:9",
+		"chr1\t201\tcplx\tA\tAT\t.\tPASS\tDP=9;AC=1;OLD_MULTIALLELIC=chr1:200:CAT/GATT/C;OLD_COMPLEX=chr1:200:CAT/GATT" +
+			"\tGT:AD:DP:PS\t1|.:0,4:9:200\t0/0:9,0:9:.",
+	})
+	c.Assert(rdr.Header.Infos[OldComplex].Number, Equals, "1")
+	c.Assert(rdr.Header.SampleFormats["PS"].Type, Equals, "Integer")
+}
+
+func (s *DecomposeSuite) TestPolicy(c *C) {
+	rdr, err := NewReader(strings.NewReader(decomposeStr), false)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	prims, err := v.Decompose(CopyNonAllelic, CopyNonAllelic)
+	c.Assert(err, IsNil)
+	c.Assert(prims, HasLen, 2)
+	c.Assert(prims[1].String(), Equals, "chr1\t103\tmnp\tT\tA\t.\tPASS\tDP=10;OLD_COMPLEX=chr1:100:ACGT/TCGA\tGT:DP:PS\t0|1:10:100\t0/1:10:.")
+	prims, err = v.Decompose(DropFields, DropFields)
+	c.Assert(err, IsNil)
+	c.Assert(prims[0].String(), Equals, "chr1\t100\tmnp\tA\tT\t.\tPASS\tOLD_COMPLEX=chr1:100:ACGT/TCGA\tGT:PS\t0|1:100\t0/1:.")
+	// The record itself is unchanged.
+	c.Assert(v.String(), Equals, "chr1\t100\tmnp\tACGT\tTCGA\t.\tPASS\tDP=10;AC=1\tGT:AD:DP\t0|1:5,5:10\t0/1:4,6:10")
+	c.Assert(DropFields.String(), Equals, "DropFields")
+
+	v = rdr.Read()
+	prims, err = v.Decompose(CopyFields, CopyFields)
+	c.Assert(err, IsNil)
+	c.Assert(prims, DeepEquals, []*Variant{v})
+}
@@ -0,0 +1,986 @@
+package vcfgo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrExpr is returned by CompileExpr for an expression that does not
+// parse or does not type-check against the Header.
+var ErrExpr = errors.New("vcfgo: bad expression")
+
+// An Expr is a compiled expression that decides whether to include a
+// Variant, for example:
+//
+//  QUAL>=30 && INFO/DP>10 && FILTER=="PASS" && count(FMT/GT=="het")>2
+//
+// Terms are:
+//
+//  CHROM POS ID REF QUAL   the fixed columns; QUAL is missing if it is .
+//  ALT FILTER              the ALT alleles and FILTER values, one per value
+//  N_ALT N_SAMPLES         the number of ALT alleles and of samples
+//  INFO/TAG                an INFO field
+//  FMT/TAG, FORMAT/TAG     a FORMAT field, the values of every sample
+//  TAG                     INFO/TAG if it is in the Header, else FMT/TAG
+//  TAG[n]                  the n'th (0-based) value of a field; for a
+//                          FORMAT field, the n'th value of each sample
+//
+// as well as numbers and double- or single-quoted strings. Operators, in
+// increasing order of precedence, are || and &&, the comparisons == (or
+// =), !=, <, <=, >, >=, ~ and !~ (regular expression match), + and -, *
+// and /, and the unary ! and -. A FMT/GT compared with "het", "hom",
+// "ref", "alt", "mis" or "hap" tests the kind of genotype rather than the
+// GT string.
+//
+// A field can have many values. Comparisons and arithmetic are done on
+// each value, pairing values or pairing each value with a single value,
+// and the resulting list is true if any of it is true. The functions
+// any(), all() and count() take a comparison and give whether any or all
+// of it is true and how much of it is true. sum(), min(), max() and
+// mean() take a number and ignore missing values. missing() is true if a
+// field is absent or all of its values are missing.
+//
+// Missing values, and fields that are absent, are never equal to,
+// less than or greater than anything, so any comparison with them is
+// false, and arithmetic with them gives a missing value.
+//
+// Fields are type-checked against the Header when the expression is
+// compiled: INFO and FORMAT fields must be in the Header, a Flag can only
+// be used as a condition, numbers can only be compared with numbers and
+// strings with strings. If the expression has no FORMAT terms, lazily
+// read samples are not parsed.
+type Expr struct {
+	src     string
+	root    exprNode
+	samples bool
+}
+
+// CompileExpr parses an expression and type-checks it against a Header.
+func CompileExpr(src string, h *Header) (*Expr, error) {
+	toks, err := exprTokens(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks, h: h}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEnd {
+		return nil, p.errorf("unexpected %s", p.peek().text)
+	}
+	if root.kind() != kindBool {
+		return nil, fmt.Errorf("%w - %s is not a condition", ErrExpr, src)
+	}
+	return &Expr{src: src, root: root, samples: p.samples}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval returns whether the Variant matches the expression. Samples are
+// only parsed if the expression has a FORMAT term.
+func (e *Expr) Eval(v *Variant) (bool, error) {
+	if e.samples {
+		// Samples that do not parse cleanly still have their Fields.
+		v.Header.ParseSamples(v)
+	}
+	val, err := e.root.eval(v)
+	if err != nil {
+		return false, err
+	}
+	return val.any(), nil
+}
+
+// exprKind is the type of the value of an expression node.
+type exprKind int
+
+const (
+	kindNum exprKind = iota
+	kindStr
+	kindBool
+)
+
+// String - Creating common behaviour - give the type a String function
+func (k exprKind) String() string {
+	return [...]string{"number", "string", "condition"}[k]
+}
+
+// exprValue is a list of values of one kind. miss marks missing values.
+type exprValue struct {
+	num  []float64
+	str  []string
+	bool []bool
+	miss []bool
+}
+
+func (x exprValue) len(k exprKind) int {
+	switch k {
+	case kindNum:
+		return len(x.num)
+	case kindStr:
+		return len(x.str)
+	}
+	return len(x.bool)
+}
+
+func (x exprValue) any() bool {
+	for _, b := range x.bool {
+		if b {
+			return true
+		}
+	}
+	return false
+}
+
+func boolValue(b bool) exprValue {
+	return exprValue{bool: []bool{b}}
+}
+
+type exprNode interface {
+	kind() exprKind
+	eval(v *Variant) (exprValue, error)
+}
+
+// Tokens
+
+type tokKind int
+
+const (
+	tokEnd tokKind = iota
+	tokNum
+	tokStr
+	tokIdent
+	tokOp
+)
+
+type exprToken struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+var exprOps = []string{`&&`, `||`, `==`, `!=`, `<=`, `>=`, `!~`, `=`, `<`, `>`, `~`, `!`, `+`, `-`, `*`, `/`, `(`, `)`, `[`, `]`, `,`}
+
+func isIdentByte(c byte, first bool) bool {
+	if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+	return !first && ((c >= '0' && c <= '9') || c == '/' || c == '.')
+}
+
+func exprTokens(src string) ([]exprToken, error) {
+	var toks []exprToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end == -1 {
+				return nil, fmt.Errorf("%w - unterminated string at %d", ErrExpr, i)
+			}
+			toks = append(toks, exprToken{tokStr, src[i+1 : i+1+end], i})
+			i += end + 2
+		case (c >= '0' && c <= '9') || (c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9'):
+			end := i
+			for end < len(src) && (strings.IndexByte("0123456789.eE", src[end]) != -1 ||
+				((src[end] == '-' || src[end] == '+') && (src[end-1] == 'e' || src[end-1] == 'E'))) {
+				end++
+			}
+			toks = append(toks, exprToken{tokNum, src[i:end], i})
+			i = end
+		case isIdentByte(c, true):
+			end := i + 1
+			for end < len(src) && isIdentByte(src[end], false) {
+				end++
+			}
+			toks = append(toks, exprToken{tokIdent, src[i:end], i})
+			i = end
+		default:
+			found := false
+			for _, op := range exprOps {
+				if strings.HasPrefix(src[i:], op) {
+					toks = append(toks, exprToken{tokOp, op, i})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%w - unexpected %q at %d", ErrExpr, c, i)
+			}
+		}
+	}
+	return append(toks, exprToken{tokEnd, `end of expression`, len(src)}), nil
+}
+
+// Parser
+
+type exprParser struct {
+	toks    []exprToken
+	pos     int
+	h       *Header
+	samples bool
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.toks[p.pos]
+	if t.kind != tokEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("%w - at %d: %s", ErrExpr, p.peek().pos, fmt.Sprintf(format, a...))
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseLogical(`||`, p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseLogical(`&&`, p.parseCompare)
+}
+
+func (p *exprParser) parseLogical(op string, sub func() (exprNode, error)) (exprNode, error) {
+	l, err := sub()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(op) {
+		r, err := sub()
+		if err != nil {
+			return nil, err
+		}
+		if l.kind() != kindBool || r.kind() != kindBool {
+			return nil, p.errorf("%s needs conditions", op)
+		}
+		l = &logicalNode{op, l, r}
+	}
+	return l, nil
+}
+
+var compareOps = map[string]bool{`==`: true, `=`: true, `!=`: true, `<`: true, `<=`: true,
+	`>`: true, `>=`: true, `~`: true, `!~`: true}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	l, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokOp || !compareOps[t.text] {
+		return l, nil
+	}
+	p.next()
+	op := t.text
+	if op == `=` {
+		op = `==`
+	}
+	r, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if l.kind() == kindBool || r.kind() == kindBool {
+		return nil, p.errorf("cannot compare a condition with %s", op)
+	}
+	if l.kind() != r.kind() {
+		return nil, p.errorf("cannot compare a %s with a %s", l.kind(), r.kind())
+	}
+	if op == `~` || op == `!~` {
+		lit, ok := r.(*literalNode)
+		if !ok || l.kind() != kindStr {
+			return nil, p.errorf("%s needs a string and a literal regular expression", op)
+		}
+		re, err := regexp.Compile(lit.val.str[0])
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		return &matchNode{op == `!~`, l, re}, nil
+	}
+	if l.kind() == kindStr && op != `==` && op != `!=` {
+		return nil, p.errorf("strings can only be compared with ==, !=, ~ and !~")
+	}
+	if f, ok := l.(*fieldNode); ok && f.tag == `GT` && f.format {
+		if lit, ok := r.(*literalNode); ok && gtClasses[lit.val.str[0]] != nil {
+			return &gtClassNode{op == `!=`, f, gtClasses[lit.val.str[0]]}, nil
+		}
+	}
+	return &compareNode{op, l, r}, nil
+}
+
+func (p *exprParser) parseAdd() (exprNode, error) {
+	return p.parseArith([]string{`+`, `-`}, p.parseMul)
+}
+
+func (p *exprParser) parseMul() (exprNode, error) {
+	return p.parseArith([]string{`*`, `/`}, p.parseUnary)
+}
+
+func (p *exprParser) parseArith(ops []string, sub func() (exprNode, error)) (exprNode, error) {
+	l, err := sub()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ``
+		for _, o := range ops {
+			if p.accept(o) {
+				op = o
+				break
+			}
+		}
+		if op == `` {
+			return l, nil
+		}
+		r, err := sub()
+		if err != nil {
+			return nil, err
+		}
+		if l.kind() != kindNum || r.kind() != kindNum {
+			return nil, p.errorf("%s needs numbers", op)
+		}
+		l = &arithNode{op, l, r}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept(`!`) {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if n.kind() != kindBool {
+			return nil, p.errorf("! needs a condition")
+		}
+		return &notNode{n}, nil
+	}
+	if p.accept(`-`) {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if n.kind() != kindNum {
+			return nil, p.errorf("- needs a number")
+		}
+		return &arithNode{`-`, &literalNode{kindNum, exprValue{num: []float64{0}, miss: []bool{false}}}, n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNum:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w - bad number %s at %d", ErrExpr, t.text, t.pos)
+		}
+		return &literalNode{kindNum, exprValue{num: []float64{f}, miss: []bool{false}}}, nil
+	case tokStr:
+		return &literalNode{kindStr, exprValue{str: []string{t.text}, miss: []bool{false}}}, nil
+	case tokOp:
+		if t.text == `(` {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(`)`) {
+				return nil, p.errorf("expected )")
+			}
+			return n, nil
+		}
+	case tokIdent:
+		if p.accept(`(`) {
+			return p.parseFunc(t)
+		}
+		return p.parseField(t)
+	}
+	return nil, fmt.Errorf("%w - unexpected %s at %d", ErrExpr, t.text, t.pos)
+}
+
+func (p *exprParser) parseFunc(t exprToken) (exprNode, error) {
+	fn := exprFuncs[t.text]
+	if fn == nil {
+		return nil, fmt.Errorf("%w - unknown function %s at %d", ErrExpr, t.text, t.pos)
+	}
+	arg, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(`)`) {
+		return nil, p.errorf("expected )")
+	}
+	if t.text == `missing` {
+		if _, ok := arg.(*fieldNode); !ok {
+			return nil, fmt.Errorf("%w - missing() needs a field at %d", ErrExpr, t.pos)
+		}
+	} else if arg.kind() != fn.arg {
+		return nil, fmt.Errorf("%w - %s() needs a %s at %d", ErrExpr, t.text, fn.arg, t.pos)
+	}
+	return &funcNode{t.text, fn, arg}, nil
+}
+
+// Fixed columns and their kinds.
+var exprColumns = map[string]exprKind{`CHROM`: kindStr, `POS`: kindNum, `ID`: kindStr,
+	`REF`: kindStr, `ALT`: kindStr, `QUAL`: kindNum, `FILTER`: kindStr,
+	`N_ALT`: kindNum, `N_SAMPLES`: kindNum}
+
+func (p *exprParser) parseField(t exprToken) (exprNode, error) {
+	f := &fieldNode{name: t.text, index: -1}
+	if k, found := exprColumns[t.text]; found {
+		f.k = k
+		return f, nil
+	}
+	tag := t.text
+	switch {
+	case strings.HasPrefix(tag, `INFO/`):
+		tag = tag[5:]
+	case strings.HasPrefix(tag, `FMT/`), strings.HasPrefix(tag, `FORMAT/`):
+		tag = tag[strings.IndexByte(tag, '/')+1:]
+		f.format = true
+	default:
+		_, found := p.h.Infos[tag]
+		f.format = !found
+	}
+	f.tag = tag
+	typ, number := ``, ``
+	if f.format {
+		if sf, found := p.h.SampleFormats[tag]; found {
+			typ, number = sf.Type, sf.Number
+		} else if tag == `GT` {
+			typ, number = `String`, `1`
+		} else {
+			return nil, fmt.Errorf("%w - %s is not in the header at %d", ErrExpr, t.text, t.pos)
+		}
+		p.samples = true
+	} else {
+		i, found := p.h.Infos[tag]
+		if !found {
+			return nil, fmt.Errorf("%w - %s is not in the header at %d", ErrExpr, t.text, t.pos)
+		}
+		typ, number = i.Type, i.Number
+	}
+	switch typ {
+	case `Integer`, `Float`:
+		f.k = kindNum
+	case `Flag`:
+		f.k = kindBool
+	default:
+		f.k = kindStr
+	}
+	if p.accept(`[`) {
+		n := p.next()
+		i, err := strconv.Atoi(n.text)
+		if n.kind != tokNum || err != nil || i < 0 {
+			return nil, fmt.Errorf("%w - bad index %s at %d", ErrExpr, n.text, n.pos)
+		}
+		if !p.accept(`]`) {
+			return nil, p.errorf("expected ]")
+		}
+		if f.k == kindBool {
+			return nil, fmt.Errorf("%w - Flag %s cannot be indexed at %d", ErrExpr, t.text, t.pos)
+		}
+		if count, err := strconv.Atoi(number); err == nil && count > 0 && i >= count {
+			return nil, fmt.Errorf("%w - %s has Number=%s so [%d] is out of range at %d", ErrExpr, t.text, number, i, t.pos)
+		}
+		f.index = i
+	}
+	return f, nil
+}
+
+// Nodes
+
+type literalNode struct {
+	k   exprKind
+	val exprValue
+}
+
+func (n *literalNode) kind() exprKind                     { return n.k }
+func (n *literalNode) eval(v *Variant) (exprValue, error) { return n.val, nil }
+
+// fieldNode is a fixed column or an INFO or FORMAT field.
+type fieldNode struct {
+	name   string
+	tag    string
+	format bool
+	index  int
+	k      exprKind
+}
+
+func (n *fieldNode) kind() exprKind { return n.k }
+
+func (n *fieldNode) eval(v *Variant) (exprValue, error) {
+	var raw []string
+	switch n.name {
+	case `CHROM`:
+		raw = []string{v.Chromosome}
+	case `POS`:
+		raw = []string{strconv.FormatUint(v.Pos, 10)}
+	case `ID`:
+		raw = []string{v.Id_}
+	case `REF`:
+		raw = []string{v.Reference}
+	case `ALT`:
+		raw = v.Alternate
+	case `QUAL`:
+		raw = []string{`.`}
+		if v.Quality != MISSING_VAL {
+			raw[0] = strconv.FormatFloat(float64(v.Quality), 'g', -1, 32)
+		}
+	case `FILTER`:
+		raw = strings.Split(v.Filter, `;`)
+	case `N_ALT`:
+		raw = []string{strconv.Itoa(len(v.Alternate))}
+	case `N_SAMPLES`:
+		raw = []string{strconv.Itoa(len(v.Header.SampleNames))}
+	default:
+		if n.format {
+			return n.evalFormat(v)
+		}
+		return n.evalInfo(v)
+	}
+	return n.values(v, raw, false)
+}
+
+func (n *fieldNode) evalInfo(v *Variant) (exprValue, error) {
+	info := NewInfoByte(v.Info().Bytes(), nil)
+	value := info.SGet(n.tag)
+	if n.k == kindBool {
+		return boolValue(len(value) > 0), nil
+	}
+	if len(value) == 0 || string(value) == n.tag {
+		return exprValue{}, nil
+	}
+	raw := strings.Split(string(value), `,`)
+	if n.index >= 0 {
+		if n.index >= len(raw) {
+			return n.values(v, []string{`.`}, v.Header.percentEncoded())
+		}
+		raw = raw[n.index : n.index+1]
+	}
+	return n.values(v, raw, v.Header.percentEncoded())
+}
+
+func (n *fieldNode) evalFormat(v *Variant) (exprValue, error) {
+	var raw []string
+	for _, g := range v.Samples {
+		value, found := g.Fields[n.tag]
+		if !found || n.tag == `GT` || n.k == kindBool {
+			raw = append(raw, value)
+			continue
+		}
+		vals := strings.Split(value, `,`)
+		if n.index >= 0 {
+			if n.index < len(vals) {
+				raw = append(raw, vals[n.index])
+			} else {
+				raw = append(raw, `.`)
+			}
+			continue
+		}
+		raw = append(raw, vals...)
+	}
+	return n.values(v, raw, v.Header.percentEncoded() && !v.Header.decoding())
+}
+
+// values converts the text of a field to values of its kind.
+func (n *fieldNode) values(v *Variant, raw []string, decode bool) (exprValue, error) {
+	var x exprValue
+	x.miss = make([]bool, len(raw))
+	switch n.k {
+	case kindNum:
+		x.num = make([]float64, len(raw))
+		for i, s := range raw {
+			if s == `.` || s == `` {
+				x.miss[i] = true
+				continue
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return x, fmt.Errorf("%s: %v", n.name, err)
+			}
+			x.num[i] = f
+		}
+	case kindStr:
+		x.str = make([]string, len(raw))
+		for i, s := range raw {
+			if s == `` || (s == `.` && n.tag != ``) {
+				x.miss[i] = true
+				continue
+			}
+			if decode {
+				if d, err := PercentDecode(s); err == nil {
+					s = d
+				}
+			}
+			x.str[i] = s
+		}
+	case kindBool:
+		x.bool = make([]bool, len(raw))
+		for i, s := range raw {
+			x.bool[i] = s != `` && s != `.`
+		}
+	}
+	return x, nil
+}
+
+// pair returns the length of the result of an operation on two lists and
+// an error if they cannot be paired.
+func pair(l, r int) (int, error) {
+	switch {
+	case l == r:
+		return l, nil
+	case l == 1:
+		return r, nil
+	case r == 1:
+		return l, nil
+	case l == 0 || r == 0:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("cannot pair %d values with %d values", l, r)
+}
+
+func at(i, n int) int {
+	if n == 1 {
+		return 0
+	}
+	return i
+}
+
+type compareNode struct {
+	op   string
+	l, r exprNode
+}
+
+func (n *compareNode) kind() exprKind { return kindBool }
+
+func (n *compareNode) eval(v *Variant) (exprValue, error) {
+	l, err := n.l.eval(v)
+	if err != nil {
+		return l, err
+	}
+	r, err := n.r.eval(v)
+	if err != nil {
+		return r, err
+	}
+	k := n.l.kind()
+	ln, rn := l.len(k), r.len(k)
+	count, err := pair(ln, rn)
+	if err != nil {
+		return exprValue{}, err
+	}
+	out := exprValue{bool: make([]bool, count)}
+	for i := range out.bool {
+		li, ri := at(i, ln), at(i, rn)
+		if l.miss[li] || r.miss[ri] {
+			continue
+		}
+		var c int
+		if k == kindNum {
+			switch {
+			case l.num[li] < r.num[ri]:
+				c = -1
+			case l.num[li] > r.num[ri]:
+				c = 1
+			}
+		} else {
+			c = strings.Compare(l.str[li], r.str[ri])
+		}
+		switch n.op {
+		case `==`:
+			out.bool[i] = c == 0
+		case `!=`:
+			out.bool[i] = c != 0
+		case `<`:
+			out.bool[i] = c < 0
+		case `<=`:
+			out.bool[i] = c <= 0
+		case `>`:
+			out.bool[i] = c > 0
+		case `>=`:
+			out.bool[i] = c >= 0
+		}
+	}
+	return out, nil
+}
+
+type matchNode struct {
+	not bool
+	l   exprNode
+	re  *regexp.Regexp
+}
+
+func (n *matchNode) kind() exprKind { return kindBool }
+
+func (n *matchNode) eval(v *Variant) (exprValue, error) {
+	l, err := n.l.eval(v)
+	if err != nil {
+		return l, err
+	}
+	out := exprValue{bool: make([]bool, len(l.str))}
+	for i, s := range l.str {
+		if !l.miss[i] {
+			out.bool[i] = n.re.MatchString(s) != n.not
+		}
+	}
+	return out, nil
+}
+
+// gtClasses are the kinds of genotype that FMT/GT can be compared with.
+var gtClasses = map[string]func(alleles []int) bool{
+	`het`: func(a []int) bool { return len(a) > 1 && !hasMissing(a) && !sameAlleles(a) },
+	`hom`: func(a []int) bool { return len(a) > 1 && !hasMissing(a) && sameAlleles(a) },
+	`ref`: func(a []int) bool { return len(a) > 0 && !hasMissing(a) && sameAlleles(a) && a[0] == 0 },
+	`alt`: func(a []int) bool {
+		for _, x := range a {
+			if x > 0 {
+				return true
+			}
+		}
+		return false
+	},
+	`mis`: func(a []int) bool { return len(a) == 0 || hasMissing(a) },
+	`hap`: func(a []int) bool { return len(a) == 1 && a[0] >= 0 },
+}
+
+func hasMissing(a []int) bool {
+	for _, x := range a {
+		if x < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func sameAlleles(a []int) bool {
+	for _, x := range a {
+		if x != a[0] {
+			return false
+		}
+	}
+	return true
+}
+
+type gtClassNode struct {
+	not   bool
+	gt    *fieldNode
+	class func([]int) bool
+}
+
+func (n *gtClassNode) kind() exprKind { return kindBool }
+
+func (n *gtClassNode) eval(v *Variant) (exprValue, error) {
+	out := exprValue{bool: make([]bool, len(v.Samples))}
+	for i, g := range v.Samples {
+		alleles, err := gtAlleles(g.Fields[`GT`])
+		if err != nil {
+			return out, fmt.Errorf("GT: %v", err)
+		}
+		out.bool[i] = n.class(alleles) != n.not
+	}
+	return out, nil
+}
+
+type arithNode struct {
+	op   string
+	l, r exprNode
+}
+
+func (n *arithNode) kind() exprKind { return kindNum }
+
+func (n *arithNode) eval(v *Variant) (exprValue, error) {
+	l, err := n.l.eval(v)
+	if err != nil {
+		return l, err
+	}
+	r, err := n.r.eval(v)
+	if err != nil {
+		return r, err
+	}
+	count, err := pair(len(l.num), len(r.num))
+	if err != nil {
+		return exprValue{}, err
+	}
+	out := exprValue{num: make([]float64, count), miss: make([]bool, count)}
+	for i := range out.num {
+		li, ri := at(i, len(l.num)), at(i, len(r.num))
+		if l.miss[li] || r.miss[ri] {
+			out.miss[i] = true
+			continue
+		}
+		switch n.op {
+		case `+`:
+			out.num[i] = l.num[li] + r.num[ri]
+		case `-`:
+			out.num[i] = l.num[li] - r.num[ri]
+		case `*`:
+			out.num[i] = l.num[li] * r.num[ri]
+		case `/`:
+			out.num[i] = l.num[li] / r.num[ri]
+			out.miss[i] = r.num[ri] == 0
+		}
+	}
+	return out, nil
+}
+
+type logicalNode struct {
+	op   string
+	l, r exprNode
+}
+
+func (n *logicalNode) kind() exprKind { return kindBool }
+
+func (n *logicalNode) eval(v *Variant) (exprValue, error) {
+	l, err := n.l.eval(v)
+	if err != nil {
+		return l, err
+	}
+	// Short circuit.
+	if l.any() == (n.op == `||`) {
+		return boolValue(l.any()), nil
+	}
+	r, err := n.r.eval(v)
+	if err != nil {
+		return r, err
+	}
+	return boolValue(r.any()), nil
+}
+
+type notNode struct {
+	n exprNode
+}
+
+func (n *notNode) kind() exprKind { return kindBool }
+
+func (n *notNode) eval(v *Variant) (exprValue, error) {
+	x, err := n.n.eval(v)
+	if err != nil {
+		return x, err
+	}
+	return boolValue(!x.any()), nil
+}
+
+// exprFunc is a function that reduces a list to a single value.
+type exprFunc struct {
+	arg    exprKind
+	result exprKind
+	f      func(x exprValue) (float64, bool)
+}
+
+var exprFuncs = map[string]*exprFunc{
+	`any`: {kindBool, kindBool, func(x exprValue) (float64, bool) {
+		return boolNum(x.any()), true
+	}},
+	`all`: {kindBool, kindBool, func(x exprValue) (float64, bool) {
+		for _, b := range x.bool {
+			if !b {
+				return 0, true
+			}
+		}
+		return boolNum(len(x.bool) > 0), true
+	}},
+	`count`: {kindBool, kindNum, func(x exprValue) (float64, bool) {
+		var c float64
+		for _, b := range x.bool {
+			if b {
+				c++
+			}
+		}
+		return c, true
+	}},
+	`missing`: {kindBool, kindBool, nil},
+	`sum`: {kindNum, kindNum, func(x exprValue) (float64, bool) {
+		var s float64
+		for i, f := range x.num {
+			if !x.miss[i] {
+				s += f
+			}
+		}
+		return s, true
+	}},
+	`min`:  {kindNum, kindNum, func(x exprValue) (float64, bool) { return extreme(x, -1) }},
+	`max`:  {kindNum, kindNum, func(x exprValue) (float64, bool) { return extreme(x, 1) }},
+	`mean`: {kindNum, kindNum, mean},
+}
+
+func boolNum(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func extreme(x exprValue, sign float64) (float64, bool) {
+	best, found := math.Inf(-int(sign)), false
+	for i, f := range x.num {
+		if !x.miss[i] && f*sign > best*sign {
+			best, found = f, true
+		}
+	}
+	return best, found
+}
+
+func mean(x exprValue) (float64, bool) {
+	var s, n float64
+	for i, f := range x.num {
+		if !x.miss[i] {
+			s += f
+			n++
+		}
+	}
+	return s / n, n > 0
+}
+
+type funcNode struct {
+	name string
+	fn   *exprFunc
+	arg  exprNode
+}
+
+func (n *funcNode) kind() exprKind { return n.fn.result }
+
+func (n *funcNode) eval(v *Variant) (exprValue, error) {
+	x, err := n.arg.eval(v)
+	if err != nil {
+		return x, err
+	}
+	if n.name == `missing` {
+		k := n.arg.kind()
+		if k == kindBool {
+			return boolValue(!x.any()), nil
+		}
+		for i := 0; i < x.len(k); i++ {
+			if !x.miss[i] {
+				return boolValue(false), nil
+			}
+		}
+		return boolValue(true), nil
+	}
+	f, ok := n.fn.f(x)
+	if n.fn.result == kindBool {
+		return boolValue(f != 0), nil
+	}
+	return exprValue{num: []float64{f}, miss: []bool{!ok}}, nil
+}
@@ -0,0 +1,108 @@
+package vcfgo
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var exprStr = `##fileformat=VCFv4.2
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Depth">
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency">
+##INFO=<ID=DB,Number=0,Type=Flag,Description="dbSNP">
+##INFO=<ID=GENE,Number=1,Type=String,Description="Gene">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths">
+##FORMAT=<ID=GQ,Number=1,Type=Integer,Description="Genotype quality">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2	S3	S4
+1	100	rs1	A	C,G	50	PASS	DP=20;AF=0.005,0.2;DB;GENE=BRCA2	GT:AD:GQ	0/1:5,4,0:30	0/1:6,3,0:20	0/2:4,0,4:10	0/1:3,3,0:.
+1	200	.	T	A	.	q10;LowQual	DP=5;AF=.	GT:AD:GQ	0/0:9,0:40	1/1:0,9:50	./.:.:.	0/0:8,0:45
+`
+
+type ExprSuite struct{}
+
+var _ = Suite(&ExprSuite{})
+
+func (s *ExprSuite) TestEval(c *C) {
+	for _, t := range []struct {
+		expr string
+		exp  []bool
+	}{
+		{`QUAL>=30 && INFO/DP>10 && FILTER=="PASS" && count(FMT/GT=="het")>2 && INFO/AF[0]<0.01`, []bool{true, false}},
+		{`QUAL<30`, []bool{false, false}},
+		{`missing(QUAL) || DB`, []bool{true, true}},
+		{`!DB`, []bool{false, true}},
+		{`AF>0.1`, []bool{true, false}},
+		{`all(AF<0.5)`, []bool{true, false}},
+		{`missing(AF)`, []bool{false, true}},
+		{`FILTER="LowQual"`, []bool{false, true}},
+		{`FILTER=="."`, []bool{false, false}},
+		{`GENE ~ "^BRCA" && GENE != "TP53"`, []bool{true, false}},
+		{`ALT=="G"`, []bool{true, false}},
+		{`N_ALT==2 && N_SAMPLES==4 && CHROM=="1" && POS<150 && ID=="rs1" && REF=="A"`, []bool{true, false}},
+		{`count(GT=="hom")==3`, []bool{false, true}},
+		{`count(GT=="mis")>0`, []bool{false, true}},
+		{`any(GT=="1/1")`, []bool{false, true}},
+		{`min(GQ)>=10`, []bool{true, true}},
+		{`mean(GQ)==20`, []bool{true, false}},
+		{`sum(AD[1])==10`, []bool{true, false}},
+		{`FMT/AD[1]/(FMT/AD[0]+FMT/AD[1]) > 0.4`, []bool{true, true}},
+		{`-DP < -10`, []bool{true, false}},
+		{`DP*2+1 == 41`, []bool{true, false}},
+		{`DP+-1 == 19 && DP*-1 == -20`, []bool{true, false}},
+	} {
+		rdr, err := NewReader(strings.NewReader(exprStr), true)
+		c.Assert(err, IsNil)
+		e, err := CompileExpr(t.expr, rdr.Header)
+		c.Assert(err, IsNil, Commentf(t.expr))
+		for i, exp := range t.exp {
+			v := rdr.Read()
+			obs, err := e.Eval(v)
+			c.Assert(err, IsNil, Commentf(t.expr))
+			c.Assert(obs, Equals, exp, Commentf("%s line %d", t.expr, i+1))
+		}
+	}
+}
+
+func (s *ExprSuite) TestLazy(c *C) {
+	rdr, err := NewReader(strings.NewReader(exprStr), true)
+	c.Assert(err, IsNil)
+	e, err := CompileExpr(`DP>10`, rdr.Header)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	ok, err := e.Eval(v)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	c.Assert(v.Samples, IsNil)
+
+	e, err = CompileExpr(`GQ>10`, rdr.Header)
+	c.Assert(err, IsNil)
+	_, err = e.Eval(v)
+	c.Assert(err, IsNil)
+	c.Assert(v.Samples, HasLen, 4)
+}
+
+func (s *ExprSuite) TestCompileErrors(c *C) {
+	rdr, err := NewReader(strings.NewReader(exprStr), true)
+	c.Assert(err, IsNil)
+	for _, t := range []struct{ expr, err string }{
+		{`XX>1`, `.*XX is not in the header.*`},
+		{`INFO/GQ>1`, `.*INFO/GQ is not in the header.*`},
+		{`DP=="a"`, `.*cannot compare a number with a string`},
+		{`GENE>"a"`, `.*strings can only be compared.*`},
+		{`DB==1`, `.*cannot compare a condition.*`},
+		{`DP`, `.*DP is not a condition`},
+		{`DP[1]>0`, `.*Number=1 so \[1\] is out of range.*`},
+		{`count(DP)>1`, `.*count\(\) needs a condition.*`},
+		{`foo(DP)`, `.*unknown function foo.*`},
+		{`(DP>1`, `.*expected \)`},
+		{`DP>1 &&`, `.*unexpected end of expression.*`},
+		{`GENE ~ "("`, `.*missing closing \).*`},
+		{`"abc`, `.*unterminated string.*`},
+		{`INFO/DP*/5==1`, `.*unexpected / at 8`},
+		{`INFO/DP+*5==1`, `.*unexpected \* at 8`},
+	} {
+		_, err := CompileExpr(t.expr, rdr.Header)
+		c.Assert(err, ErrorMatches, t.err, Commentf(t.expr))
+	}
+}
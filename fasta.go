@@ -0,0 +1,366 @@
+package vcfgo
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrFai         = errors.New("vcfgo: bad FASTA index")
+	ErrOutOfBounds = errors.New("vcfgo: region is outside the contig")
+)
+
+// FaiRecord is a line of a samtools FASTA index (.fai). Offset is the
+// offset of the first base in the uncompressed file. Each line of
+// sequence holds LineBases bases and LineWidth bytes, including the end
+// of line.
+type FaiRecord struct {
+	Name      string
+	Length    int64
+	Offset    int64
+	LineBases int64
+	LineWidth int64
+}
+
+// ReadFai reads a FASTA index.
+func ReadFai(r io.Reader) ([]FaiRecord, error) {
+	var recs []FaiRecord
+	scanner := bufio.NewScanner(r)
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == `` {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("%w - too few fields at line %d", ErrFai, n)
+		}
+		rec := FaiRecord{Name: fields[0]}
+		for i, p := range []*int64{&rec.Length, &rec.Offset, &rec.LineBases, &rec.LineWidth} {
+			var err error
+			if *p, err = strconv.ParseInt(fields[i+1], 10, 64); err != nil {
+				return nil, fmt.Errorf("%w - bad number %s at line %d", ErrFai, fields[i+1], n)
+			}
+		}
+		recs = append(recs, rec)
+	}
+	return recs, scanner.Err()
+}
+
+// WriteFai writes a FASTA index.
+func WriteFai(w io.Writer, recs []FaiRecord) error {
+	for _, r := range recs {
+		if _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", r.Name, r.Length, r.Offset, r.LineBases, r.LineWidth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildFai indexes uncompressed FASTA as samtools faidx does. Every line
+// of a sequence but the last must have the same length.
+func BuildFai(r io.Reader) ([]FaiRecord, error) {
+	br := bufio.NewReader(r)
+	var recs []FaiRecord
+	var rec *FaiRecord
+	var offset int64
+	short := false // a line shorter than LineBases has been seen
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		width := int64(len(line))
+		offset += width
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case len(line) > 0 && line[0] == '>':
+			name := string(line[1:])
+			if i := strings.IndexAny(name, " \t"); i != -1 {
+				name = name[:i]
+			}
+			recs = append(recs, FaiRecord{Name: name, Offset: offset})
+			rec, short = &recs[len(recs)-1], false
+		case len(line) > 0:
+			if rec == nil {
+				return nil, fmt.Errorf("%w - sequence before the first > at line %d", ErrFai, n)
+			}
+			bases := int64(len(line))
+			if rec.LineBases == 0 {
+				rec.LineBases, rec.LineWidth = bases, width
+			} else if short || bases > rec.LineBases {
+				return nil, fmt.Errorf("%w - %s has lines of different lengths at line %d", ErrFai, rec.Name, n)
+			}
+			short = short || bases < rec.LineBases
+			rec.Length += bases
+		}
+		if err == io.EOF {
+			return recs, nil
+		}
+	}
+}
+
+// gziEntry maps the file offset of a BGZF block to the uncompressed
+// offset of its first byte.
+type gziEntry struct {
+	comp, uncomp uint64
+}
+
+// readGzi reads a bgzip index (.gzi). The first block is implicit.
+func readGzi(r io.Reader) ([]gziEntry, error) {
+	var n uint64
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return nil, fmt.Errorf("%w - bad .gzi: %v", ErrFai, err)
+	}
+	entries := make([]gziEntry, n+1)
+	for i := uint64(1); i <= n; i++ {
+		var e [2]uint64
+		if err := binary.Read(r, binary.LittleEndian, &e); err != nil {
+			return nil, fmt.Errorf("%w - bad .gzi: %v", ErrFai, err)
+		}
+		entries[i] = gziEntry{e[0], e[1]}
+	}
+	return entries, nil
+}
+
+// buildGzi indexes the blocks of BGZF data.
+func buildGzi(b *bgzfReader) ([]gziEntry, error) {
+	var entries []gziEntry
+	var addr, total uint64
+	for {
+		if err := b.readBlock(int64(addr)); err != nil {
+			if err == io.EOF {
+				return entries, nil
+			}
+			return nil, err
+		}
+		entries = append(entries, gziEntry{addr, total})
+		addr, total = uint64(b.next), total+uint64(len(b.block))
+	}
+}
+
+// fastaWindowSize is the number of bases held by each cached window.
+const fastaWindowSize = 1 << 14
+
+type fastaWindow struct {
+	rec, n int
+}
+
+type fastaCached struct {
+	key   fastaWindow
+	bases []byte
+}
+
+// Fasta gives random access to an indexed FASTA file, which may be
+// compressed with bgzip. Recently read windows of sequence are cached.
+// Fasta implements Genome and is safe for concurrent use.
+type Fasta struct {
+	Records []FaiRecord
+	// CacheWindows is the number of windows of 16kb to cache.
+	CacheWindows int
+
+	r     io.ReadSeeker
+	bgzf  *bgzfReader
+	gzi   []gziEntry
+	names map[string]int
+	mu    sync.Mutex
+	lru   *list.List
+	cache map[fastaWindow]*list.Element
+}
+
+// NewFasta returns a Fasta that reads uncompressed FASTA from r.
+func NewFasta(r io.ReadSeeker, fai []FaiRecord) *Fasta {
+	f := &Fasta{Records: fai, CacheWindows: 32, r: r, names: make(map[string]int),
+		lru: list.New(), cache: make(map[fastaWindow]*list.Element)}
+	for i, rec := range fai {
+		f.names[rec.Name] = i
+	}
+	return f
+}
+
+// OpenFasta opens a FASTA file and its index, path with .fai appended.
+// If there is no .fai, the file is indexed in memory. A file compressed
+// with bgzip is indexed by path with .gzi appended, or by reading the
+// whole file if there is no .gzi.
+func OpenFasta(path string) (*Fasta, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := openFasta(path, file)
+	if err != nil {
+		file.Close()
+	}
+	return f, err
+}
+
+func openFasta(path string, file *os.File) (*Fasta, error) {
+	var magic [2]byte
+	io.ReadFull(file, magic[:])
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var b *bgzfReader
+	var gzi []gziEntry
+	var text io.Reader = file
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		b = newBGZFReader(file)
+		text = b
+		if g, err := os.Open(path + `.gzi`); err == nil {
+			gzi, err = readGzi(bufio.NewReader(g))
+			g.Close()
+			if err != nil {
+				return nil, err
+			}
+		} else if gzi, err = buildGzi(b); err != nil {
+			return nil, err
+		}
+	}
+
+	var fai []FaiRecord
+	if fi, err := os.Open(path + `.fai`); err == nil {
+		fai, err = ReadFai(fi)
+		fi.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if b != nil {
+			b = newBGZFReader(file)
+			text = b
+		}
+		if fai, err = BuildFai(text); err != nil {
+			return nil, err
+		}
+	}
+	f := NewFasta(file, fai)
+	f.bgzf, f.gzi = b, gzi
+	return f, nil
+}
+
+// Close closes the file.
+func (f *Fasta) Close() error {
+	if c, ok := f.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Contigs returns a ContigDict of the names and lengths of the
+// sequences, for Header.SetContigs.
+func (f *Fasta) Contigs() *ContigDict {
+	d := NewContigDict()
+	for _, rec := range f.Records {
+		d.Add(&Contig{Name: rec.Name, Length: uint64(rec.Length)})
+	}
+	return d
+}
+
+// Length returns the length of a sequence.
+func (f *Fasta) Length(chrom string) (int, bool) {
+	i, found := f.names[chrom]
+	if !found {
+		return 0, false
+	}
+	return int(f.Records[i].Length), true
+}
+
+// Seq returns the bases of the 0-based, half-open region [start, end).
+func (f *Fasta) Seq(chrom string, start, end int) ([]byte, error) {
+	i, found := f.names[chrom]
+	if !found {
+		return nil, fmt.Errorf("%w - %s", ErrContigNotFound, chrom)
+	}
+	if start < 0 || end < start || int64(end) > f.Records[i].Length {
+		return nil, fmt.Errorf("%w - %s:%d-%d", ErrOutOfBounds, chrom, start, end)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seq := make([]byte, 0, end-start)
+	for pos := start; pos < end; {
+		n := pos / fastaWindowSize
+		w, err := f.window(fastaWindow{i, n})
+		if err != nil {
+			return nil, err
+		}
+		from, to := pos-n*fastaWindowSize, end-n*fastaWindowSize
+		if to > len(w) {
+			to = len(w)
+		}
+		seq = append(seq, w[from:to]...)
+		pos += to - from
+	}
+	return seq, nil
+}
+
+// Fetch returns the bases of the 1-based, closed region start-end.
+func (f *Fasta) Fetch(chrom string, start, end int) ([]byte, error) {
+	return f.Seq(chrom, start-1, end)
+}
+
+// window returns a window of bases from the cache or the file.
+func (f *Fasta) window(key fastaWindow) ([]byte, error) {
+	if e, found := f.cache[key]; found {
+		f.lru.MoveToFront(e)
+		return e.Value.(*fastaCached).bases, nil
+	}
+	rec := f.Records[key.rec]
+	start := int64(key.n) * fastaWindowSize
+	end := start + fastaWindowSize
+	if end > rec.Length {
+		end = rec.Length
+	}
+	offset := func(pos int64) int64 {
+		return rec.Offset + pos/rec.LineBases*rec.LineWidth + pos%rec.LineBases
+	}
+	raw := make([]byte, offset(end-1)+1-offset(start))
+	if err := f.readAt(raw, offset(start)); err != nil {
+		return nil, err
+	}
+	bases := raw[:0]
+	for _, c := range raw {
+		if c != '\n' && c != '\r' {
+			bases = append(bases, c)
+		}
+	}
+	f.cache[key] = f.lru.PushFront(&fastaCached{key, bases})
+	for f.lru.Len() > f.CacheWindows && f.lru.Len() > 1 {
+		e := f.lru.Back()
+		delete(f.cache, e.Value.(*fastaCached).key)
+		f.lru.Remove(e)
+	}
+	return bases, nil
+}
+
+// readAt fills p from the uncompressed offset.
+func (f *Fasta) readAt(p []byte, offset int64) error {
+	if f.bgzf == nil {
+		if _, err := f.r.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := io.ReadFull(f.r, p)
+		return err
+	}
+	u := uint64(offset)
+	i := sort.Search(len(f.gzi), func(i int) bool { return f.gzi[i].uncomp > u }) - 1
+	if i < 0 {
+		return fmt.Errorf("%w - offset %d is not in the .gzi", ErrFai, offset)
+	}
+	if err := f.bgzf.Seek(f.gzi[i].comp<<16 | (u - f.gzi[i].uncomp)); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(f.bgzf, p)
+	return err
+}
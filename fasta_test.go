@@ -0,0 +1,148 @@
+package vcfgo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type FastaSuite struct {
+	seq  string // chr1
+	text string // the FASTA file
+}
+
+var _ = Suite(&FastaSuite{})
+
+func (s *FastaSuite) SetUpSuite(c *C) {
+	var b strings.Builder
+	for i := 0; i < 40000; i++ {
+		b.WriteByte("ACGTTGCA"[(i*7+i/13)%8])
+	}
+	s.seq = b.String()
+	var t strings.Builder
+	t.WriteString(">chr1 test sequence\n")
+	for i := 0; i < len(s.seq); i += 60 {
+		end := i + 60
+		if end > len(s.seq) {
+			end = len(s.seq)
+		}
+		t.WriteString(s.seq[i:end] + "\n")
+	}
+	t.WriteString(">chr2\nACGT\nACGT\nac\n")
+	s.text = t.String()
+}
+
+func (s *FastaSuite) TestBuildFai(c *C) {
+	fai, err := BuildFai(strings.NewReader(s.text))
+	c.Assert(err, IsNil)
+	c.Assert(fai, DeepEquals, []FaiRecord{
+		{"chr1", 40000, 20, 60, 61},
+		{"chr2", 10, int64(len(s.text)) - 13, 4, 5},
+	})
+	var buf bytes.Buffer
+	c.Assert(WriteFai(&buf, fai), IsNil)
+	c.Assert(strings.SplitN(buf.String(), "\n", 2)[0], Equals, "chr1\t40000\t20\t60\t61")
+	back, err := ReadFai(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(back, DeepEquals, fai)
+
+	_, err = BuildFai(strings.NewReader(">a\nACG\nA\nACG\n"))
+	c.Assert(err, ErrorMatches, ".*a has lines of different lengths at line 4")
+	_, err = ReadFai(strings.NewReader("chr1\t10\t5\n"))
+	c.Assert(err, ErrorMatches, ".*too few fields at line 1")
+}
+
+func (s *FastaSuite) check(c *C, f *Fasta) {
+	seq, err := f.Seq("chr1", 16370, 16400)
+	c.Assert(err, IsNil)
+	c.Assert(string(seq), Equals, s.seq[16370:16400])
+	seq, err = f.Fetch("chr1", 1, 61)
+	c.Assert(err, IsNil)
+	c.Assert(string(seq), Equals, s.seq[:61])
+	seq, err = f.Seq("chr1", 0, 40000)
+	c.Assert(err, IsNil)
+	c.Assert(string(seq), Equals, s.seq)
+	seq, err = f.Seq("chr2", 3, 10)
+	c.Assert(err, IsNil)
+	c.Assert(string(seq), Equals, "TACGTac")
+
+	_, err = f.Seq("chr2", 5, 11)
+	c.Assert(err, ErrorMatches, ".*outside the contig - chr2:5-11")
+	_, err = f.Seq("chr3", 0, 1)
+	c.Assert(err, ErrorMatches, ".*contig not found - chr3")
+	n, ok := f.Length("chr2")
+	c.Assert(ok, Equals, true)
+	c.Assert(n, Equals, 10)
+	c.Assert(f.Contigs().Names(), DeepEquals, []string{"chr1", "chr2"})
+}
+
+func (s *FastaSuite) TestPlain(c *C) {
+	path := filepath.Join(c.MkDir(), "ref.fa")
+	c.Assert(os.WriteFile(path, []byte(s.text), 0644), IsNil)
+	f, err := OpenFasta(path)
+	c.Assert(err, IsNil)
+	s.check(c, f)
+	c.Assert(f.lru.Len(), Equals, 4)
+	f.Close()
+
+	// With a .fai and a cache of one window.
+	fai, _ := BuildFai(strings.NewReader(s.text))
+	var buf bytes.Buffer
+	WriteFai(&buf, fai)
+	c.Assert(os.WriteFile(path+".fai", buf.Bytes(), 0644), IsNil)
+	f, err = OpenFasta(path)
+	c.Assert(err, IsNil)
+	f.CacheWindows = 1
+	s.check(c, f)
+	c.Assert(f.lru.Len(), Equals, 1)
+	f.Close()
+}
+
+func (s *FastaSuite) TestBGZF(c *C) {
+	var chunks []string
+	for i := 0; i < len(s.text); i += 10000 {
+		end := i + 10000
+		if end > len(s.text) {
+			end = len(s.text)
+		}
+		chunks = append(chunks, s.text[i:end])
+	}
+	data, offsets := writeBGZF(chunks)
+	path := filepath.Join(c.MkDir(), "ref.fa.gz")
+	c.Assert(os.WriteFile(path, data, 0644), IsNil)
+	f, err := OpenFasta(path)
+	c.Assert(err, IsNil)
+	c.Assert(len(f.gzi), Equals, len(chunks)+1)
+	s.check(c, f)
+	f.Close()
+
+	var gzi bytes.Buffer
+	binary.Write(&gzi, binary.LittleEndian, uint64(len(chunks)-1))
+	for i := 1; i < len(chunks); i++ {
+		binary.Write(&gzi, binary.LittleEndian, []uint64{offsets[i] >> 16, uint64(i * 10000)})
+	}
+	c.Assert(os.WriteFile(path+".gzi", gzi.Bytes(), 0644), IsNil)
+	f, err = OpenFasta(path)
+	c.Assert(err, IsNil)
+	c.Assert(len(f.gzi), Equals, len(chunks))
+	s.check(c, f)
+	f.Close()
+}
+
+func (s *FastaSuite) TestNormalize(c *C) {
+	path := filepath.Join(c.MkDir(), "ref.fa")
+	c.Assert(os.WriteFile(path, []byte(">chr1\nTTTGCACACA\nCTTT\n"), 0644), IsNil)
+	f, err := OpenFasta(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	v := &Variant{Chromosome: "chr1", Pos: 9, Reference: "CAC", Alternate: []string{"C"}}
+	changed, err := v.Normalize(f)
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, true)
+	c.Assert(v.Pos, Equals, uint64(4))
+	c.Assert(v.Reference, Equals, "GCA")
+}
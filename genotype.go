@@ -0,0 +1,253 @@
+package vcfgo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Helpers for working with GT strings and Number=G values that are
+// shared by the operations that change the alleles of a record.
+
+// gtAlleles returns the allele indices in a GT string with -1 for a
+// missing allele. Any leading phasing indicator is ignored.
+func gtAlleles(gt string) ([]int, error) {
+	gt = strings.TrimLeft(gt, `|/`)
+	if gt == `` {
+		return nil, nil
+	}
+	fields := strings.FieldsFunc(gt, func(r rune) bool { return r == '|' || r == '/' })
+	alleles := make([]int, len(fields))
+	for i, f := range fields {
+		if f == `.` {
+			alleles[i] = -1
+			continue
+		}
+		a, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		alleles[i] = a
+	}
+	return alleles, nil
+}
+
+// recodeGT returns a GT string with every allele index replaced by
+// f(index). Missing alleles, separators and any leading phasing
+// indicator are kept. If f returns -1 the allele becomes missing.
+func recodeGT(gt string, f func(int) int) (string, error) {
+	var b strings.Builder
+	b.Grow(len(gt))
+	start := -1
+	flush := func(end int) error {
+		if start == -1 {
+			return nil
+		}
+		tok := gt[start:end]
+		start = -1
+		if tok == `.` {
+			b.WriteString(tok)
+			return nil
+		}
+		a, err := strconv.Atoi(tok)
+		if err != nil {
+			return err
+		}
+		if n := f(a); n < 0 {
+			b.WriteByte('.')
+		} else {
+			b.WriteString(strconv.Itoa(n))
+		}
+		return nil
+	}
+	for i := 0; i < len(gt); i++ {
+		if gt[i] == '|' || gt[i] == '/' {
+			if err := flush(i); err != nil {
+				return gt, err
+			}
+			b.WriteByte(gt[i])
+			continue
+		}
+		if start == -1 {
+			start = i
+		}
+	}
+	if err := flush(len(gt)); err != nil {
+		return gt, err
+	}
+	return b.String(), nil
+}
+
+// ploidyOf returns the number of alleles in a GT string or 2 if the GT
+// is missing or absent.
+func ploidyOf(gt string) int {
+	alleles, err := gtAlleles(gt)
+	if err != nil || len(alleles) == 0 || gt == `.` {
+		return 2
+	}
+	return len(alleles)
+}
+
+// splitValues splits a comma-separated field value. A missing value
+// gives nil.
+func splitValues(value string) []string {
+	if value == `` || value == `.` {
+		return nil
+	}
+	return strings.Split(value, `,`)
+}
+
+// missingValues returns n missing values.
+func missingValues(n int) []string {
+	vals := make([]string, n)
+	for i := range vals {
+		vals[i] = `.`
+	}
+	return vals
+}
+
+// infoValues returns the values of an INFO field, percent-decoded if the
+// Header needs it, or false if the field is absent or missing.
+func infoValues(v *Variant, key string) ([]string, bool) {
+	raw := string(NewInfoByte(v.Info().Bytes(), nil).SGet(key))
+	vals := splitValues(raw)
+	if vals == nil {
+		return nil, false
+	}
+	if v.Header.percentEncoded() {
+		for i, val := range vals {
+			if d, err := PercentDecode(val); err == nil {
+				vals[i] = d
+			}
+		}
+	}
+	return vals, true
+}
+
+// remapValues remaps the values of a Number=A, R or G field when allele
+// a becomes allele m[a] of n alleles. An allele with m[a] of -1 is
+// dropped and values with no old allele are missing. ploidy is only
+// used for Number=G.
+func remapValues(vals []string, number string, m []int, n int, ploidy int) ([]string, error) {
+	switch number {
+	case `R`:
+		if len(vals) != len(m) {
+			return nil, fmt.Errorf("Number=R with %d values for %d alleles", len(vals), len(m))
+		}
+		out := missingValues(n)
+		for a, val := range vals {
+			if m[a] >= 0 {
+				out[m[a]] = val
+			}
+		}
+		return out, nil
+	case `A`:
+		if len(vals) != len(m)-1 {
+			return nil, fmt.Errorf("Number=A with %d values for %d ALT alleles", len(vals), len(m)-1)
+		}
+		out := missingValues(n - 1)
+		for a, val := range vals {
+			if m[a+1] > 0 {
+				out[m[a+1]-1] = val
+			}
+		}
+		return out, nil
+	case `G`:
+		order := genotypeOrder(len(m), ploidy)
+		if len(vals) != len(order) {
+			return nil, fmt.Errorf("Number=G with %d values for %d genotypes", len(vals), len(order))
+		}
+		out := missingValues(genotypeCount(n, ploidy))
+	genotypes:
+		for i, g := range order {
+			mg := make([]int, len(g))
+			for j, a := range g {
+				if m[a] < 0 {
+					continue genotypes
+				}
+				mg[j] = m[a]
+			}
+			out[genotypeIndex(mg)] = vals[i]
+		}
+		return out, nil
+	}
+	return vals, nil
+}
+
+// remapAlleles remaps the Number=A, R and G values of the INFO and
+// FORMAT fields of v and recodes GT when allele a becomes allele m[a] of
+// n alleles, as for remapValues. REF and ALT are not changed. Number=G
+// INFO values are taken to be diploid. Lazily parsed samples are parsed
+// first. Fields with the wrong number of values are left as they are
+// and the first such error is returned.
+func (v *Variant) remapAlleles(m []int, n int) error {
+	var first error
+	addErr := func(err error) {
+		if first == nil {
+			first = err
+		}
+	}
+	info := NewInfoByte(v.Info().Bytes(), nil)
+	for _, k := range info.Keys() {
+		def, found := v.Header.Infos[k]
+		if !found || (def.Number != `A` && def.Number != `R` && def.Number != `G`) {
+			continue
+		}
+		vals, ok := infoValues(v, k)
+		if !ok {
+			continue
+		}
+		out, err := remapValues(vals, def.Number, m, n, 2)
+		if err != nil {
+			addErr(fmt.Errorf("%s:%d INFO %s %v", v.Chromosome, v.Pos, k, err))
+			continue
+		}
+		addErr(v.Info().Set(k, out))
+	}
+
+	if err := v.Header.ParseSamples(v); err != nil && v.Samples == nil {
+		return err
+	}
+	for _, g := range v.Samples {
+		for _, f := range v.Format {
+			value, found := g.Fields[f]
+			if !found {
+				continue
+			}
+			if f == `GT` {
+				gt, err := recodeGT(value, func(a int) int {
+					if a >= len(m) {
+						return -1
+					}
+					return m[a]
+				})
+				if err != nil {
+					addErr(fmt.Errorf("%s:%d %v", v.Chromosome, v.Pos, err))
+					continue
+				}
+				g.Fields[f] = gt
+				g.GT = g.GT[:0]
+				v.Header.setSampleGT(g, gt)
+				continue
+			}
+			def, found := v.Header.SampleFormats[f]
+			vals := splitValues(value)
+			if !found || vals == nil || (def.Number != `A` && def.Number != `R` && def.Number != `G`) {
+				continue
+			}
+			out, err := remapValues(vals, def.Number, m, n, ploidyOf(g.Fields[`GT`]))
+			if err != nil {
+				addErr(fmt.Errorf("%s:%d FORMAT %s %v", v.Chromosome, v.Pos, f, err))
+				continue
+			}
+			g.Fields[f] = strings.Join(out, `,`)
+			switch f {
+			case `PL`:
+				v.Header.setSampleGL(g, g.Fields[f], true)
+			case `GL`:
+				v.Header.setSampleGL(g, g.Fields[f], false)
+			}
+		}
+	}
+	return first
+}
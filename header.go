@@ -18,8 +18,6 @@ import (
 var typeRe = `String|Integer|Float|Flag|Character|Unknown`
 var filterRegexp = regexp.MustCompile(`##FILTER=<ID=(.+),Description="(.*)">`)
 var contigRegexp = regexp.MustCompile(`contig=<.*((\w+)=([^,>]+))`)
-var sampleRegexp = regexp.MustCompile(`SAMPLE=<ID=([^,>]+)`)
-var pedRegexp = regexp.MustCompile(`PEDIGREE=<=([^,>]+)`)
 
 //var headerIdRegexp = regexp.MustCompile(`##([^=]+)=<ID=([^,]+)`)
 var fileVersionRegexp = regexp.MustCompile(`##fileformat=VCFv(.+)`)
@@ -68,15 +66,32 @@ type Header struct {
 	SampleFormats map[string]*SampleFormat
 	Filters       map[string]string
 	Extras        []string
-	// Contigs is a list of maps of length, URL, etc.
-	Contigs []map[string]string
-	// ##SAMPLE
-	Samples   map[string]string
+	// Contigs holds the ##contig lines as an ordered dictionary. Use
+	// SetContigs() to change it so that Lines is kept in step.
+	Contigs *ContigDict
+	// Samples holds the ##SAMPLE lines keyed by ID and Pedigree holds
+	// the ##PEDIGREE lines and the graph they describe. Use SetSamples()
+	// and SetPedigree() to change them so that Lines is kept in step.
+	Samples  map[string]*SampleMeta
+	Pedigree *Pedigree
+	// Pedigrees holds the ##PEDIGREE lines as strings.
+	//
+	// Deprecated: use Pedigree.
 	Pedigrees []string
+
+	// percentDecode is set by SetPercentDecoding.
+	percentDecode bool
 }
 
-// String returns a string representation.
+// String returns a string representation. An Info that was created
+// directly rather than parsed has no fields so the string is built from
+// Id, Number, Type and Description.
 func (i *Info) String() string {
+	if len(i.fields) == 0 {
+		return fmt.Sprintf("##INFO=<ID=%s,Number=%s,Type=%s,Description=\"%s\">",
+			i.Id, i.Number, i.Type, i.Description)
+	}
+
 	// Work out original order of fields
 	positions := make([]int, 0)
 	ogorder := make(map[int]*KV)
@@ -107,8 +122,15 @@ func (i *Info) String() string {
 	return newStr
 }
 
-// String returns a string representation.
+// String returns a string representation. A SampleFormat that was
+// created directly rather than parsed has no fields so the string is
+// built from Id, Number, Type and Description.
 func (s *SampleFormat) String() string {
+	if len(s.fields) == 0 {
+		return fmt.Sprintf("##FORMAT=<ID=%s,Number=%s,Type=%s,Description=\"%s\">",
+			s.Id, s.Number, s.Type, s.Description)
+	}
+
 	// Work out original order of fields
 	positions := make([]int, 0)
 	ogorder := make(map[int]*KV)
@@ -187,6 +209,146 @@ func (h *Header) GetLineByTypeAndId(t string, id string) (*MetaLine, error) {
 	}
 }
 
+// shallowCopy returns a new Header that shares the maps and MetaLines of
+// h but has its own Lines and SampleNames slices.
+func (h *Header) shallowCopy() *Header {
+	nh := NewHeader()
+	nh.FileFormat = h.FileFormat
+	nh.SampleNames = append(nh.SampleNames, h.SampleNames...)
+	nh.Lines = append(nh.Lines, h.Lines...)
+	nh.Infos = h.Infos
+	nh.SampleFormats = h.SampleFormats
+	nh.Filters = h.Filters
+	nh.Extras = h.Extras
+	nh.Contigs = h.Contigs
+	nh.Samples = h.Samples
+	nh.Pedigree = h.Pedigree
+	nh.Pedigrees = h.Pedigrees
+	nh.percentDecode = h.percentDecode
+	return nh
+}
+
+// hasLine reports whether Lines holds a structured meta-information line
+// of the supplied type and ID.
+func (h *Header) hasLine(t string, id string) bool {
+	for _, m := range h.Lines {
+		if m.MetaType == Structured && m.LineKey == t && m.GetValue(`ID`) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPedigreeLine reports whether Lines has a ##PEDIGREE line for id,
+// which may be given by ID or, as in VCFv4.1, by Child or Derived.
+func (h *Header) hasPedigreeLine(id string) bool {
+	for _, m := range h.Lines {
+		if m.MetaType != Structured || m.LineKey != `PEDIGREE` {
+			continue
+		}
+		for _, k := range []string{`ID`, `Child`, `Derived`} {
+			if m.GetValue(k) == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddInfoLine adds an ##INFO line to Lines and Infos. The line is
+// placed after the last ##INFO line. If there is already an ##INFO line
+// with the ID, nothing is added and the existing Info is returned.
+func (h *Header) AddInfoLine(id, number, typ, desc string) *Info {
+	if i, found := h.Infos[id]; found && h.hasLine(`INFO`, id) {
+		return i
+	}
+	m := newFieldLine(`INFO`, id, number, typ, desc)
+	h.insertLine(m)
+	s, _ := m.String()
+	i, _ := parseHeaderInfo(s)
+	h.Infos[id] = i
+	return i
+}
+
+// AddFormatLine adds a ##FORMAT line to Lines and SampleFormats. The
+// line is placed after the last ##FORMAT line. If there is already a
+// ##FORMAT line with the ID, nothing is added and the existing
+// SampleFormat is returned.
+func (h *Header) AddFormatLine(id, number, typ, desc string) *SampleFormat {
+	if f, found := h.SampleFormats[id]; found && h.hasLine(`FORMAT`, id) {
+		return f
+	}
+	m := newFieldLine(`FORMAT`, id, number, typ, desc)
+	h.insertLine(m)
+	s, _ := m.String()
+	f, _ := parseHeaderFormat(s)
+	h.SampleFormats[id] = f
+	return f
+}
+
+// AddFilterLine adds a ##FILTER line to Lines and Filters. The line is
+// placed after the last ##FILTER line. desc is written as it is, so any
+// " or \ in it must already be escaped. If there is already a ##FILTER
+// line with the ID, nothing is added.
+func (h *Header) AddFilterLine(id, desc string) {
+	if h.hasLine(`FILTER`, id) {
+		return
+	}
+	m := NewMetaLine()
+	m.LineKey = `FILTER`
+	m.AddKV(`ID`, id, 0)
+	m.AddKV(`Description`, desc, '"')
+	h.insertLine(m)
+	h.Filters[id] = desc
+}
+
+// RemoveLine removes the structured line of the supplied type and ID from
+// Lines and, for INFO, FORMAT and FILTER lines, from Infos, SampleFormats
+// or Filters. It returns false if there was no such line.
+func (h *Header) RemoveLine(t string, id string) bool {
+	found := false
+	lines := h.Lines[:0:0]
+	for _, m := range h.Lines {
+		if m.MetaType == Structured && m.LineKey == t && m.GetValue(`ID`) == id {
+			found = true
+			continue
+		}
+		lines = append(lines, m)
+	}
+	h.Lines = lines
+	switch t {
+	case `INFO`:
+		delete(h.Infos, id)
+	case `FORMAT`:
+		delete(h.SampleFormats, id)
+	case `FILTER`:
+		delete(h.Filters, id)
+	}
+	return found
+}
+
+func newFieldLine(t, id, number, typ, desc string) *MetaLine {
+	m := NewMetaLine()
+	m.LineKey = t
+	m.AddKV(`ID`, id, 0)
+	m.AddKV(`Number`, number, 0)
+	m.AddKV(`Type`, typ, 0)
+	m.AddKV(`Description`, desc, '"')
+	return m
+}
+
+// insertLine adds a MetaLine after the last line of the same type or at
+// the end of Lines.
+func (h *Header) insertLine(m *MetaLine) {
+	pos := len(h.Lines)
+	for i, l := range h.Lines {
+		if l.LineKey == m.LineKey {
+			pos = i + 1
+		}
+	}
+	h.Lines = append(h.Lines[:pos:pos], append([]*MetaLine{m}, h.Lines[pos:]...)...)
+}
+
 func (h *Header) parseSample(format []string, s string) (*SampleGenotype, []error) {
 	values := strings.Split(s, ":")
 	if len(format) != len(values) {
@@ -220,6 +382,11 @@ func (h *Header) parseSample(format []string, s string) (*SampleGenotype, []erro
 			errs = append(errs, e)
 		}
 	}
+	if h.decoding() {
+		if e = h.decodeSample(format, geno); e != nil {
+			errs = append(errs, e)
+		}
+	}
 	return geno, errs
 }
 
@@ -271,14 +438,23 @@ func (h *Header) setSampleGL(geno *SampleGenotype, value string, isPL bool) erro
 	return err
 }
 
+// setSampleGT parses a GT value. From VCFv4.4, the first allele may have
+// an explicit phasing indicator (e.g. |0|1 or /1) and phased and unphased
+// separators may be mixed (e.g. 0|1/2). Phased is only true if every
+// separator, and the phasing indicator if there is one, is |. A haploid
+// GT is only phased if it has a leading |.
 func (h *Header) setSampleGT(geno *SampleGenotype, value string) error {
-	geno.Phased = strings.Contains(value, "|")
-	splitString := "/"
-	if geno.Phased {
-		splitString = "|"
+	var prefix byte
+	if len(value) > 0 && (value[0] == '|' || value[0] == '/') {
+		prefix = value[0]
+		value = value[1:]
 	}
+	geno.Phased = strings.Contains(value, "|") && !strings.Contains(value, "/") && prefix != '/'
 
-	alleles := strings.Split(value, splitString)
+	alleles := strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == '/' })
+	if len(alleles) == 1 {
+		geno.Phased = prefix == '|'
+	}
 	for _, allele := range alleles {
 		switch allele {
 		case ".":
@@ -306,10 +482,10 @@ func NewHeader() *Header {
 	h.Infos = make(map[string]*Info)
 	h.SampleFormats = make(map[string]*SampleFormat)
 	h.SampleNames = make([]string, 0)
-	h.Pedigrees = make([]string, 0)
-	h.Samples = make(map[string]string)
+	h.Pedigree = NewPedigree()
+	h.Samples = make(map[string]*SampleMeta)
 	h.Extras = make([]string, 0)
-	h.Contigs = make([]map[string]string, 0, 64)
+	h.Contigs = NewContigDict()
 	return &h
 }
 
@@ -435,15 +611,6 @@ func parseHeaderFilter(info string) ([]string, error) {
 	return res[1:3], nil
 }
 
-// return just the sample id.
-func parseHeaderSample(line string) (string, error) {
-	res := sampleRegexp.FindStringSubmatch(line)
-	if len(res) != 2 {
-		return "", fmt.Errorf("error parsing ##SAMPLE")
-	}
-	return res[1], nil
-}
-
 func parseHeaderFileVersion(format string) (string, error) {
 	res := fileVersionRegexp.FindStringSubmatch(format)
 	if len(res) != 2 {
@@ -159,3 +159,17 @@ func (s *VariantSuite) TestParseOne(c *C) {
 
 }
 */
+
+func (s *HeaderSuite) TestAddFilterLine(c *C) {
+	h := NewHeader()
+	h.FileFormat = "4.2"
+	h.AddFilterLine("x", `Set if true: FILTER==\"PASS\"`)
+	h.AddFilterLine("x", "ignored")
+	lines, err := h.MetaLineStrings()
+	c.Assert(err, IsNil)
+	c.Assert(lines, DeepEquals, []string{`##FILTER=<ID=x,Description="Set if true: FILTER==\"PASS\"">`})
+
+	rdr, err := NewReader(strings.NewReader("##fileformat=VCFv4.2\n"+lines[0]+"\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"), false)
+	c.Assert(err, IsNil)
+	c.Assert(rdr.Header.Filters["x"], Equals, `Set if true: FILTER==\"PASS\"`)
+}
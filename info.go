@@ -146,6 +146,8 @@ func (i *InfoByte) Delete(key string) {
 	}
 }
 
+// ItoS returns the string form of an INFO value. Values are not
+// percent-encoded; InfoByte.Set encodes them when the Header needs it.
 func ItoS(k string, v interface{}) string {
 	if _, ok := v.(bool); ok {
 		return k
@@ -221,8 +223,18 @@ func (i InfoByte) SGet(key string) []byte {
 	return val
 }
 
-// Get a value from the bytes typed according to the header.
+// Get a value from the bytes typed according to the header. If the
+// Header has percent decoding turned on, String and Character values
+// are decoded.
 func (i InfoByte) Get(key string) (interface{}, error) {
+	val, err := i.get(key)
+	if err != nil || !i.header.decoding() {
+		return val, err
+	}
+	return decodeInfoValue(val)
+}
+
+func (i InfoByte) get(key string) (interface{}, error) {
 	v := string(i.SGet(key))
 	skey := string(key)
 	var ok bool
@@ -278,7 +290,7 @@ func (i InfoByte) Get(key string) (interface{}, error) {
 		}
 		return true, err
 
-	case "R", "A", "G", "2", "3", ".":
+	case "R", "A", "G", "P", "LA", "LR", "LG", "2", "3", ".":
 		vals := strings.Split(v, ",")
 		var vi interface{}
 		switch hi.Type {
@@ -359,14 +371,39 @@ func (i *InfoByte) UpdateHeader(key string, value interface{}) {
 	}
 }
 
+// valueString returns the string form of a value for Set. From
+// VCFv4.3, string values are percent-encoded. Before VCFv4.3 there is no
+// way to represent some characters so values containing them are an
+// error.
+func (i *InfoByte) valueString(key string, value interface{}) (string, error) {
+	if i.header.percentEncoded() {
+		return encodeInfoValue(key, value), nil
+	}
+	s := ItoS(key, value)
+	if versionIndex(i.header.fileFormat()) != -1 && strings.ContainsAny(s, unencodableInfoChars) {
+		return s, fmt.Errorf("Info Error: %s value %q cannot be represented in VCFv%s", key, s, i.header.FileFormat)
+	}
+	return s, nil
+}
+
+// Set a value. From VCFv4.3, special characters in string values are
+// percent-encoded. A single string is taken to be a comma-separated
+// list so its commas are kept; use a []string to encode commas.
 func (i *InfoByte) Set(key string, value interface{}) error {
+	var str string
+	if _, ok := value.(bool); !ok {
+		var err error
+		if str, err = i.valueString(key, value); err != nil {
+			return err
+		}
+	}
 	if len(i.Info) == 0 {
 		if v, ok := value.(bool); ok {
 			if v {
 				i.Info = []byte(key)
 			}
 		} else {
-			i.Info = []byte(fmt.Sprintf("%s=%s", key, ItoS(key, value)))
+			i.Info = []byte(fmt.Sprintf("%s=%s", key, str))
 		}
 		return nil
 	}
@@ -379,7 +416,7 @@ func (i *InfoByte) Set(key string, value interface{}) error {
 			}
 			return nil
 		}
-		slug := fmt.Sprintf(";%s=%s", key, ItoS(key, value))
+		slug := fmt.Sprintf(";%s=%s", key, str)
 		i.Info = append(i.Info, slug...)
 		//i.UpdateHeader(key, value)
 		return nil
@@ -390,7 +427,7 @@ func (i *InfoByte) Set(key string, value interface{}) error {
 		}
 		return nil
 	}
-	slug := []byte(ItoS(key, value))
+	slug := []byte(str)
 	if e == -1 {
 		i.Info = append(i.Info[:s], slug...)
 	} else {
@@ -0,0 +1,462 @@
+package vcfgo
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JoinMode selects which records at the same position a Joiner joins.
+type JoinMode int
+
+const (
+	// JoinAny joins all records at a position.
+	JoinAny JoinMode = iota
+	// JoinBoth joins SNPs with SNPs and indels with indels.
+	JoinBoth
+	// JoinSNPs joins SNPs and leaves other records as they are.
+	JoinSNPs
+	// JoinIndels joins indels and leaves other records as they are.
+	JoinIndels
+)
+
+// joinClass returns the class of records that v may be joined with for
+// mode or "" if v is not joined. Records whose ALT alleles all have the
+// length of REF are SNPs, which includes MNPs, and records whose ALT
+// alleles all differ in length from REF are indels. Records with
+// symbolic, breakend or * alleles are only joined by JoinAny.
+func joinClass(v *Variant, mode JoinMode) string {
+	if mode == JoinAny {
+		return `any`
+	}
+	class := ``
+	for _, a := range v.Alternate {
+		c := `indel`
+		switch {
+		case !IsSequenceAllele(a):
+			return ``
+		case len(a) == len(v.Reference):
+			c = `snp`
+		}
+		if class != `` && c != class {
+			return ``
+		}
+		class = c
+	}
+	switch {
+	case class == `snp` && mode == JoinIndels, class == `indel` && mode == JoinSNPs:
+		return ``
+	}
+	return class
+}
+
+// Join joins Variants at the same CHROM and POS into one Variant with the
+// ALT alleles of them all, in order and without duplicates. The first
+// Variant gives the fields that are not per allele. REF is the longest
+// REF and the ALT alleles of Variants with a shorter REF are extended to
+// match; Join returns nil if one REF is not a prefix of another.
+//
+// Number=A, R and G values of INFO and FORMAT fields are placed in the
+// order of the new alleles and values that no Variant has, such as the
+// likelihood of a genotype with ALT alleles from two Variants, are
+// missing. Where Variants have values for the same allele or genotype,
+// those of the earlier Variant are kept and PL is not rescaled. GT is
+// recoded to the new alleles and the GTs of a sample are combined: where
+// Variants call different ALT alleles at the same place they are kept
+// in order of allele and phasing is lost. IDs and FILTERs are combined,
+// QUAL is the largest and the OLD_MULTIALLELIC field added by Split is
+// dropped.
+//
+// The Variants are not changed but lazily parsed samples are parsed.
+// Fields with the wrong number of values are left as they are in the
+// first Variant that has them and the first such error is returned.
+func Join(vars ...*Variant) (*Variant, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	ref := vars[0].Reference
+	for _, v := range vars[1:] {
+		if v.Chromosome != vars[0].Chromosome || v.Pos != vars[0].Pos {
+			return nil, nil
+		}
+		if len(v.Reference) > len(ref) {
+			ref = v.Reference
+		}
+	}
+	alleles := []string{ref}
+	index := map[string]int{ref: 0}
+	maps := make([][]int, len(vars))
+	for i, v := range vars {
+		if !strings.HasPrefix(ref, v.Reference) {
+			return nil, nil
+		}
+		ext := ref[len(v.Reference):]
+		maps[i] = make([]int, len(v.Alternate)+1)
+		for j, a := range v.Alternate {
+			if IsSequenceAllele(a) {
+				a += ext
+			}
+			n, found := index[a]
+			if !found {
+				n = len(alleles)
+				index[a] = n
+				alleles = append(alleles, a)
+			}
+			maps[i][j+1] = n
+		}
+	}
+
+	var first error
+	addErr := func(err error) {
+		if first == nil {
+			first = err
+		}
+	}
+	remapped := make([]*Variant, len(vars))
+	for i, v := range vars {
+		if v.Header != nil {
+			addErr(v.Header.ParseSamples(v))
+		}
+		r := v.Clone()
+		if r.Header != nil {
+			addErr(r.remapAlleles(maps[i], len(alleles)))
+		}
+		remapped[i] = r
+	}
+
+	j := remapped[0]
+	j.Reference, j.Alternate = ref, alleles[1:]
+	ids, filters := joinSet(j.Id_), joinSet(j.Filter)
+	for _, r := range remapped[1:] {
+		ids, filters = append(ids, joinSet(r.Id_)...), append(filters, joinSet(r.Filter)...)
+		if j.Quality == MISSING_VAL || (r.Quality != MISSING_VAL && r.Quality > j.Quality) {
+			j.Quality = r.Quality
+		}
+	}
+	j.Id_ = joinList(ids, `;`, false)
+	j.Filter = joinList(filters, `;`, true)
+	if j.Header == nil {
+		return j, first
+	}
+
+	for _, r := range remapped[1:] {
+		joinInfo(j, r)
+	}
+	j.Info().Delete(OldMultiallelic)
+	joinSamples(j, remapped[1:])
+	return j, first
+}
+
+// joinSet splits an ID or FILTER column, dropping the missing value.
+func joinSet(s string) []string {
+	if s == `` || s == `.` {
+		return nil
+	}
+	return strings.Split(s, `;`)
+}
+
+// joinList joins the unique values of an ID or FILTER column. If filter
+// is true, PASS is dropped when there are other values.
+func joinList(vals []string, sep string, filter bool) string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range vals {
+		if !seen[s] && !(filter && s == `PASS` && len(vals) > 1) {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		if filter && len(vals) > 0 {
+			return `PASS`
+		}
+		return `.`
+	}
+	return strings.Join(out, sep)
+}
+
+// joinValues fills the missing values of into from the values of from.
+func joinValues(into, from []string) {
+	for i := range into {
+		if into[i] == `.` && i < len(from) {
+			into[i] = from[i]
+		}
+	}
+}
+
+// joinInfo copies the INFO fields of r that j is missing and fills the
+// missing Number=A, R and G values of j from r.
+func joinInfo(j, r *Variant) {
+	have := make(map[string]bool)
+	for _, k := range j.Info().Keys() {
+		have[k] = true
+	}
+	for _, k := range r.Info().Keys() {
+		def, found := j.Header.Infos[k]
+		if found && def.Type == `Flag` {
+			if !have[k] {
+				j.Info().Set(k, true)
+			}
+			continue
+		}
+		vals, ok := infoValues(r, k)
+		if !have[k] {
+			if ok {
+				j.Info().Set(k, vals)
+			}
+			continue
+		}
+		if !ok || !found || (def.Number != `A` && def.Number != `R` && def.Number != `G`) {
+			continue
+		}
+		into, _ := infoValues(j, k)
+		if len(into) == len(vals) {
+			joinValues(into, vals)
+			j.Info().Set(k, into)
+		}
+	}
+}
+
+// joinSamples joins the FORMAT fields of each sample of rs into j.
+func joinSamples(j *Variant, rs []*Variant) {
+	has := make(map[string]bool)
+	for _, f := range j.Format {
+		has[f] = true
+	}
+	for _, r := range rs {
+		for _, f := range r.Format {
+			if !has[f] {
+				has[f] = true
+				j.Format = append(j.Format, f)
+			}
+		}
+	}
+	for s, g := range j.Samples {
+		gts := []string{g.Fields[`GT`]}
+		for _, r := range rs {
+			if s >= len(r.Samples) || r.Samples[s] == nil {
+				continue
+			}
+			o := r.Samples[s]
+			gts = append(gts, o.Fields[`GT`])
+			for f, value := range o.Fields {
+				into, found := g.Fields[f]
+				if !found || into == `` || into == `.` {
+					g.Fields[f] = value
+					continue
+				}
+				def, found := j.Header.SampleFormats[f]
+				if f == `GT` || !found || (def.Number != `A` && def.Number != `R` && def.Number != `G`) {
+					continue
+				}
+				vals, from := splitValues(into), splitValues(value)
+				if len(vals) == len(from) {
+					joinValues(vals, from)
+					g.Fields[f] = strings.Join(vals, `,`)
+				}
+			}
+		}
+		if _, found := g.Fields[`GT`]; found {
+			g.Fields[`GT`] = joinGT(gts)
+			g.GT = g.GT[:0]
+			j.Header.setSampleGT(g, g.Fields[`GT`])
+		}
+		for _, f := range j.Format {
+			if _, found := g.Fields[f]; !found {
+				g.Fields[f] = `.`
+			}
+		}
+		if pl, found := g.Fields[`PL`]; found {
+			j.Header.setSampleGL(g, pl, true)
+		} else if gl, found := g.Fields[`GL`]; found {
+			j.Header.setSampleGL(g, gl, false)
+		}
+	}
+}
+
+// joinGT combines GTs that have been recoded to the same alleles. At
+// each place the ALT allele is taken, or REF if there is no ALT allele.
+// If the GTs call different ALT alleles at the same place, the ALT
+// alleles are sorted and unphased, with REF alleles to make up the
+// ploidy, or the GT is missing if there are too many.
+func joinGT(gts []string) string {
+	var out []int
+	phased := true
+	conflict := false
+	var alts []int
+	for _, gt := range gts {
+		alleles, err := gtAlleles(gt)
+		if err != nil || !calledGT(alleles) {
+			continue
+		}
+		if strings.Contains(gt, `/`) || (len(alleles) == 1 && !strings.HasPrefix(gt, `|`)) {
+			phased = false
+		}
+		if out == nil {
+			out = make([]int, len(alleles))
+			for i := range out {
+				out[i] = -1
+			}
+		}
+		for i, a := range alleles {
+			if a > 0 {
+				alts = append(alts, a)
+			}
+			if i >= len(out) || a < 0 {
+				continue
+			}
+			switch {
+			case out[i] <= 0:
+				if a > out[i] {
+					out[i] = a
+				}
+			case a > 0 && a != out[i]:
+				conflict = true
+			}
+		}
+	}
+	if out == nil {
+		return gts[0]
+	}
+	if conflict {
+		sort.Ints(alts)
+		var uniq []int
+		for i, a := range alts {
+			if i == 0 || a != alts[i-1] {
+				uniq = append(uniq, a)
+			}
+		}
+		phased = false
+		for i := range out {
+			out[i] = 0
+			if k := i - (len(out) - len(uniq)); k >= 0 {
+				out[i] = uniq[k]
+			}
+		}
+		if len(uniq) > len(out) {
+			for i := range out {
+				out[i] = -1
+			}
+		}
+	}
+	sep := `/`
+	if phased {
+		sep = `|`
+	}
+	s := make([]string, len(out))
+	for i, a := range out {
+		if a < 0 {
+			s[i] = `.`
+		} else {
+			s[i] = strconv.Itoa(a)
+		}
+	}
+	if len(out) == 1 && phased {
+		return `|` + s[0]
+	}
+	return strings.Join(s, sep)
+}
+
+// calledGT reports whether any allele of a GT is called.
+func calledGT(alleles []int) bool {
+	for _, a := range alleles {
+		if a >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Joiner reads Variants from a Reader and joins consecutive Variants at
+// the same CHROM and POS with Join. Variants that JoinMode leaves as they
+// are, and Variants whose REF cannot be joined, are returned separately.
+// Joined Variants are returned in the order of their first Variant.
+type Joiner struct {
+	Mode JoinMode
+
+	rdr  *Reader
+	site []*Variant
+	next *Variant
+	out  []*Variant
+	verr *VCFError
+}
+
+// NewJoiner returns a Joiner that joins all Variants at a position.
+func NewJoiner(rdr *Reader) *Joiner {
+	return &Joiner{rdr: rdr, verr: NewVCFError()}
+}
+
+// Read returns the next joined Variant or nil at the end of the input.
+// Errors joining Variants are added to those returned by Error.
+func (j *Joiner) Read() *Variant {
+	for len(j.out) == 0 {
+		if j.next == nil {
+			j.next = j.rdr.Read()
+		}
+		if j.next == nil {
+			return nil
+		}
+		j.site = append(j.site[:0], j.next)
+		j.next = nil
+		for {
+			v := j.rdr.Read()
+			if v == nil || v.Chromosome != j.site[0].Chromosome || v.Pos != j.site[0].Pos {
+				j.next = v
+				break
+			}
+			j.site = append(j.site, v)
+		}
+		j.flush()
+	}
+	v := j.out[0]
+	j.out = j.out[1:]
+	return v
+}
+
+// flush joins the Variants of a site into out.
+func (j *Joiner) flush() {
+	type group struct {
+		class string
+		ref   string
+		vars  []*Variant
+	}
+	var groups []*group
+	for _, v := range j.site {
+		class := joinClass(v, j.Mode)
+		var into *group
+		if class != `` {
+			for _, g := range groups {
+				if g.class == class && (strings.HasPrefix(g.ref, v.Reference) || strings.HasPrefix(v.Reference, g.ref)) {
+					into = g
+					break
+				}
+			}
+		}
+		if into == nil {
+			into = &group{class: class}
+			groups = append(groups, into)
+		}
+		into.vars = append(into.vars, v)
+		if len(v.Reference) > len(into.ref) {
+			into.ref = v.Reference
+		}
+	}
+	for _, g := range groups {
+		if len(g.vars) == 1 {
+			j.out = append(j.out, g.vars[0])
+			continue
+		}
+		v, err := Join(g.vars...)
+		j.verr.Add(err, g.vars[0].LineNumber)
+		j.out = append(j.out, v)
+	}
+}
+
+// Error returns the errors joining Variants. Errors reading the input are
+// returned by the Reader.
+func (j *Joiner) Error() error {
+	if j.verr.IsEmpty() {
+		return nil
+	}
+	return j.verr
+}
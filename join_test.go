@@ -0,0 +1,114 @@
+package vcfgo
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var joinStr = `##fileformat=VCFv4.2
+##INFO=<ID=AC,Number=A,Type=Integer,Description="Allele count in genotypes">
+##INFO=<ID=AN,Number=1,Type=Integer,Description="Total number of alleles in called genotypes">
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Approximate read depth">
+##INFO=<ID=DB,Number=0,Type=Flag,Description="dbSNP membership">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths">
+##FORMAT=<ID=GQ,Number=1,Type=Integer,Description="Genotype Quality">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled genotype likelihoods">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	s1	s2
+chr1	100	rs1	A	G	30	PASS	AC=1;AN=4;DP=20	GT:AD:PL	0/1:6,4:40,0,90	0/0:10,0:0,30,300
+chr1	100	rs2	A	T	50	q10	AC=2;AN=4;DP=20;DB	GT:AD:GQ:PL	0/1:5,5:60:50,0,80	1/1:0,10:30:300,30,0
+chr1	100	.	A	AT	20	PASS	AC=1;AN=4;DP=20	GT:AD:PL	0/0:10,0:0,20,200	0/1:5,5:60,0,60
+chr1	100	.	ACT	A	10	PASS	AC=1;AN=4;DP=20	GT:AD:PL	0/1:8,2:20,0,100	0/0:.:.
+chr1	200	.	C	T	.	.	AC=1;AN=4	GT	0|1	0/0
+chr1	300	.	G	GC,GCC	.	.	AC=1,1;AN=4	GT:AD	1/2:0,3,3	0/0:8,0,0
+chr1	300	.	G	<DEL>	.	.	AC=1;AN=4	GT:AD	0/0:6,0	0/1:3,3
+`
+
+type JoinSuite struct{}
+
+var _ = Suite(&JoinSuite{})
+
+func joinAll(c *C, text string, mode JoinMode) []string {
+	rdr, err := NewReader(strings.NewReader(text), true)
+	c.Assert(err, IsNil)
+	j := NewJoiner(rdr)
+	j.Mode = mode
+	var out []string
+	for v := j.Read(); v != nil; v = j.Read() {
+		out = append(out, v.String())
+	}
+	c.Assert(rdr.Error(), IsNil)
+	c.Assert(j.Error(), IsNil)
+	return out
+}
+
+func (s *JoinSuite) TestJoinAny(c *C) {
+	out := joinAll(c, joinStr, JoinAny)
+	c.Assert(out, DeepEquals, []string{
+		"chr1\t100\trs1;rs2\tACT\tGCT,TCT,ATCT,A\t50.0\tq10\tAC=1,2,1,1;AN=4;DP=20;DB\tGT:AD:PL:GQ" +
+			"\t./.:6,4,5,0,2:40,0,90,0,.,80,20,.,.,200,0,.,.,.,100:60" +
+			"\t2/3:10,0,10,5,.:0,30,300,30,.,0,0,.,.,60,.,.,.,.,.:30",
+		"chr1\t200\t.\tC\tT\t.\t.\tAC=1;AN=4\tGT\t0|1\t0/0",
+		"chr1\t300\t.\tG\tGC,GCC,<DEL>\t.\t.\tAC=1,1,1;AN=4\tGT:AD\t1/2:0,3,3,0\t0/3:8,0,0,3",
+	})
+}
+
+func (s *JoinSuite) TestJoinBoth(c *C) {
+	out := joinAll(c, joinStr, JoinBoth)
+	c.Assert(out, DeepEquals, []string{
+		"chr1\t100\trs1;rs2\tA\tG,T\t50.0\tq10\tAC=1,2;AN=4;DP=20;DB\tGT:AD:PL:GQ" +
+			"\t1/2:6,4,5:40,0,90,0,.,80:60\t2/2:10,0,10:0,30,300,30,.,0:30",
+		"chr1\t100\t.\tACT\tATCT,A\t20.0\tPASS\tAC=1,1;AN=4;DP=20\tGT:AD:PL\t0/2:10,0,2:0,20,200,0,.,100\t0/1:5,5,.:60,0,60,.,.,.",
+		"chr1\t200\t.\tC\tT\t.\t.\tAC=1;AN=4\tGT\t0|1\t0/0",
+		"chr1\t300\t.\tG\tGC,GCC\t.\t.\tAC=1,1;AN=4\tGT:AD\t1/2:0,3,3\t0/0:8,0,0",
+		"chr1\t300\t.\tG\t<DEL>\t.\t.\tAC=1;AN=4\tGT:AD\t0/0:6,0\t0/1:3,3",
+	})
+
+	out = joinAll(c, joinStr, JoinSNPs)
+	c.Assert(out, HasLen, 6)
+	c.Assert(strings.Split(out[0], "\t")[4], Equals, "G,T")
+	out = joinAll(c, joinStr, JoinIndels)
+	c.Assert(out, HasLen, 6)
+	c.Assert(strings.Split(out[2], "\t")[4], Equals, "ATCT,A")
+}
+
+func (s *JoinSuite) TestSplitJoin(c *C) {
+	rdr, err := NewReader(strings.NewReader(splitGATK), false)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	split, err := v.Split()
+	c.Assert(err, IsNil)
+	j, err := Join(split...)
+	c.Assert(err, IsNil)
+	c.Assert(j.String(), Equals, "chr20\t2000\trs1\tA\tAT,ATT\t300.0\tPASS\tAC=1,1;AF=0.5,0.5;AN=2;DP=20;MLEAC=1,1;MLEAF=0.5,0.5"+
+		"\tGT:AD:DP:GQ:PL\t1/2:0,8,9:17:99:370,20,0,20,.,0")
+	c.Assert(j.Samples[0].GT, DeepEquals, []int{1, 2})
+	// The split records are unchanged.
+	c.Assert(split[1].Alt(), DeepEquals, []string{"ATT"})
+	c.Assert(split[1].Samples[0].Fields["GT"], Equals, "./1")
+
+	w := &Variant{Chromosome: "chr20", Pos: 2000, Reference: "C", Alternate: []string{"T"}}
+	j, err = Join(split[0], w)
+	c.Assert(err, IsNil)
+	c.Assert(j, IsNil)
+}
+
+func (s *JoinSuite) TestJoinGT(c *C) {
+	for _, t := range []struct {
+		in   []string
+		want string
+	}{
+		{[]string{"0/1", "0/2"}, "1/2"},
+		{[]string{"0|1", "2|0"}, "2|1"},
+		{[]string{"0|1", "0/2"}, "1/2"},
+		{[]string{"0/0", "0/0"}, "0/0"},
+		{[]string{"./.", "0/1"}, "0/1"},
+		{[]string{"1/1", "2/2"}, "1/2"},
+		{[]string{"0/1", "0/2", "0/3"}, "./."},
+		{[]string{"1", "0"}, "1"},
+		{[]string{".", "."}, "."},
+	} {
+		c.Check(joinGT(t.in), Equals, t.want, Commentf("%v", t.in))
+	}
+}
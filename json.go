@@ -0,0 +1,662 @@
+package vcfgo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Header and Variant can be marshalled to and unmarshalled from JSON with
+// encoding/json. The mapping is stable and is designed so that the JSON
+// can be turned back into VCF without loss.
+//
+// A Header is an object with the VCF version, the meta-information lines
+// in the order they are written by NewWriter, and the sample names:
+//
+//  {"fileformat":"4.3",
+//   "meta":[{"key":"source","value":"myCaller"},
+//           {"key":"INFO","fields":{"ID":"DP","Number":"1","Type":"Integer",
+//            "Description":"Depth"},"quoted":["Description"]}],
+//   "samples":["S1","S2"]}
+//
+// Unstructured lines have a value. Structured lines have fields, an
+// object whose keys are in the same order as in the line, and quoted,
+// the keys whose values are quoted. A line that cannot be parsed as
+// either is held verbatim as line.
+//
+// A Variant is an object:
+//
+//  {"chrom":"1","pos":100,"id":["rs1"],"ref":"A","alt":["C","G"],
+//   "qual":50,"filter":["PASS"],"info":{"DP":10,"AF":[0.5,0.1],"DB":true},
+//   "format":["GT","DP"],"samples":{"S1":{"GT":"0/1","DP":7}}}
+//
+// A missing ID, ALT or FILTER is an empty list and a missing QUAL is null.
+// INFO and the FORMAT values of each sample are objects with their keys
+// in record order, and samples are keyed by sample name in header order.
+// Trailing FORMAT fields that were dropped from a sample are left out.
+// Values are typed by the Header: Flags are true, a field with Number=1
+// is a single value and any other Number is a list. Integer and Float
+// values are numbers, String and Character values are strings and
+// missing values are null. A value that does not match its declared type
+// is kept as a string. Fields that are not in the Header are strings
+// holding the value exactly as it is in the VCF. From VCFv4.3, strings
+// are percent-decoded and they are encoded again when the JSON is turned
+// back into VCF.
+//
+// JSONWriter writes JSON lines: the Header on the first line then one
+// Variant per line. JSONReader reads them back.
+
+// jsonMember is a key and value of a JSON object.
+type jsonMember struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// jsonObject is a JSON object that keeps the order of its keys.
+type jsonObject []jsonMember
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(m.Value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func (o *jsonObject) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		*o = nil
+		return nil
+	}
+	if d, ok := t.(json.Delim); !ok || d != '{' {
+		return fmt.Errorf("vcfgo: expected a JSON object but got %s", data)
+	}
+	*o = (*o)[:0]
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		*o = append(*o, jsonMember{Key: t.(string), Value: raw})
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// get returns the value for a key.
+func (o jsonObject) get(key string) (json.RawMessage, bool) {
+	for _, m := range o {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// jsonMetaLine is the JSON form of a meta-information line.
+type jsonMetaLine struct {
+	Key    string     `json:"key,omitempty"`
+	Value  *string    `json:"value,omitempty"`
+	Fields jsonObject `json:"fields,omitempty"`
+	Quoted []string   `json:"quoted,omitempty"`
+	Line   string     `json:"line,omitempty"`
+}
+
+type jsonHeader struct {
+	FileFormat string         `json:"fileformat"`
+	Meta       []jsonMetaLine `json:"meta"`
+	Samples    []string       `json:"samples"`
+}
+
+// MarshalJSON returns the JSON form of the Header.
+func (h *Header) MarshalJSON() ([]byte, error) {
+	lines, err := h.MetaLineStrings()
+	if err != nil {
+		return nil, err
+	}
+	jh := jsonHeader{FileFormat: h.FileFormat, Meta: make([]jsonMetaLine, 0, len(lines)), Samples: h.SampleNames}
+	if jh.Samples == nil {
+		jh.Samples = []string{}
+	}
+	for _, s := range lines {
+		m, err := NewMetaLineFromString(s)
+		if err != nil {
+			jh.Meta = append(jh.Meta, jsonMetaLine{Line: s})
+			continue
+		}
+		jm := jsonMetaLine{Key: m.LineKey}
+		if m.MetaType == Unstructured {
+			value := m.Value
+			jm.Value = &value
+		} else {
+			kvs := make([]*KV, 0, len(m.KVs))
+			for _, kv := range m.KVs {
+				kvs = append(kvs, kv)
+			}
+			sort.Slice(kvs, func(i, j int) bool { return kvs[i].Index < kvs[j].Index })
+			for _, kv := range kvs {
+				value, _ := json.Marshal(kv.Value)
+				jm.Fields = append(jm.Fields, jsonMember{Key: kv.Key, Value: value})
+				if kv.Quote != 0 {
+					jm.Quoted = append(jm.Quoted, kv.Key)
+				}
+			}
+		}
+		jh.Meta = append(jh.Meta, jm)
+	}
+	return json.Marshal(jh)
+}
+
+// UnmarshalJSON sets the Header from its JSON form. Infos,
+// SampleFormats, Filters, Contigs, Samples and Pedigree are populated
+// from the meta-information lines as they are by NewReader.
+func (h *Header) UnmarshalJSON(data []byte) error {
+	var jh jsonHeader
+	if err := json.Unmarshal(data, &jh); err != nil {
+		return err
+	}
+	nh := NewHeader()
+	h.FileFormat = jh.FileFormat
+	h.SampleNames = jh.Samples
+	if h.SampleNames == nil {
+		h.SampleNames = nh.SampleNames
+	}
+	h.Infos, h.SampleFormats, h.Filters = nh.Infos, nh.SampleFormats, nh.Filters
+	h.Extras, h.Samples, h.Pedigree = nh.Extras, nh.Samples, nh.Pedigree
+	h.Lines = nil
+
+	verr := NewVCFError()
+	for i, jm := range jh.Meta {
+		s := jm.Line
+		if s == `` {
+			var err error
+			if s, err = jm.metaString(); err != nil {
+				verr.Add(err, i+2)
+				continue
+			}
+		}
+		m, err := NewMetaLineFromString(s)
+		if err != nil {
+			verr.Add(err, i+2)
+			continue
+		}
+		m.LineNumber = i + 2
+		h.Lines = append(h.Lines, m)
+	}
+	for _, err := range h.parseLines() {
+		verr.Add(err, 0)
+	}
+	if verr.IsEmpty() {
+		return nil
+	}
+	return verr
+}
+
+// metaString returns the VCF form of a jsonMetaLine.
+func (jm jsonMetaLine) metaString() (string, error) {
+	if jm.Value != nil {
+		return `##` + jm.Key + `=` + *jm.Value, nil
+	}
+	fields := make([]string, len(jm.Fields))
+	for i, f := range jm.Fields {
+		var value string
+		if err := json.Unmarshal(f.Value, &value); err != nil {
+			return ``, fmt.Errorf("vcfgo: %s meta line field %s: %v", jm.Key, f.Key, err)
+		}
+		if containsString(jm.Quoted, f.Key) {
+			value = `"` + value + `"`
+		}
+		fields[i] = f.Key + `=` + value
+	}
+	return `##` + jm.Key + `=<` + strings.Join(fields, `,`) + `>`, nil
+}
+
+type jsonVariant struct {
+	Chrom   string          `json:"chrom"`
+	Pos     uint64          `json:"pos"`
+	ID      []string        `json:"id"`
+	Ref     string          `json:"ref"`
+	Alt     []string        `json:"alt"`
+	Qual    json.RawMessage `json:"qual"`
+	Filter  []string        `json:"filter"`
+	Info    jsonObject      `json:"info"`
+	Format  []string        `json:"format,omitempty"`
+	Samples jsonObject      `json:"samples,omitempty"`
+}
+
+// jsonList splits a VCF column into a list with a missing value being an
+// empty list.
+func jsonList(s string, sep string) []string {
+	if s == `` || s == `.` {
+		return []string{}
+	}
+	return strings.Split(s, sep)
+}
+
+// vcfList joins a list into a VCF column.
+func vcfList(l []string, sep string) string {
+	if len(l) == 0 {
+		return `.`
+	}
+	return strings.Join(l, sep)
+}
+
+// MarshalJSON returns the JSON form of the Variant. The Variant is not
+// changed so lazily parsed samples stay unparsed.
+func (v *Variant) MarshalJSON() ([]byte, error) {
+	h := v.Header
+	jv := jsonVariant{
+		Chrom:  v.Chromosome,
+		Pos:    v.Pos,
+		ID:     jsonList(v.Id_, `;`),
+		Ref:    v.Reference,
+		Alt:    jsonList(strings.Join(v.Alternate, `,`), `,`),
+		Qual:   json.RawMessage(`null`),
+		Filter: jsonList(v.Filter, `;`),
+		Info:   jsonObject{},
+		Format: v.Format,
+	}
+	if v.Quality != MISSING_VAL {
+		jv.Qual = json.RawMessage(jsonNumber(strconv.FormatFloat(float64(v.Quality), 'f', -1, 32)))
+	}
+
+	if v.Info_ != nil {
+		for _, kv := range bytes.Split(v.Info_.Bytes(), []byte{';'}) {
+			if len(kv) == 0 || (len(kv) == 1 && kv[0] == '.') {
+				continue
+			}
+			parts := strings.SplitN(string(kv), `=`, 2)
+			m := jsonMember{Key: parts[0], Value: json.RawMessage(`true`)}
+			if len(parts) == 2 {
+				number, typ, known := ``, ``, false
+				if h != nil {
+					if i, found := h.Infos[parts[0]]; found {
+						number, typ, known = i.Number, i.Type, true
+					}
+				}
+				m.Value = h.jsonValue(parts[1], number, typ, known)
+			}
+			jv.Info = append(jv.Info, m)
+		}
+	}
+
+	samples := v.wireSamples()
+	for i, values := range samples {
+		name := strconv.Itoa(i + 1)
+		if h != nil && i < len(h.SampleNames) {
+			name = h.SampleNames[i]
+		}
+		var fields jsonObject
+		for j, f := range v.Format {
+			// Dropped trailing fields are left out.
+			if j >= len(values) {
+				break
+			}
+			number, typ, known := ``, ``, false
+			if h != nil {
+				if sf, found := h.SampleFormats[f]; found {
+					number, typ, known = sf.Number, sf.Type, true
+				}
+			}
+			fields = append(fields, jsonMember{Key: f, Value: h.jsonValue(values[j], number, typ, known)})
+		}
+		value, err := fields.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		jv.Samples = append(jv.Samples, jsonMember{Key: name, Value: value})
+	}
+	return json.Marshal(jv)
+}
+
+// wireSamples returns the values of each sample as they are in the VCF.
+func (v *Variant) wireSamples() [][]string {
+	if v.Samples == nil {
+		if v.sampleString == `` {
+			return nil
+		}
+		samples := strings.Split(v.sampleString, "\t")
+		values := make([][]string, len(samples))
+		for i, s := range samples {
+			values[i] = strings.Split(s, `:`)
+		}
+		return values
+	}
+	values := make([][]string, len(v.Samples))
+	for i, g := range v.Samples {
+		values[i] = make([]string, len(v.Format))
+		for j, f := range v.Format {
+			values[i][j] = g.Fields[f]
+			if v.Header.decoding() && v.Header.isTextField(f) {
+				values[i][j] = PercentEncode(values[i][j], formatSpecialChars)
+			}
+		}
+	}
+	return values
+}
+
+// jsonNumber returns s if it is a valid JSON number, s reformatted if it
+// is a number that JSON cannot represent as written (e.g. .5) or s as a
+// JSON string if it is not a finite number.
+func jsonNumber(s string) string {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+	if json.Valid([]byte(s)) {
+		return s
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// jsonValue returns the JSON form of an INFO or FORMAT value as it is in
+// the VCF. If the field is not known the value is a string.
+func (h *Header) jsonValue(value string, number string, typ string, known bool) json.RawMessage {
+	if !known {
+		b, _ := json.Marshal(value)
+		return b
+	}
+	if typ == `Flag` {
+		return json.RawMessage(`true`)
+	}
+	one := func(s string) string {
+		if s == `.` || s == `` {
+			return `null`
+		}
+		switch typ {
+		case `Integer`, `Float`:
+			return jsonNumber(s)
+		}
+		if h.percentEncoded() {
+			if d, err := PercentDecode(s); err == nil {
+				s = d
+			}
+		}
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+	if value == `.` {
+		return json.RawMessage(`null`)
+	}
+	if number == `1` {
+		return json.RawMessage(one(value))
+	}
+	vals := strings.Split(value, `,`)
+	for i, s := range vals {
+		vals[i] = one(s)
+	}
+	return json.RawMessage(`[` + strings.Join(vals, `,`) + `]`)
+}
+
+// vcfValue returns the VCF form of a JSON INFO or FORMAT value and
+// whether it is a Flag that is set. Strings are percent-encoded with
+// special if it is not empty.
+func vcfValue(raw json.RawMessage, special string) (string, bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return ``, false, err
+	}
+	one := func(e interface{}, special string) (string, error) {
+		switch x := e.(type) {
+		case nil:
+			return `.`, nil
+		case json.Number:
+			return x.String(), nil
+		case string:
+			if special != `` {
+				return PercentEncode(x, special), nil
+			}
+			return x, nil
+		}
+		return ``, fmt.Errorf("vcfgo: unexpected JSON value %s", raw)
+	}
+	switch x := value.(type) {
+	case bool:
+		return ``, x, nil
+	case []interface{}:
+		vals := make([]string, len(x))
+		for i, e := range x {
+			s, err := one(e, special)
+			if err != nil {
+				return ``, false, err
+			}
+			vals[i] = s
+		}
+		if len(vals) == 0 {
+			return `.`, false, nil
+		}
+		return strings.Join(vals, `,`), false, nil
+	}
+	s, err := one(value, special)
+	return s, false, err
+}
+
+// UnmarshalJSON sets the Variant from its JSON form. If the Variant has
+// a Header, it is used to encode the values and order the samples.
+// Otherwise a Header with the sample names from the JSON is created. The
+// samples are left unparsed, as if read lazily, so call
+// Header.ParseSamples() to access them.
+func (v *Variant) UnmarshalJSON(data []byte) error {
+	var jv jsonVariant
+	if err := json.Unmarshal(data, &jv); err != nil {
+		return err
+	}
+	h := v.Header
+	if h == nil {
+		h = NewHeader()
+		for _, m := range jv.Samples {
+			h.SampleNames = append(h.SampleNames, m.Key)
+		}
+	}
+	*v = Variant{
+		Chromosome: jv.Chrom,
+		Pos:        jv.Pos,
+		Id_:        vcfList(jv.ID, `;`),
+		Reference:  jv.Ref,
+		Alternate:  strings.Split(vcfList(jv.Alt, `,`), `,`),
+		Quality:    MISSING_VAL,
+		Filter:     vcfList(jv.Filter, `;`),
+		Format:     jv.Format,
+		Header:     h,
+	}
+	if len(jv.Qual) > 0 && string(jv.Qual) != `null` {
+		q, err := strconv.ParseFloat(string(jv.Qual), 32)
+		if err != nil {
+			return fmt.Errorf("vcfgo: bad qual %s: %v", jv.Qual, err)
+		}
+		v.Quality = float32(q)
+	}
+
+	var info []string
+	for _, m := range jv.Info {
+		special := ``
+		if _, found := h.Infos[m.Key]; found && h.percentEncoded() {
+			special = infoSpecialChars
+		}
+		s, flag, err := vcfValue(m.Value, special)
+		if err != nil {
+			return fmt.Errorf("INFO/%s: %v", m.Key, err)
+		}
+		if flag {
+			info = append(info, m.Key)
+		} else if s != `` {
+			info = append(info, m.Key+`=`+s)
+		}
+	}
+	v.Info_ = NewInfoByte([]byte(vcfList(info, `;`)), h)
+
+	if len(v.Format) == 0 {
+		return nil
+	}
+	samples := make([]string, len(h.SampleNames))
+	for i, name := range h.SampleNames {
+		raw, found := jv.Samples.get(name)
+		if !found {
+			samples[i] = strings.Join(missingValues(len(v.Format)), `:`)
+			continue
+		}
+		var fields jsonObject
+		if err := fields.UnmarshalJSON(raw); err != nil {
+			return fmt.Errorf("sample %s: %v", name, err)
+		}
+		values := missingValues(len(v.Format))
+		last := 0
+		for j, f := range v.Format {
+			value, found := fields.get(f)
+			if !found {
+				continue
+			}
+			last = j
+			special := ``
+			if _, known := h.SampleFormats[f]; known && h.percentEncoded() {
+				special = formatSpecialChars
+			}
+			s, flag, err := vcfValue(value, special)
+			if err != nil {
+				return fmt.Errorf("sample %s FORMAT/%s: %v", name, f, err)
+			}
+			if flag {
+				s = `.`
+			}
+			values[j] = s
+		}
+		// Trailing fields that are not present were dropped.
+		samples[i] = strings.Join(values[:last+1], `:`)
+	}
+	v.sampleString = strings.Join(samples, "\t")
+	return nil
+}
+
+// JSONWriter writes a Header and Variants as JSON lines.
+type JSONWriter struct {
+	w    io.Writer
+	verr *VCFError
+}
+
+// NewJSONWriter returns a JSONWriter after writing the Header as the
+// first line.
+func NewJSONWriter(w io.Writer, h *Header) (*JSONWriter, error) {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", b); err != nil {
+		return nil, err
+	}
+	return &JSONWriter{w, NewVCFError()}, nil
+}
+
+// WriteVariant writes a single variant as a line of JSON.
+func (jw *JSONWriter) WriteVariant(v *Variant) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		jw.verr.Add(err, v.LineNumber)
+		return
+	}
+	fmt.Fprintf(jw.w, "%s\n", b)
+}
+
+// Error aggregates any errors that occurred while writing variants.
+func (jw *JSONWriter) Error() error {
+	if jw.verr.IsEmpty() {
+		return nil
+	}
+	return jw.verr
+}
+
+// JSONReader reads the JSON lines written by JSONWriter. The Variants it
+// returns can be written as VCF with a Writer for its Header.
+type JSONReader struct {
+	Header     *Header
+	LineNumber int
+	buf        *bufio.Reader
+	verr       *VCFError
+}
+
+// NewJSONReader returns a JSONReader after reading the Header from the
+// first line.
+func NewJSONReader(r io.Reader) (*JSONReader, error) {
+	jr := &JSONReader{Header: NewHeader(), buf: bufio.NewReaderSize(r, 32768*2), verr: NewVCFError()}
+	line := jr.readLine()
+	if line == nil {
+		return nil, fmt.Errorf("vcfgo: no JSON header: %v", jr.Error())
+	}
+	if err := json.Unmarshal(line, jr.Header); err != nil {
+		return jr, err
+	}
+	return jr, nil
+}
+
+// readLine returns the next non-empty line or nil at the end of input.
+func (jr *JSONReader) readLine() []byte {
+	for {
+		line, err := jr.buf.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			jr.verr.Add(err, jr.LineNumber)
+		}
+		if len(line) == 0 && err != nil {
+			return nil
+		}
+		jr.LineNumber++
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return line
+		}
+	}
+}
+
+// Read returns the next Variant or nil at the end of input. Errors are
+// reported via Error().
+func (jr *JSONReader) Read() *Variant {
+	for {
+		line := jr.readLine()
+		if line == nil {
+			return nil
+		}
+		v := &Variant{Header: jr.Header}
+		if err := json.Unmarshal(line, v); err != nil {
+			jr.verr.Add(err, jr.LineNumber)
+			if v.Chromosome == `` {
+				continue
+			}
+		}
+		v.LineNumber = jr.LineNumber
+		return v
+	}
+}
+
+// Error aggregates the errors that occurred while reading.
+func (jr *JSONReader) Error() error {
+	if jr.verr.IsEmpty() {
+		return nil
+	}
+	return jr.verr
+}
@@ -0,0 +1,96 @@
+package vcfgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var jsonStr = `##fileformat=VCFv4.3
+##source=test
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Depth">
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency">
+##INFO=<ID=DB,Number=0,Type=Flag,Description="dbSNP">
+##INFO=<ID=NOTE,Number=1,Type=String,Description="Free text">
+##FILTER=<ID=q10,Description="Quality below 10">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths">
+##FORMAT=<ID=FT,Number=1,Type=String,Description="Sample filter">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2
+1	100	rs1	A	C,G	50	PASS	DP=10;AF=0.5,.;DB;NOTE=a%3Bb;XX=1	GT:AD:FT	0/1:5,4,.:PASS	./.:.:q%3A10
+1	200	.	T	.	.	q10;LowQual	.	GT:AD:FT	0/0:9:.	0/0:8:.
+`
+
+type JSONSuite struct{}
+
+var _ = Suite(&JSONSuite{})
+
+func (s *JSONSuite) TestVariant(c *C) {
+	rdr, err := NewReader(strings.NewReader(jsonStr), true)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	b, err := json.Marshal(v)
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"chrom":"1","pos":100,"id":["rs1"],"ref":"A","alt":["C","G"],"qual":50,"filter":["PASS"],`+
+		`"info":{"DP":10,"AF":[0.5,null],"DB":true,"NOTE":"a;b","XX":"1"},"format":["GT","AD","FT"],`+
+		`"samples":{"S1":{"GT":"0/1","AD":[5,4,null],"FT":"PASS"},"S2":{"GT":"./.","AD":null,"FT":"q:10"}}}`)
+
+	v = rdr.Read()
+	b, err = json.Marshal(v)
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"chrom":"1","pos":200,"id":[],"ref":"T","alt":[],"qual":null,"filter":["q10","LowQual"],`+
+		`"info":{},"format":["GT","AD","FT"],"samples":{"S1":{"GT":"0/0","AD":[9],"FT":null},"S2":{"GT":"0/0","AD":[8],"FT":null}}}`)
+
+	// Parsed samples give the same JSON.
+	rdr, _ = NewReader(strings.NewReader(jsonStr), false)
+	rdr.Header.SetPercentDecoding(true)
+	v = rdr.Read()
+	b2, err := json.Marshal(v)
+	c.Assert(err, IsNil)
+	v = &Variant{Header: rdr.Header}
+	c.Assert(json.Unmarshal(b2, v), IsNil)
+	c.Assert(rdr.Header.ParseSamples(v), IsNil)
+	c.Assert(v.Samples[1].Fields["FT"], Equals, "q:10")
+	c.Assert(v.String(), Equals, "1\t100\trs1\tA\tC,G\t50.0\tPASS\tDP=10;AF=0.5,.;DB;NOTE=a%3Bb;XX=1\tGT:AD:FT\t0/1:5,4,.:PASS\t./.:.:q%3A10")
+}
+
+func (s *JSONSuite) TestHeader(c *C) {
+	rdr, err := NewReader(strings.NewReader(jsonStr), true)
+	c.Assert(err, IsNil)
+	b, err := json.Marshal(rdr.Header)
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasPrefix(string(b), `{"fileformat":"4.3","meta":[{"key":"source","value":"test"},`+
+		`{"key":"INFO","fields":{"ID":"DP","Number":"1","Type":"Integer","Description":"Depth"},"quoted":["Description"]},`), Equals, true)
+
+	h := NewHeader()
+	c.Assert(json.Unmarshal(b, h), IsNil)
+	c.Assert(h.Infos["AF"].Number, Equals, "A")
+	c.Assert(h.SampleNames, DeepEquals, []string{"S1", "S2"})
+	c.Assert(h.Filters["q10"], Equals, "Quality below 10")
+}
+
+func (s *JSONSuite) TestRoundTrip(c *C) {
+	rdr, err := NewReader(strings.NewReader(jsonStr), true)
+	c.Assert(err, IsNil)
+	var js bytes.Buffer
+	jw, err := NewJSONWriter(&js, rdr.Header)
+	c.Assert(err, IsNil)
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		jw.WriteVariant(v)
+	}
+	c.Assert(jw.Error(), IsNil)
+	c.Assert(strings.Count(js.String(), "\n"), Equals, 3)
+
+	jr, err := NewJSONReader(&js)
+	c.Assert(err, IsNil)
+	var out bytes.Buffer
+	wtr, err := NewWriter(&out, jr.Header)
+	c.Assert(err, IsNil)
+	for v := jr.Read(); v != nil; v = jr.Read() {
+		wtr.WriteVariant(v)
+	}
+	c.Assert(jr.Error(), IsNil)
+	c.Assert(out.String(), Equals, strings.Replace(jsonStr, "\t50\t", "\t50.0\t", 1))
+}
@@ -0,0 +1,439 @@
+package vcfgo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Local alleles (VCFv4.4) keep the size of Number=R and Number=G fields
+// manageable for records with many ALT alleles, e.g. from large joint
+// calls. FORMAT/LAA lists, per sample, the 1-based indices of the ALT
+// alleles that are relevant to the sample and the local fields LGT, LAD
+// and LPL are then indexed by local allele: local allele 0 is REF and
+// local allele i is ALT allele LAA[i-1].
+//
+// Expanding local alleles computes GT, AD and PL from LGT, LAD and LPL.
+// Values for genotypes and alleles that are not local to a sample are
+// missing. Compacting does the reverse and keeps GT.
+//
+// The Header is shared by many Variants so it is not changed by the
+// Variant methods - call Header.ExpandLocalAlleleLines or
+// Header.CompactLocalAlleleLines once before writing the Header.
+
+// fieldDef is the Number, Type and Description of a FORMAT field.
+type fieldDef struct {
+	number, typ, desc string
+}
+
+// Definitions of the FORMAT fields involved in local alleles.
+var localAlleleFormats = map[string]fieldDef{
+	`GT`:  {`1`, `String`, `Genotype`},
+	`AD`:  {NumberAlleles, `Integer`, `Allelic depths for the ref and alt alleles in the order listed`},
+	`PL`:  {NumberGenotypes, `Integer`, `Phred-scaled genotype likelihoods rounded to the closest integer`},
+	`LAA`: {NumberUnknown, `Integer`, `1-based indices into ALT, indicating which alleles are relevant (local) for the current sample`},
+	`LGT`: {`1`, `String`, `Local genotype`},
+	`LAD`: {NumberLocalAlleles, `Integer`, `Local allelic depths for the ref and alt alleles`},
+	`LPL`: {NumberLocalGenotypes, `Integer`, `Local phred-scaled genotype likelihoods rounded to the closest integer`},
+}
+
+// The local fields and their global counterparts.
+var localToGlobal = map[string]string{`LGT`: `GT`, `LAD`: `AD`, `LPL`: `PL`}
+var globalToLocal = map[string]string{`GT`: `LGT`, `AD`: `LAD`, `PL`: `LPL`}
+
+// LocalAlleles returns the global allele index of each local allele of
+// the sample, i.e. 0 followed by the values of LAA.
+func (g *SampleGenotype) LocalAlleles() ([]int, error) {
+	local := []int{0}
+	for _, s := range splitValues(g.Fields[`LAA`]) {
+		a, err := strconv.Atoi(s)
+		if err != nil || a < 1 {
+			return nil, fmt.Errorf("bad LAA value: %s", g.Fields[`LAA`])
+		}
+		local = append(local, a)
+	}
+	return local, nil
+}
+
+// GlobalValue returns the value of GT, AD or PL computed from LAA and
+// LGT, LAD or LPL for a record with nAlts ALT alleles.
+func (g *SampleGenotype) GlobalValue(field string, nAlts int) (string, error) {
+	lf, ok := globalToLocal[field]
+	if !ok {
+		return ``, fmt.Errorf("%s has no local equivalent", field)
+	}
+	value, found := g.Fields[lf]
+	if !found {
+		return ``, fmt.Errorf("%s not found in genotypes", lf)
+	}
+	local, err := g.LocalAlleles()
+	if err != nil {
+		return ``, err
+	}
+	for _, a := range local {
+		if a > nAlts {
+			return ``, fmt.Errorf("LAA allele %d but only %d ALT alleles", a, nAlts)
+		}
+	}
+
+	switch field {
+	case `GT`:
+		return recodeGT(value, func(a int) int {
+			if a >= len(local) {
+				return -1
+			}
+			return local[a]
+		})
+	case `AD`:
+		vals := splitValues(value)
+		if vals == nil {
+			return `.`, nil
+		}
+		if len(vals) != len(local) {
+			return ``, fmt.Errorf("LAD has %d values for %d local alleles", len(vals), len(local))
+		}
+		out := missingValues(nAlts + 1)
+		for i, a := range local {
+			out[a] = vals[i]
+		}
+		return strings.Join(out, `,`), nil
+	}
+
+	// PL
+	vals := splitValues(value)
+	if vals == nil {
+		return `.`, nil
+	}
+	ploidy := ploidyOf(g.Fields[`LGT`])
+	if _, found := g.Fields[`LGT`]; !found {
+		ploidy = ploidyOf(g.Fields[`GT`])
+	}
+	order := genotypeOrder(len(local), ploidy)
+	if len(vals) != len(order) {
+		return ``, fmt.Errorf("LPL has %d values for %d local genotypes", len(vals), len(order))
+	}
+	out := missingValues(genotypeCount(nAlts+1, ploidy))
+	for i, lg := range order {
+		gg := make([]int, len(lg))
+		for j, a := range lg {
+			gg[j] = local[a]
+		}
+		out[genotypeIndex(gg)] = vals[i]
+	}
+	return strings.Join(out, `,`), nil
+}
+
+// LocalValue returns the value of LGT, LAD or LPL for the supplied local
+// alleles (as returned by LocalAlleles) computed from GT, AD or PL.
+func (g *SampleGenotype) LocalValue(field string, local []int) (string, error) {
+	gf, ok := localToGlobal[field]
+	if !ok {
+		return ``, fmt.Errorf("%s is not a local field", field)
+	}
+	value, found := g.Fields[gf]
+	if !found {
+		return ``, fmt.Errorf("%s not found in genotypes", gf)
+	}
+	index := make(map[int]int, len(local))
+	for i, a := range local {
+		index[a] = i
+	}
+
+	switch field {
+	case `LGT`:
+		var missing int
+		s, err := recodeGT(value, func(a int) int {
+			i, found := index[a]
+			if !found {
+				missing = a
+				return -1
+			}
+			return i
+		})
+		if err == nil && missing != 0 {
+			err = fmt.Errorf("GT allele %d is not a local allele", missing)
+		}
+		return s, err
+	case `LAD`:
+		vals := splitValues(value)
+		if vals == nil {
+			return `.`, nil
+		}
+		out := make([]string, len(local))
+		for i, a := range local {
+			if a >= len(vals) {
+				return ``, fmt.Errorf("AD has no value for allele %d", a)
+			}
+			out[i] = vals[a]
+		}
+		return strings.Join(out, `,`), nil
+	}
+
+	// LPL
+	vals := splitValues(value)
+	if vals == nil {
+		return `.`, nil
+	}
+	order := genotypeOrder(len(local), ploidyOf(g.Fields[`GT`]))
+	out := make([]string, len(order))
+	for i, lg := range order {
+		gg := make([]int, len(lg))
+		for j, a := range lg {
+			gg[j] = local[a]
+		}
+		gi := genotypeIndex(gg)
+		if gi >= len(vals) {
+			return ``, fmt.Errorf("PL has no value for genotype %d", gi)
+		}
+		out[i] = vals[gi]
+	}
+	return strings.Join(out, `,`), nil
+}
+
+// ExpandLocalAlleles replaces LGT, LAD and LPL with GT, AD and PL for
+// every sample. LAA is removed unless other local fields remain. If GT
+// is already present it is kept and LGT is dropped. Lazily parsed
+// samples are parsed first. If any sample cannot be expanded an error
+// is returned and the samples and FORMAT are unchanged.
+func (v *Variant) ExpandLocalAlleles() error {
+	if !containsString(v.Format, `LAA`) {
+		return nil
+	}
+	if err := v.Header.ParseSamples(v); err != nil {
+		return err
+	}
+	nAlts := len(v.Alternate)
+	values := make([]map[string]string, len(v.Samples))
+	for i, g := range v.Samples {
+		values[i] = make(map[string]string)
+		for lf, gf := range localToGlobal {
+			if !containsString(v.Format, lf) || (gf == `GT` && containsString(v.Format, `GT`)) {
+				continue
+			}
+			val, err := g.GlobalValue(gf, nAlts)
+			if err != nil {
+				return fmt.Errorf("%s:%d %v", v.Chromosome, v.Pos, err)
+			}
+			values[i][gf] = val
+		}
+	}
+	for i, g := range v.Samples {
+		for lf := range localToGlobal {
+			if containsString(v.Format, lf) {
+				delete(g.Fields, lf)
+			}
+		}
+		for gf, val := range values[i] {
+			g.Fields[gf] = val
+			switch gf {
+			case `GT`:
+				g.GT = g.GT[:0]
+				v.Header.setSampleGT(g, val)
+			case `PL`:
+				v.Header.setSampleGL(g, val, true)
+			}
+		}
+	}
+
+	keepLAA := false
+	for _, f := range v.Format {
+		if _, found := localToGlobal[f]; !found && f != `LAA` && v.Header.isLocalField(f) {
+			keepLAA = true
+		}
+	}
+	format := make([]string, 0, len(v.Format))
+	for _, f := range v.Format {
+		if gf, found := localToGlobal[f]; found {
+			if !containsString(v.Format, gf) {
+				format = append(format, gf)
+			}
+			continue
+		}
+		if f == `LAA` && !keepLAA {
+			for _, g := range v.Samples {
+				delete(g.Fields, `LAA`)
+			}
+			continue
+		}
+		format = append(format, f)
+	}
+	v.Format = format
+	return nil
+}
+
+// CompactLocalAlleles replaces AD and PL with LAA, LAD and LPL for every
+// sample, keeping at most maxAlts local ALT alleles per sample. The ALT
+// alleles in the sample's GT are always local and the remaining places
+// go to the other alleles with the highest AD. Alleles with no reads are
+// not made local. GT is kept. It is an error for a GT to have more than
+// maxAlts ALT alleles. Lazily parsed samples are parsed first. If any
+// sample cannot be compacted an error is returned and the samples and
+// FORMAT are unchanged.
+func (v *Variant) CompactLocalAlleles(maxAlts int) error {
+	if containsString(v.Format, `LAA`) {
+		return nil
+	}
+	hasAD, hasPL := containsString(v.Format, `AD`), containsString(v.Format, `PL`)
+	if !hasAD && !hasPL {
+		return nil
+	}
+	if err := v.Header.ParseSamples(v); err != nil {
+		return err
+	}
+	values := make([]map[string]string, len(v.Samples))
+	for i, g := range v.Samples {
+		local, err := g.chooseLocalAlleles(maxAlts)
+		if err != nil {
+			return fmt.Errorf("%s:%d %v", v.Chromosome, v.Pos, err)
+		}
+		laa := make([]string, len(local)-1)
+		for j, a := range local[1:] {
+			laa[j] = strconv.Itoa(a)
+		}
+		values[i] = map[string]string{`LAA`: `.`}
+		if len(laa) > 0 {
+			values[i][`LAA`] = strings.Join(laa, `,`)
+		}
+		for _, lf := range []string{`LAD`, `LPL`} {
+			gf := localToGlobal[lf]
+			if _, found := g.Fields[gf]; !found {
+				continue
+			}
+			val, err := g.LocalValue(lf, local)
+			if err != nil {
+				return fmt.Errorf("%s:%d %v", v.Chromosome, v.Pos, err)
+			}
+			values[i][lf] = val
+		}
+	}
+	for i, g := range v.Samples {
+		for lf, val := range values[i] {
+			g.Fields[lf] = val
+			if gf, found := localToGlobal[lf]; found {
+				delete(g.Fields, gf)
+			}
+		}
+	}
+
+	format := make([]string, 0, len(v.Format)+1)
+	for _, f := range v.Format {
+		switch f {
+		case `AD`, `PL`:
+			if !containsString(format, `LAA`) {
+				format = append(format, `LAA`)
+			}
+			format = append(format, globalToLocal[f])
+		default:
+			format = append(format, f)
+		}
+	}
+	v.Format = format
+	return nil
+}
+
+// chooseLocalAlleles picks the local alleles for a sample.
+func (g *SampleGenotype) chooseLocalAlleles(maxAlts int) ([]int, error) {
+	chosen := make(map[int]bool)
+	alleles, err := gtAlleles(g.Fields[`GT`])
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range alleles {
+		if a > 0 {
+			chosen[a] = true
+		}
+	}
+	if len(chosen) > maxAlts {
+		return nil, fmt.Errorf("GT %s has more than %d ALT alleles", g.Fields[`GT`], maxAlts)
+	}
+
+	type depth struct{ allele, reads int }
+	var depths []depth
+	for i, s := range splitValues(g.Fields[`AD`]) {
+		n, err := strconv.Atoi(s)
+		if i == 0 || err != nil || n == 0 || chosen[i] {
+			continue
+		}
+		depths = append(depths, depth{i, n})
+	}
+	sort.SliceStable(depths, func(i, j int) bool { return depths[i].reads > depths[j].reads })
+	for _, d := range depths {
+		if len(chosen) >= maxAlts {
+			break
+		}
+		chosen[d.allele] = true
+	}
+
+	local := make([]int, 0, len(chosen)+1)
+	for a := range chosen {
+		local = append(local, a)
+	}
+	sort.Ints(local)
+	return append([]int{0}, local...), nil
+}
+
+// isLocalField reports whether a FORMAT field has one of the
+// local-allele Numbers.
+func (h *Header) isLocalField(id string) bool {
+	f, found := h.SampleFormats[id]
+	if !found {
+		return false
+	}
+	switch f.Number {
+	case NumberLocalAltAlleles, NumberLocalAlleles, NumberLocalGenotypes:
+		return true
+	}
+	return false
+}
+
+// ExpandLocalAlleleLines updates the Header for Variants that have had
+// ExpandLocalAlleles called. FORMAT lines for GT, AD and PL are added
+// for each of LGT, LAD and LPL and the local lines are removed. The LAA
+// line is kept if other local fields remain.
+func (h *Header) ExpandLocalAlleleLines() {
+	for _, lf := range []string{`LGT`, `LAD`, `LPL`} {
+		if _, found := h.SampleFormats[lf]; !found {
+			continue
+		}
+		gf := localToGlobal[lf]
+		d := localAlleleFormats[gf]
+		h.AddFormatLine(gf, d.number, d.typ, d.desc)
+		h.RemoveLine(`FORMAT`, lf)
+	}
+	for id := range h.SampleFormats {
+		if h.isLocalField(id) {
+			return
+		}
+	}
+	h.RemoveLine(`FORMAT`, `LAA`)
+}
+
+// CompactLocalAlleleLines updates the Header for Variants that have had
+// CompactLocalAlleles called. FORMAT lines for LAA, LAD and LPL replace
+// the AD and PL lines and, because the local Numbers need VCFv4.4,
+// FileFormat is raised to 4.4 if it is older.
+func (h *Header) CompactLocalAlleleLines() {
+	for _, gf := range []string{`AD`, `PL`} {
+		if _, found := h.SampleFormats[gf]; !found {
+			continue
+		}
+		for _, f := range []string{`LAA`, globalToLocal[gf]} {
+			d := localAlleleFormats[f]
+			h.AddFormatLine(f, d.number, d.typ, d.desc)
+		}
+		h.RemoveLine(`FORMAT`, gf)
+	}
+	if v := versionIndex(h.FileFormat); v != -1 && v < v44 {
+		h.FileFormat = vcfVersions[v44]
+	}
+}
+
+func containsString(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var localStr = `##fileformat=VCFv4.4
+##FORMAT=<ID=LGT,Number=1,Type=String,Description="Local genotype">
+##FORMAT=<ID=LAA,Number=.,Type=Integer,Description="Local alternate alleles">
+##FORMAT=<ID=LAD,Number=LR,Type=Integer,Description="Local allelic depths">
+##FORMAT=<ID=LPL,Number=LG,Type=Integer,Description="Local phred-scaled genotype likelihoods">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2	S3
+1	100	.	A	C,G,T	50	PASS	.	LGT:LAA:LAD:LPL	0/1:3:10,8:0,20,200	1|2:1,2:0,5,6:90,30,80,40,0,70	0/0:.:12:0
+`
+
+var globalStr = `##fileformat=VCFv4.2
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled genotype likelihoods">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2
+1	100	.	A	C,G,T	50	PASS	.	GT:AD:PL	0/1:10,8,0,1:0,20,200,1,2,3,4,5,6,7	2/2:0,1,9,3:90,30,80,40,20,0,7,7,7,7
+`
+
+type LocalSuite struct{}
+
+var _ = Suite(&LocalSuite{})
+
+func (s *LocalSuite) TestGenotypeOrder(c *C) {
+	c.Assert(genotypeOrder(3, 2), DeepEquals, [][]int{{0, 0}, {0, 1}, {1, 1}, {0, 2}, {1, 2}, {2, 2}})
+	c.Assert(genotypeOrder(2, 3), DeepEquals, [][]int{{0, 0, 0}, {0, 0, 1}, {0, 1, 1}, {1, 1, 1}})
+	c.Assert(genotypeIndex([]int{2, 1}), Equals, 4)
+	gt, err := recodeGT("|0|1/.", func(a int) int { return a + 2 })
+	c.Assert(err, IsNil)
+	c.Assert(gt, Equals, "|2|3/.")
+}
+
+func (s *LocalSuite) TestGetGenotypeField(c *C) {
+	rdr, err := NewReader(strings.NewReader(localStr), false)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+
+	pl, err := v.GetGenotypeField(v.Samples[0], "PL", -1)
+	c.Assert(err, IsNil)
+	c.Assert(pl, DeepEquals, []int{0, -1, -1, -1, -1, -1, 20, -1, -1, 200})
+	ad, err := v.GetGenotypeField(v.Samples[1], "AD", -1)
+	c.Assert(err, IsNil)
+	c.Assert(ad, DeepEquals, []int{0, 5, 6, -1})
+	gt, err := v.GetGenotypeField(v.Samples[1], "GT", "")
+	c.Assert(err, IsNil)
+	c.Assert(gt, Equals, "1|2")
+	lpl, err := v.GetGenotypeField(v.Samples[1], "LPL", -1)
+	c.Assert(err, IsNil)
+	c.Assert(lpl, DeepEquals, []int{90, 30, 80, 40, 0, 70})
+}
+
+func (s *LocalSuite) TestExpand(c *C) {
+	for _, lazy := range []bool{false, true} {
+		rdr, err := NewReader(strings.NewReader(localStr), lazy)
+		c.Assert(err, IsNil)
+		rdr.Header.ExpandLocalAlleleLines()
+		v := rdr.Read()
+		c.Assert(v.ExpandLocalAlleles(), IsNil)
+		c.Assert(v.Samples[1].GT, DeepEquals, []int{1, 2})
+		c.Assert(v.Samples[1].Phased, Equals, true)
+
+		var buf bytes.Buffer
+		wtr, err := NewWriter(&buf, rdr.Header)
+		c.Assert(err, IsNil)
+		wtr.WriteVariant(v)
+		c.Assert(buf.String(), Equals, `##fileformat=VCFv4.4
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths for the ref and alt alleles in the order listed">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled genotype likelihoods rounded to the closest integer">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2	S3
+1	100	.	A	C,G,T	50.0	PASS	.	GT:AD:PL	0/3:10,.,.,8:0,.,.,.,.,.,20,.,.,200	1|2:0,5,6,.:90,30,80,40,0,70,.,.,.,.	0/0:12,.,.,.:0,.,.,.,.,.,.,.,.,.
+`)
+	}
+}
+
+func (s *LocalSuite) TestExpandError(c *C) {
+	// A bad LAA in the second sample leaves every sample unchanged.
+	rdr, err := NewReader(strings.NewReader(strings.Replace(localStr, "1|2:1,2:", "1|2:1,9:", 1)), false)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	before := v.String()
+	c.Assert(v.ExpandLocalAlleles(), NotNil)
+	c.Assert(v.String(), Equals, before)
+	c.Assert(v.Samples[0].Fields["LAD"], Equals, "10,8")
+	_, found := v.Samples[0].Fields["AD"]
+	c.Assert(found, Equals, false)
+}
+
+func (s *LocalSuite) TestCompact(c *C) {
+	rdr, err := NewReader(strings.NewReader(globalStr), true)
+	c.Assert(err, IsNil)
+	rdr.Header.CompactLocalAlleleLines()
+	c.Assert(rdr.Header.FileFormat, Equals, "4.4")
+	v := rdr.Read()
+	c.Assert(v.CompactLocalAlleles(1), IsNil)
+	c.Assert(v.String(), Equals, "1\t100\t.\tA\tC,G,T\t50.0\tPASS\t.\tGT:LAA:LAD:LPL\t0/1:1:10,8:0,20,200\t2/2:2:0,9:90,40,0")
+
+	// Round trip through GetGenotypeField.
+	pl, err := v.GetGenotypeField(v.Samples[1], "PL", -1)
+	c.Assert(err, IsNil)
+	c.Assert(pl, DeepEquals, []int{90, -1, -1, 40, -1, 0, -1, -1, -1, -1})
+
+	rdr, _ = NewReader(strings.NewReader(globalStr), false)
+	v = rdr.Read()
+	c.Assert(v.CompactLocalAlleles(2), IsNil)
+	c.Assert(v.Samples[1].Fields["LAA"], Equals, "2,3")
+	c.Assert(v.Samples[1].Fields["LPL"], Equals, "90,40,0,7,7,7")
+
+	rdr, _ = NewReader(strings.NewReader(strings.Replace(globalStr, "2/2:", "1/2:", 1)), false)
+	v = rdr.Read()
+	before := v.String()
+	c.Assert(v.CompactLocalAlleles(1), ErrorMatches, ".*GT 1/2 has more than 1 ALT alleles")
+	c.Assert(v.String(), Equals, before)
+	_, found := v.Samples[0].Fields["LAA"]
+	c.Assert(found, Equals, false)
+}
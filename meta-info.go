@@ -187,6 +187,37 @@ func (m *MetaLine) String() (string, error) {
 }
 
 
+// Clone returns a copy of the MetaLine that shares no pointers with the
+// original.
+func (m *MetaLine) Clone() *MetaLine {
+	c := *m
+	c.KVs = make(map[string]*KV, len(m.KVs))
+	for k, kv := range m.KVs {
+		nkv := *kv
+		c.KVs[k] = &nkv
+	}
+	c.Order = make([]string, len(m.Order))
+	copy(c.Order, m.Order)
+	return &c
+}
+
+// AddKV appends a key=value pair to the MetaLine and sets the MetaType
+// to Structured. The quote rune should be 0 if the value is not to be
+// quoted. Keys must be unique within a MetaLine so ErrDuplicateKey is
+// returned if the key is already present.
+func (m *MetaLine) AddKV(k string, v string, quote rune) error {
+	if m.KVs == nil {
+		m.KVs = make(map[string]*KV)
+	}
+	if _, found := m.KVs[k]; found {
+		return fmt.Errorf("%w - %s", ErrDuplicateKey, k)
+	}
+	m.KVs[k] = &KV{Key: k, Value: v, Index: len(m.Order), Quote: quote}
+	m.Order = append(m.Order, k)
+	m.MetaType = Structured
+	return nil
+}
+
 // GetValue takes a key and returns the value for that key from the
 // MetaLine's key=value set. Only meaningful for Structured MetaLines and
 // will always return an empty string for Unstructured MetaLines.
@@ -213,7 +244,7 @@ func kvSplitter(s string) (map[string]*KV, []string, error) {
 	state := inKey
 
 	var k, v string
-	var ctr int
+	var ctr, depth int
 	var quote, lastrune rune
 
 	for i, r := range runes {
@@ -232,9 +263,19 @@ func kvSplitter(s string) (map[string]*KV, []string, error) {
 			} else {
 				state = inValue
 				v = v + string(r)
+				if r == '[' {
+					depth++
+				}
 			}
 		case inValue:
-			if r == fieldSeparator {
+			// VCFv4.3 ##META lines have bracketed lists of values
+			// such as Values=[WGS, WES] that can contain commas.
+			if r == '[' {
+				depth++
+			} else if r == ']' && depth > 0 {
+				depth--
+			}
+			if r == fieldSeparator && depth == 0 {
 				//fmt.Printf("> i:%d r:%c k:%s v:%s ctr:%d state:%v\n", i, r, k, v, ctr, state)
 				f := KV{Key: k, Value: v, Index: ctr}
 				//fmt.Printf("field: %v\n", f)
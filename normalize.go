@@ -1,52 +1,193 @@
 package vcfgo
 
-func leftalign(pos int, ref []byte, alt []byte, seq []byte) (int, []byte, []byte, error) {
-	/* actually this isn't necessary
-	if !bytes.HasSuffix(seq, ref) {
-		return 0, ref, alt, errors.New("leftalign: sequence should end with ref")
-	}
-	*/
-	subseq := seq[:len(seq)-len(ref)]
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrRefMismatch is returned by Normalize when REF does not match the
+// reference genome.
+var ErrRefMismatch = errors.New("vcfgo: REF does not match the reference")
+
+// A Genome gives random access to a reference genome. Seq returns the
+// bases of the 0-based, half-open region [start, end) of chrom.
+type Genome interface {
+	Seq(chrom string, start, end int) ([]byte, error)
+}
 
-	quit := false
-	j := len(subseq)
+// normalizeFlank is the number of bases fetched at a time when moving an
+// allele to the left.
+const normalizeFlank = 64
 
-	for j > 0 && !quit {
-		quit = true
-		if ref[len(ref)-1] == alt[len(alt)-1] {
-			ref = ref[:len(ref)-1]
-			alt = alt[:len(alt)-1]
-			quit = false
+// Normalize left-aligns and trims the alleles of v against the reference
+// genome in the manner of vt normalize and bcftools norm. All alleles of
+// a multiallelic record are moved together until they no longer share a
+// last base, then bases shared by the start of every allele are trimmed,
+// keeping at least one. Pos, Reference and Alternate are updated and
+// Normalize reports whether they changed. Records with symbolic, breakend
+// or * alleles, or whose ALT alleles are all the same as REF, are left
+// alone. REF is compared with the genome, ignoring case, and
+// ErrRefMismatch is returned if they differ.
+func (v *Variant) Normalize(g Genome) (bool, error) {
+	for _, a := range v.Alternate {
+		if !IsSequenceAllele(a) {
+			return false, nil
 		}
-		if len(ref) == 0 || len(alt) == 0 {
-			j--
-			// use j + 1 to get a slice.
-			ref = append(subseq[j:j+1], ref...)
-			alt = append(subseq[j:j+1], alt...)
-			quit = false
+	}
+	start := int(v.Pos) - 1
+	seq, err := g.Seq(v.Chromosome, start, start+len(v.Reference))
+	if err != nil {
+		return false, err
+	}
+	if !strings.EqualFold(string(seq), v.Reference) {
+		return false, fmt.Errorf("%w - %s:%d REF is %s but the reference has %s", ErrRefMismatch, v.Chromosome, v.Pos, v.Reference, seq)
+	}
 
+	alleles := make([][]byte, 1+len(v.Alternate))
+	alleles[0] = bytes.ToUpper([]byte(v.Reference))
+	for i, a := range v.Alternate {
+		alleles[i+1] = bytes.ToUpper([]byte(a))
+	}
+	same := true
+	for _, a := range alleles[1:] {
+		same = same && bytes.Equal(a, alleles[0])
+	}
+	if same {
+		return false, nil
+	}
+	var flank []byte // the bases before start
+	for {
+		last := alleles[0][len(alleles[0])-1]
+		short := false
+		for _, a := range alleles {
+			if a[len(a)-1] != last {
+				short = true
+				break
+			}
+		}
+		if short {
+			break
+		}
+		// Before removing the last base, add a base on the left if any
+		// allele would become empty.
+		for _, a := range alleles {
+			short = short || len(a) == 1
+		}
+		if short {
+			if start == 0 {
+				break
+			}
+			if len(flank) == 0 {
+				from := start - normalizeFlank
+				if from < 0 {
+					from = 0
+				}
+				if flank, err = g.Seq(v.Chromosome, from, start); err != nil {
+					return false, err
+				}
+				flank = bytes.ToUpper(flank)
+			}
+			b := flank[len(flank)-1]
+			flank = flank[:len(flank)-1]
+			start--
+			for i, a := range alleles {
+				alleles[i] = append([]byte{b}, a...)
+			}
+		}
+		for i, a := range alleles {
+			alleles[i] = a[:len(a)-1]
+		}
+	}
+	for {
+		first := alleles[0][0]
+		for _, a := range alleles {
+			if len(a) < 2 || a[0] != first {
+				first = 0
+				break
+			}
+		}
+		if first == 0 {
+			break
 		}
+		for i, a := range alleles {
+			alleles[i] = a[1:]
+		}
+		start++
+	}
 
+	changed := uint64(start+1) != v.Pos || string(alleles[0]) != v.Reference
+	for i, a := range v.Alternate {
+		changed = changed || string(alleles[i+1]) != a
 	}
-	return pos - (len(subseq) - j), ref, alt, nil
+	if !changed {
+		return false, nil
+	}
+	v.Pos = uint64(start + 1)
+	v.Reference = string(alleles[0])
+	for i := range v.Alternate {
+		v.Alternate[i] = string(alleles[i+1])
+	}
+	return true, nil
 }
 
-func lefttrim(pos int, ref []byte, alt []byte) (int, []byte, []byte, error) {
-	if 1 == len(ref) && len(alt) == 1 {
-		return pos, ref, alt, nil
-	}
+// Normalizer reads Variants from a Reader and normalizes them. As
+// normalization can move a Variant to the left of those before it, the
+// Variants are held until those read are more than Window bases further
+// on, and are returned sorted by position. Variants that move further
+// than Window may be out of order.
+type Normalizer struct {
+	Window uint64
 
-	n := 0
-	minLen := len(ref)
-	if len(alt) < minLen {
-		minLen = len(alt)
-	}
+	rdr    *Reader
+	genome Genome
+	buf    []*Variant
+	chrom  string // of the last Variant read
+	pos    uint64 // of the last Variant read, before normalization
+	eof    bool
+	verr   *VCFError
+}
+
+// NewNormalizer returns a Normalizer with a Window of window bases.
+func NewNormalizer(rdr *Reader, g Genome, window uint64) *Normalizer {
+	return &Normalizer{Window: window, rdr: rdr, genome: g, verr: NewVCFError()}
+}
 
-	for n+1 < minLen && alt[n] == ref[n] {
-		n++
+// Read returns the next normalized Variant or nil at the end of the
+// input. A Variant that cannot be normalized is returned unchanged and
+// the error is added to those returned by Error.
+func (n *Normalizer) Read() *Variant {
+	for !n.eof && (len(n.buf) == 0 || (n.buf[0].Chromosome == n.chrom && n.buf[0].Pos+n.Window >= n.pos)) {
+		v := n.rdr.Read()
+		if v == nil {
+			n.eof = true
+			break
+		}
+		n.chrom, n.pos = v.Chromosome, v.Pos
+		_, err := v.Normalize(n.genome)
+		n.verr.Add(err, v.LineNumber)
+		i := sort.Search(len(n.buf), func(i int) bool {
+			return n.buf[i].Chromosome == v.Chromosome && n.buf[i].Pos > v.Pos
+		})
+		n.buf = append(n.buf, nil)
+		copy(n.buf[i+1:], n.buf[i:])
+		n.buf[i] = v
 	}
+	if len(n.buf) == 0 {
+		return nil
+	}
+	v := n.buf[0]
+	n.buf = n.buf[1:]
+	return v
+}
 
-	alt, ref = alt[n:], ref[n:]
-	pos += n
-	return pos, ref, alt, nil
+// Error returns the normalization errors. Errors reading the input are
+// returned by the Reader.
+func (n *Normalizer) Error() error {
+	if n.verr.IsEmpty() {
+		return nil
+	}
+	return n.verr
 }
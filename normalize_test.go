@@ -1,77 +1,100 @@
 package vcfgo
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
-var leftaligntests = []struct {
-	pos int
-	ref []byte
-	alt []byte
-	seq []byte
+type mapGenome map[string]string
 
-	outPos int
-	outRef string
-	outAlt string
-}{
-	{123, []byte{'C', 'A', 'C'}, []byte{'C'}, []byte("GGGCACACAC"), 118, "GCA", "G"},
-	{123, []byte{'C', 'A', 'C'}, []byte{'C'}, []byte("CACACAC"), 119, "CAC", "C"},
-	{123, []byte{'C', 'C', 'A'}, []byte{'C', 'A', 'A'}, []byte("ACCCCCCA"), 123, "CC", "CA"},
-	{123, []byte{'C'}, []byte{'A'}, []byte("ACCCCCC"), 123, "C", "A"},
+func (g mapGenome) Seq(chrom string, start, end int) ([]byte, error) {
+	s, ok := g[chrom]
+	if !ok {
+		return nil, fmt.Errorf("%w - %s", ErrContigNotFound, chrom)
+	}
+	if start < 0 || end > len(s) || start > end {
+		return nil, fmt.Errorf("%w - %s:%d-%d", ErrOutOfBounds, chrom, start, end)
+	}
+	return []byte(s[start:end]), nil
 }
 
-var lefttrimtests = []struct {
-	pos int
-	ref []byte
-	alt []byte
+var normGenome = mapGenome{"chr1": "TTTGCACACACTTT", "chr2": "ACGTACGT",
+	"chr3": "GGGCACACAC", "chr4": "CACACAC", "chr5": "ACCCCCCA", "chr6": "ACCCT"}
 
-	outPos int
-	outRef string
-	outAlt string
+var normalizetests = []struct {
+	chrom string
+	pos   uint64
+	ref   string
+	alt   []string
+
+	outPos  uint64
+	outRef  string
+	outAlt  []string
+	changed bool
 }{
-	{123, []byte{'C', 'C'}, []byte{'C', 'A'}, 124, "C", "A"},
-	{123, []byte{'C', 'C'}, []byte{'C', 'C', 'T'}, 124, "C", "CT"},
-	{123, []byte{'C', 'C', 'C'}, []byte{'C', 'C', 'C', 'T'}, 125, "C", "CT"},
-	{123, []byte{'C'}, []byte{'T'}, 123, "C", "T"},
+	{"chr1", 9, "CAC", []string{"C"}, 4, "GCA", []string{"G"}, true},
+	{"chr1", 9, "CAC", []string{"C", "CACAC"}, 4, "GCA", []string{"G", "GCACA"}, true},
+	{"chr1", 4, "GCAC", []string{"GCTC"}, 6, "A", []string{"T"}, true},
+	{"chr1", 4, "gcac", []string{"GCTC", "GGAC"}, 5, "CA", []string{"CT", "GA"}, true},
+	{"chr1", 5, "C", []string{"G"}, 5, "C", []string{"G"}, false},
+	{"chr1", 1, "TT", []string{"T"}, 1, "TT", []string{"T"}, false},
+	{"chr1", 9, "CAC", []string{"<DEL>"}, 9, "CAC", []string{"<DEL>"}, false},
+	{"chr1", 5, "C", []string{"C"}, 5, "C", []string{"C"}, false},
+	{"chr1", 9, "CAC", []string{"cac", "CAC"}, 9, "CAC", []string{"cac", "CAC"}, false},
+	// The cases of the old leftalign and lefttrim tests.
+	{"chr3", 8, "CAC", []string{"C"}, 3, "GCA", []string{"G"}, true},
+	{"chr4", 5, "CAC", []string{"C"}, 1, "CAC", []string{"C"}, true},
+	{"chr5", 6, "CCA", []string{"CAA"}, 7, "C", []string{"A"}, true},
+	{"chr5", 7, "C", []string{"A"}, 7, "C", []string{"A"}, false},
+	{"chr6", 2, "CC", []string{"CA"}, 3, "C", []string{"A"}, true},
+	{"chr6", 2, "CC", []string{"CCT"}, 3, "C", []string{"CT"}, true},
+	{"chr6", 2, "CCC", []string{"CCCT"}, 4, "C", []string{"CT"}, true},
+	{"chr6", 2, "C", []string{"T"}, 2, "C", []string{"T"}, false},
 }
 
-func TestLeftAlign(t *testing.T) {
-
-	for _, v := range leftaligntests {
-		p, r, a, err := leftalign(v.pos, v.ref, v.alt, v.seq)
-
-		if p != v.outPos {
-			t.Errorf("position should be %d instead of %d\n", v.outPos, p)
-		}
-		if string(r) != v.outRef {
-			t.Errorf("ref should be %s instead of %s\n", v.outRef, string(r))
-		}
-		if string(a) != v.outAlt {
-			t.Errorf("alt should be '%s' instead of %s", v.outAlt, a)
-		}
+func TestNormalize(t *testing.T) {
+	for _, n := range normalizetests {
+		v := &Variant{Chromosome: n.chrom, Pos: n.pos, Reference: n.ref, Alternate: append([]string{}, n.alt...)}
+		changed, err := v.Normalize(normGenome)
 		if err != nil {
 			t.Error(err)
 		}
+		if changed != n.changed || v.Pos != n.outPos || v.Reference != n.outRef || strings.Join(v.Alternate, ",") != strings.Join(n.outAlt, ",") {
+			t.Errorf("%s:%d %s %v should be %d %s %v (changed %v) not %d %s %v (changed %v)", n.chrom, n.pos, n.ref, n.alt,
+				n.outPos, n.outRef, n.outAlt, n.changed, v.Pos, v.Reference, v.Alternate, changed)
+		}
 	}
-}
-
-func TestLeftTrim(t *testing.T) {
 
-	for _, v := range lefttrimtests {
-		p, r, a, err := lefttrim(v.pos, v.ref, v.alt)
-
-		if p != v.outPos {
-			t.Errorf("position should be %d instead of %d\n", v.outPos, p)
-		}
-		if string(r) != v.outRef {
-			t.Errorf("ref should be %s instead of %s\n", v.outRef, string(r))
-		}
-		if string(a) != v.outAlt {
-			t.Errorf("alt should be '%s' instead of %s", v.outAlt, a)
-		}
-		if err != nil {
-			t.Error(err)
-		}
+	v := &Variant{Chromosome: "chr1", Pos: 4, Reference: "A", Alternate: []string{"T"}}
+	if _, err := v.Normalize(normGenome); !errors.Is(err, ErrRefMismatch) {
+		t.Errorf("expected ErrRefMismatch not %v", err)
+	}
+}
 
+func TestNormalizer(t *testing.T) {
+	vcf := "##fileformat=VCFv4.2\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n" +
+		"chr1\t5\ta\tC\tG\t.\tPASS\t.\n" +
+		"chr1\t9\tb\tCAC\tC\t.\tPASS\t.\n" +
+		"chr1\t12\tc\tT\tTTT\t.\tPASS\t.\n" +
+		"chr1\t13\td\tT\tA\t.\tPASS\t.\n" +
+		"chr2\t2\te\tC\tA\t.\tPASS\t.\n" +
+		"chr2\t3\tf\tT\tC\t.\tPASS\t.\n"
+	rdr, err := NewReader(strings.NewReader(vcf), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNormalizer(rdr, normGenome, 10)
+	var got []string
+	for v := n.Read(); v != nil; v = n.Read() {
+		got = append(got, fmt.Sprintf("%s:%d:%s", v.Chromosome, v.Pos, v.Id()))
+	}
+	exp := "chr1:4:b chr1:5:a chr1:11:c chr1:13:d chr2:2:e chr2:3:f"
+	if strings.Join(got, " ") != exp {
+		t.Errorf("expected %s not %s", exp, strings.Join(got, " "))
+	}
+	if err := n.Error(); err == nil || !strings.Contains(err.Error(), "chr2:3 REF is T but the reference has G") {
+		t.Errorf("expected a REF mismatch not %v", err)
 	}
 }
@@ -0,0 +1,206 @@
+package vcfgo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The Number of an INFO or FORMAT field says how many values the field
+// holds. As well as an integer, it can be one of the special values
+// below. VCFv4.4 added P and the local-allele Numbers LA, LR and LG
+// which are counted against the local alleles listed in FORMAT/LAA
+// rather than against ALT.
+const (
+	NumberAltAlleles      = `A`  // one value per ALT allele
+	NumberAlleles         = `R`  // one value per allele including REF
+	NumberGenotypes       = `G`  // one value per possible genotype
+	NumberUnknown         = `.`  // unknown, unbounded or varying
+	NumberPloidy          = `P`  // one value per allele in GT (VCFv4.4)
+	NumberLocalAltAlleles = `LA` // one value per local ALT allele (VCFv4.4)
+	NumberLocalAlleles    = `LR` // one value per local allele including REF (VCFv4.4)
+	NumberLocalGenotypes  = `LG` // one value per local genotype (VCFv4.4)
+)
+
+// validNumber reports whether number is an integer or one of the
+// special Number values.
+func validNumber(number string) bool {
+	switch number {
+	case NumberAltAlleles, NumberAlleles, NumberGenotypes, NumberUnknown,
+		NumberPloidy, NumberLocalAltAlleles, NumberLocalAlleles, NumberLocalGenotypes:
+		return true
+	}
+	n, err := strconv.Atoi(number)
+	return err == nil && n >= 0
+}
+
+// numberSince returns the VCF version that introduced a Number value.
+func numberSince(number string) string {
+	switch number {
+	case NumberAltAlleles, NumberGenotypes:
+		return `4.1`
+	case NumberAlleles:
+		return `4.2`
+	case NumberPloidy, NumberLocalAltAlleles, NumberLocalAlleles, NumberLocalGenotypes:
+		return `4.4`
+	}
+	return `4.0`
+}
+
+// genotypeCount returns the number of unordered genotypes for a given
+// number of alleles and ploidy, i.e. the number of values expected for
+// a Number=G field. For diploids this is n(n+1)/2.
+func genotypeCount(nAlleles int, ploidy int) int {
+	// (nAlleles + ploidy - 1) choose ploidy
+	count := 1
+	for i := 1; i <= ploidy; i++ {
+		count = count * (nAlleles + i - 1) / i
+	}
+	return count
+}
+
+// genotypeIndex returns the position of a genotype in the VCF ordering
+// of Number=G values. The alleles do not need to be sorted and must not
+// be missing. For diploids the index of j/k (j <= k) is k(k+1)/2 + j.
+func genotypeIndex(alleles []int) int {
+	a := append([]int{}, alleles...)
+	sort.Ints(a)
+	index := 0
+	for m := 1; m <= len(a); m++ {
+		index += binomial(a[m-1]+m-1, m)
+	}
+	return index
+}
+
+// genotypeOrder returns every genotype, as sorted allele indices, for a
+// number of alleles and ploidy in the VCF ordering of Number=G values.
+func genotypeOrder(nAlleles int, ploidy int) [][]int {
+	order := make([][]int, genotypeCount(nAlleles, ploidy))
+	var walk func(prefix []int, min int)
+	walk = func(prefix []int, min int) {
+		if len(prefix) == ploidy {
+			g := append([]int{}, prefix...)
+			order[genotypeIndex(g)] = g
+			return
+		}
+		for a := min; a < nAlleles; a++ {
+			walk(append(prefix, a), a)
+		}
+	}
+	walk(make([]int, 0, ploidy), 0)
+	return order
+}
+
+func binomial(n, k int) int {
+	if k < 0 || k > n {
+		return 0
+	}
+	r := 1
+	for i := 1; i <= k; i++ {
+		r = r * (n - k + i) / i
+	}
+	return r
+}
+
+// numberCount returns the number of values expected for a Number given
+// the number of ALT alleles, the ploidy and the number of local ALT
+// alleles. It returns false if the Number does not imply a fixed count.
+func numberCount(number string, nAlts int, ploidy int, nLocalAlts int) (int, bool) {
+	if ploidy == 0 {
+		ploidy = 2
+	}
+	switch number {
+	case NumberAltAlleles:
+		return nAlts, true
+	case NumberAlleles:
+		return nAlts + 1, true
+	case NumberGenotypes:
+		return genotypeCount(nAlts+1, ploidy), true
+	case NumberPloidy:
+		return ploidy, true
+	case NumberLocalAltAlleles:
+		return nLocalAlts, true
+	case NumberLocalAlleles:
+		return nLocalAlts + 1, true
+	case NumberLocalGenotypes:
+		return genotypeCount(nLocalAlts+1, ploidy), true
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseFieldLines populates Infos, SampleFormats and Filters from the
+// INFO, FORMAT and FILTER lines in Lines. Lines with an invalid Number
+// are still added but are reported.
+func (h *Header) parseFieldLines() []error {
+	var errs []error
+	for _, m := range h.Lines {
+		if m.MetaType != Structured {
+			continue
+		}
+		s, err := m.String()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch m.LineKey {
+		case `INFO`:
+			i, err := parseHeaderInfo(s)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !validNumber(i.Number) {
+				errs = append(errs, fmt.Errorf("INFO/%s has invalid Number=%s", i.Id, i.Number))
+			}
+			h.Infos[i.Id] = i
+		case `FORMAT`:
+			f, err := parseHeaderFormat(s)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !validNumber(f.Number) {
+				errs = append(errs, fmt.Errorf("FORMAT/%s has invalid Number=%s", f.Id, f.Number))
+			}
+			h.SampleFormats[f.Id] = f
+		case `FILTER`:
+			h.Filters[m.GetValue(`ID`)] = m.GetValue(`Description`)
+		}
+	}
+	return errs
+}
+
+// versionErrors reports INFO and FORMAT lines whose Number was not
+// available in the version of the Header.
+func (h *Header) versionErrors() []error {
+	version := versionIndex(h.FileFormat)
+	if version == -1 {
+		return nil
+	}
+	var errs []error
+	for _, m := range h.Lines {
+		if m.MetaType != Structured || (m.LineKey != `INFO` && m.LineKey != `FORMAT`) {
+			continue
+		}
+		number := m.GetValue(`Number`)
+		if since := numberSince(number); versionIndex(since) > version {
+			errs = append(errs, fmt.Errorf("%s/%s has Number=%s which needs VCFv%s but header is VCFv%s",
+				m.LineKey, m.GetValue(`ID`), number, since, h.FileFormat))
+		}
+	}
+	return errs
+}
+
+// countValues returns the number of comma-separated values in a field
+// value or 0 if the value is missing.
+func countValues(value string) int {
+	if value == `` || value == `.` {
+		return 0
+	}
+	return strings.Count(value, `,`) + 1
+}
@@ -0,0 +1,240 @@
+package vcfgo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// From VCFv4.3, characters with a special meaning in INFO and FORMAT
+// values must be percent-encoded using capitalised hex (section 1.2 of
+// the spec). The characters are:
+//
+//  : %3A   ; %3B   = %3D   % %25   , %2C   CR %0D   LF %0A   TAB %09
+//
+// ':' only has a special meaning in FORMAT (sample) values so it does
+// not need to be encoded in INFO values.
+
+// Characters that must be percent-encoded in INFO and FORMAT values.
+const (
+	infoSpecialChars   = ";=%,\r\n\t"
+	formatSpecialChars = ":;=%,\r\n\t"
+)
+
+const upperHex = "0123456789ABCDEF"
+
+// PercentEncode returns s with every character in special replaced by
+// its percent-encoding.
+func PercentEncode(s string, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if strings.IndexByte(special, c) != -1 {
+			b.WriteByte('%')
+			b.WriteByte(upperHex[c>>4])
+			b.WriteByte(upperHex[c&15])
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// PercentDecode returns s with any percent-encoded characters decoded.
+// A % that is not followed by two hex digits is an error.
+func PercentDecode(s string) (string, error) {
+	d, _, err := percentDecodeOnly(s, ``)
+	return d, err
+}
+
+// percentDecodeOnly decodes the percent-encodings in s. If keep is not
+// empty, any encoded character in keep is left encoded and kept is true.
+func percentDecodeOnly(s string, keep string) (d string, kept bool, err error) {
+	if strings.IndexByte(s, '%') == -1 {
+		return s, false, nil
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return s, false, fmt.Errorf("bad percent-encoding in %s", s)
+		}
+		hi, ok1 := unhex(s[i+1])
+		lo, ok2 := unhex(s[i+2])
+		if !ok1 || !ok2 {
+			return s, false, fmt.Errorf("bad percent-encoding in %s", s)
+		}
+		c := hi<<4 | lo
+		if keep != `` && strings.IndexByte(keep, c) != -1 {
+			b.WriteString(s[i : i+3])
+			kept = true
+		} else {
+			b.WriteByte(c)
+		}
+		i += 2
+	}
+	return b.String(), kept, nil
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// Percent-encoding is gated on Header.FileFormat - it only applies to
+// VCFv4.3 and later. Values are always held in InfoByte in their encoded
+// (on disk) form: InfoByte.Set encodes the values it is given and, if
+// decoding is turned on with Header.SetPercentDecoding, InfoByte.Get
+// decodes the String and Character values it returns. With decoding
+// turned on, parsed sample values are also held decoded and are encoded
+// again when the Variant is written. Lazily parsed samples are never
+// decoded.
+
+// SetPercentDecoding turns decoding of percent-encoded INFO and FORMAT
+// values on or off. It has no effect on files older than VCFv4.3.
+func (h *Header) SetPercentDecoding(on bool) {
+	h.percentDecode = on
+}
+
+// percentEncoded reports whether the values of records with this Header
+// are percent-encoded, i.e. whether the Header is VCFv4.3 or later.
+func (h *Header) percentEncoded() bool {
+	return h != nil && versionIndex(h.FileFormat) >= v43
+}
+
+// decoding reports whether values should be decoded on reading.
+func (h *Header) decoding() bool {
+	return h.percentEncoded() && h.percentDecode
+}
+
+// isTextField reports whether a FORMAT field can hold percent-encoded
+// text. GT and numeric fields cannot.
+func (h *Header) isTextField(id string) bool {
+	if id == `GT` {
+		return false
+	}
+	if f, found := h.SampleFormats[id]; found {
+		switch f.Type {
+		case `Integer`, `Float`, `Flag`:
+			return false
+		}
+	}
+	return true
+}
+
+// decodeSample decodes the text values of a parsed sample.
+func (h *Header) decodeSample(format []string, g *SampleGenotype) error {
+	for _, f := range format {
+		val, found := g.Fields[f]
+		if !found || !h.isTextField(f) {
+			continue
+		}
+		d, err := PercentDecode(val)
+		if err != nil {
+			return fmt.Errorf("FORMAT/%s: %v", f, err)
+		}
+		g.Fields[f] = d
+	}
+	return nil
+}
+
+// encodedSampleString returns the string representation of a sample
+// whose text values are held decoded.
+func (h *Header) encodedSampleString(format []string, g *SampleGenotype) string {
+	if len(format) == 0 {
+		return "."
+	}
+	s := make([]string, len(format))
+	for i, f := range format {
+		s[i] = g.Fields[f]
+		if h.isTextField(f) {
+			s[i] = PercentEncode(s[i], formatSpecialChars)
+		}
+	}
+	return strings.Join(s, ":")
+}
+
+// encodeInfoValue returns the string form of an INFO value for a header
+// that uses percent-encoding. The elements of a list are encoded
+// separately, including any commas they contain, but a single string is
+// assumed to be already joined with commas so commas are not encoded.
+func encodeInfoValue(key string, value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return PercentEncode(v, strings.Replace(infoSpecialChars, `,`, ``, 1))
+	case []string:
+		out := make([]string, len(v))
+		for i, s := range v {
+			out[i] = PercentEncode(s, infoSpecialChars)
+		}
+		return strings.Join(out, `,`)
+	case []interface{}:
+		out := make([]string, len(v))
+		for i, e := range v {
+			if s, ok := e.(string); ok {
+				out[i] = PercentEncode(s, infoSpecialChars)
+			} else {
+				out[i] = ItoS(key, e)
+			}
+		}
+		return strings.Join(out, `,`)
+	}
+	return ItoS(key, value)
+}
+
+// Characters that cannot appear in an INFO value before VCFv4.3.
+const unencodableInfoChars = ";=\r\n\t"
+
+// fileFormat returns the FileFormat of a possibly nil Header.
+func (h *Header) fileFormat() string {
+	if h == nil {
+		return ``
+	}
+	return h.FileFormat
+}
+
+// decodeInfoValue decodes the string values returned by InfoByte.get.
+func decodeInfoValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return PercentDecode(v)
+	case []string:
+		out := make([]string, len(v))
+		for i, s := range v {
+			d, err := PercentDecode(s)
+			if err != nil {
+				return v, err
+			}
+			out[i] = d
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = e
+			if s, ok := e.(string); ok {
+				d, err := PercentDecode(s)
+				if err != nil {
+					return v, err
+				}
+				out[i] = d
+			}
+		}
+		return out, nil
+	}
+	return value, nil
+}
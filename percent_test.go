@@ -0,0 +1,69 @@
+package vcfgo
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var percentStr = `##fileformat=VCFv4.3
+##INFO=<ID=NOTE,Number=1,Type=String,Description="Free text">
+##INFO=<ID=TAGS,Number=.,Type=String,Description="Tags">
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Depth">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=FT,Number=1,Type=String,Description="Sample filter">
+##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Depth">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1
+1	100	.	A	C	50	PASS	NOTE=a%3Bb%3Dc;TAGS=x%2Cy,z;DP=10	GT:FT:DP	0/1:q%3A10:7
+`
+
+type PercentSuite struct{}
+
+var _ = Suite(&PercentSuite{})
+
+func (s *PercentSuite) TestGet(c *C) {
+	rdr, err := NewReader(strings.NewReader(percentStr), false)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+
+	// Without decoding, values are returned as they are in the file.
+	note, err := v.Info().Get("NOTE")
+	c.Assert(err, IsNil)
+	c.Assert(note, Equals, "a%3Bb%3Dc")
+	c.Assert(v.Samples[0].Fields["FT"], Equals, "q%3A10")
+
+	rdr, err = NewReader(strings.NewReader(percentStr), false)
+	c.Assert(err, IsNil)
+	rdr.Header.SetPercentDecoding(true)
+	v = rdr.Read()
+	note, err = v.Info().Get("NOTE")
+	c.Assert(err, IsNil)
+	c.Assert(note, Equals, "a;b=c")
+	tags, err := v.Info().Get("TAGS")
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []string{"x,y", "z"})
+	dp, err := v.Info().Get("DP")
+	c.Assert(err, IsNil)
+	c.Assert(dp, Equals, 10)
+	c.Assert(v.Samples[0].Fields["FT"], Equals, "q:10")
+
+	// Decoded sample values are encoded again when written.
+	c.Assert(v.String(), Equals, "1\t100\t.\tA\tC\t50.0\tPASS\tNOTE=a%3Bb%3Dc;TAGS=x%2Cy,z;DP=10\tGT:FT:DP\t0/1:q%3A10:7")
+}
+
+func (s *PercentSuite) TestSet(c *C) {
+	rdr, err := NewReader(strings.NewReader(percentStr), false)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	c.Assert(v.Info().Set("NOTE", "50% of 1;2"), IsNil)
+	c.Assert(v.Info().Set("TAGS", []string{"a,b", "c=d"}), IsNil)
+	c.Assert(v.Info().Set("NEW", "p,q"), IsNil)
+	c.Assert(v.Info().String(), Equals, "NOTE=50%25 of 1%3B2;TAGS=a%2Cb,c%3Dd;DP=10;NEW=p,q")
+
+	// Before VCFv4.3 special characters cannot be written.
+	rdr.Header.FileFormat = "4.2"
+	c.Assert(v.Info().Set("NOTE", "a;b"), ErrorMatches, `Info Error: NOTE value "a;b" cannot be represented in VCFv4.2`)
+	c.Assert(v.Info().Set("NOTE", "50%"), IsNil)
+	note, _ := v.Info().Get("NOTE")
+	c.Assert(note, Equals, "50%")
+}
@@ -0,0 +1,526 @@
+package vcfgo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrQueryFormat is returned by NewQuery for a template it cannot parse.
+var ErrQueryFormat = errors.New("vcfgo: bad query format")
+
+// A Query turns Variants into lines of delimited text using a template
+// in the style of bcftools query, for example:
+//
+//  %CHROM\t%POS\t%REF\t%ALT\t%INFO/DP[\t%SAMPLE=%GT]\n
+//
+// Text is copied to the output and \t, \n and \\ in the template are
+// replaced by tab, newline and backslash. Fields start with % and are:
+//
+//  CHROM POS POS0 END ID REF ALT FIRST_ALT QUAL FILTER INFO
+//  INFO/TAG    an INFO field; TAG alone is the same outside a sample loop
+//  FMT/TAG     a FORMAT field; TAG alone is the same inside a sample loop
+//  SAMPLE      the sample name
+//  TGT         the genotype with alleles as bases, e.g. A/C
+//
+// Text in square brackets is repeated for each sample. A field can be
+// followed by {N} to print the N'th (0-based) of its values. INFO and
+// FORMAT fields are typed by the Header using InfoByte.Get and
+// Variant.GetGenotypeField: a Flag is 1 or 0 and missing values, missing
+// fields and out of range indices are printed as Missing.
+//
+// If PerAlt is set, a line is printed for each ALT allele. %ALT is the
+// ALT allele of the line, Number=A fields are the value for that allele
+// and Number=R fields are the values for REF and that allele.
+type Query struct {
+	// Missing is printed for missing values. NewQuery sets it to ".".
+	Missing string
+	// PerAlt prints a line for each ALT allele.
+	PerAlt bool
+	// CSV quotes values that contain a comma, a double quote or a
+	// newline.
+	CSV bool
+
+	header *Header
+	terms  []*queryTerm
+	loop   bool
+}
+
+// queryTerm is a piece of text or a field of a Query template.
+type queryTerm struct {
+	text   string
+	field  string // upper case name of a fixed field or INFO or FMT
+	tag    string // the tag of an INFO or FMT field
+	index  int    // {N} or -1
+	sample bool   // inside the sample loop
+}
+
+// Fields that can be used anywhere and those that need a sample.
+var queryFields = map[string]bool{`CHROM`: true, `POS`: true, `POS0`: true, `END`: true,
+	`ID`: true, `REF`: true, `ALT`: true, `FIRST_ALT`: true, `QUAL`: true, `FILTER`: true}
+var querySampleFields = map[string]bool{`SAMPLE`: true, `TGT`: true}
+
+// NewQuery parses a template and checks its INFO and FORMAT fields
+// against the Header.
+func NewQuery(format string, h *Header) (*Query, error) {
+	q := &Query{Missing: `.`, header: h}
+	var text strings.Builder
+	inLoop := false
+	flush := func() {
+		if text.Len() > 0 {
+			q.terms = append(q.terms, &queryTerm{text: text.String(), index: -1, sample: inLoop})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case c == '\\' && i+1 < len(format):
+			i++
+			switch format[i] {
+			case 't':
+				text.WriteByte('\t')
+			case 'n':
+				text.WriteByte('\n')
+			default:
+				text.WriteByte(format[i])
+			}
+		case c == '[':
+			if inLoop {
+				return nil, fmt.Errorf("%w - nested [ at %d", ErrQueryFormat, i)
+			}
+			flush()
+			inLoop, q.loop = true, true
+		case c == ']':
+			if !inLoop {
+				return nil, fmt.Errorf("%w - unmatched ] at %d", ErrQueryFormat, i)
+			}
+			flush()
+			inLoop = false
+		case c == '%':
+			flush()
+			end := i + 1
+			for end < len(format) && (isQueryNameByte(format[end])) {
+				end++
+			}
+			if end == i+1 {
+				return nil, fmt.Errorf("%w - %% without a field at %d", ErrQueryFormat, i)
+			}
+			t, err := q.newTerm(format[i+1:end], inLoop)
+			if err != nil {
+				return nil, err
+			}
+			if end < len(format) && format[end] == '{' {
+				close := strings.IndexByte(format[end:], '}')
+				if close == -1 {
+					return nil, fmt.Errorf("%w - unmatched { at %d", ErrQueryFormat, end)
+				}
+				n, err := strconv.Atoi(format[end+1 : end+close])
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("%w - bad index %s", ErrQueryFormat, format[end:end+close+1])
+				}
+				t.index = n
+				end += close + 1
+			}
+			q.terms = append(q.terms, t)
+			i = end - 1
+		default:
+			text.WriteByte(c)
+		}
+	}
+	if inLoop {
+		return nil, fmt.Errorf("%w - unmatched [", ErrQueryFormat)
+	}
+	flush()
+	return q, nil
+}
+
+func isQueryNameByte(c byte) bool {
+	return c == '_' || c == '/' || c == '.' || (c >= '0' && c <= '9') ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// newTerm returns the term for a field name, checking it against the
+// Header.
+func (q *Query) newTerm(name string, sample bool) (*queryTerm, error) {
+	t := &queryTerm{index: -1, sample: sample}
+	upper := strings.ToUpper(name)
+	switch {
+	case queryFields[upper]:
+		t.field = upper
+		return t, nil
+	case name == `INFO`:
+		t.field = `INFO`
+		return t, nil
+	case querySampleFields[upper]:
+		if !sample {
+			return nil, fmt.Errorf("%w - %%%s outside [ ]", ErrQueryFormat, name)
+		}
+		t.field = upper
+		return t, nil
+	case strings.HasPrefix(name, `INFO/`):
+		t.field, t.tag = `INFO`, name[5:]
+	case strings.HasPrefix(name, `FMT/`), strings.HasPrefix(name, `FORMAT/`):
+		t.field, t.tag = `FMT`, name[strings.IndexByte(name, '/')+1:]
+	case sample:
+		t.field, t.tag = `FMT`, name
+	default:
+		t.field, t.tag = `INFO`, name
+	}
+	if t.field == `INFO` {
+		if _, found := q.header.Infos[t.tag]; !found {
+			return nil, fmt.Errorf("%w - INFO/%s is not in the header", ErrQueryFormat, t.tag)
+		}
+		return t, nil
+	}
+	if !sample {
+		return nil, fmt.Errorf("%w - FORMAT/%s outside [ ]", ErrQueryFormat, t.tag)
+	}
+	if _, found := q.header.SampleFormats[t.tag]; !found && t.tag != `GT` {
+		return nil, fmt.Errorf("%w - FORMAT/%s is not in the header", ErrQueryFormat, t.tag)
+	}
+	return t, nil
+}
+
+// name returns the column name of a term for the header row.
+func (t *queryTerm) name() string {
+	n := t.field
+	if t.tag != `` {
+		n = t.field + `/` + t.tag
+		if t.field == `FMT` {
+			n = t.tag
+		}
+	}
+	if t.index >= 0 {
+		n += fmt.Sprintf("{%d}", t.index)
+	}
+	return n
+}
+
+// HeaderRow returns a header row for the template with the field names
+// in place of their values. Fields in the sample loop are prefixed with
+// the sample name, e.g. S1:GT.
+func (q *Query) HeaderRow() string {
+	var b strings.Builder
+	b.WriteByte('#')
+	for i := 0; i < len(q.terms); {
+		if !q.terms[i].sample {
+			q.writeName(&b, q.terms[i], ``)
+			i++
+			continue
+		}
+		j := i
+		for j < len(q.terms) && q.terms[j].sample {
+			j++
+		}
+		for _, s := range q.header.SampleNames {
+			for _, t := range q.terms[i:j] {
+				q.writeName(&b, t, s+`:`)
+			}
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func (q *Query) writeName(b *strings.Builder, t *queryTerm, prefix string) {
+	if t.field == `` {
+		b.WriteString(t.text)
+	} else {
+		b.WriteString(q.quote(prefix + t.name()))
+	}
+}
+
+// Format returns the text for a Variant. With PerAlt, this is the text
+// for each ALT allele one after the other. Samples are parsed if the
+// template has a sample loop.
+func (q *Query) Format(v *Variant) (string, error) {
+	if q.loop {
+		// As with Reader, samples that do not parse cleanly still have
+		// their Fields so the error is not fatal.
+		v.Header.ParseSamples(v)
+	}
+	if !q.PerAlt {
+		return q.format(v, -1)
+	}
+	var b strings.Builder
+	for alt := range v.Alternate {
+		s, err := q.format(v, alt)
+		if err != nil {
+			return ``, err
+		}
+		b.WriteString(s)
+	}
+	return b.String(), nil
+}
+
+// Write writes the text for a Variant to w.
+func (q *Query) Write(w io.Writer, v *Variant) error {
+	s, err := q.Format(v)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, s)
+	return err
+}
+
+// format returns the text for a Variant and an ALT index or -1 for all.
+func (q *Query) format(v *Variant, alt int) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(q.terms); {
+		t := q.terms[i]
+		if !t.sample {
+			s, err := q.value(v, t, nil, alt)
+			if err != nil {
+				return ``, err
+			}
+			b.WriteString(s)
+			i++
+			continue
+		}
+		j := i
+		for j < len(q.terms) && q.terms[j].sample {
+			j++
+		}
+		for k, g := range v.Samples {
+			for _, t := range q.terms[i:j] {
+				s, err := q.value(v, t, g, alt)
+				if err != nil {
+					return ``, fmt.Errorf("sample %d: %v", k+1, err)
+				}
+				b.WriteString(s)
+			}
+		}
+		i = j
+	}
+	return b.String(), nil
+}
+
+// value returns the text for a term.
+func (q *Query) value(v *Variant, t *queryTerm, g *SampleGenotype, alt int) (string, error) {
+	var vals []string
+	switch t.field {
+	case ``:
+		return t.text, nil
+	case `CHROM`:
+		vals = []string{v.Chromosome}
+	case `POS`:
+		vals = []string{strconv.FormatUint(v.Pos, 10)}
+	case `POS0`:
+		vals = []string{strconv.FormatUint(v.Pos-1, 10)}
+	case `END`:
+		vals = []string{strconv.FormatUint(uint64(v.End()), 10)}
+	case `ID`:
+		vals = []string{v.Id_}
+	case `REF`:
+		vals = []string{v.Reference}
+	case `ALT`:
+		vals = v.Alternate
+		if alt >= 0 {
+			vals = v.Alternate[alt : alt+1]
+		}
+	case `FIRST_ALT`:
+		vals = []string{q.Missing}
+		if len(v.Alternate) > 0 {
+			vals = v.Alternate[:1]
+		}
+	case `QUAL`:
+		vals = []string{q.Missing}
+		if v.Quality != MISSING_VAL {
+			vals[0] = strconv.FormatFloat(float64(v.Quality), 'g', -1, 32)
+		}
+	case `FILTER`:
+		vals = []string{v.Filter}
+	case `SAMPLE`:
+		vals = []string{q.sampleName(v, g)}
+	case `TGT`:
+		vals = []string{translateGT(g.Fields[`GT`], append([]string{v.Reference}, v.Alternate...))}
+	case `INFO`:
+		if t.tag == `` {
+			vals = []string{v.Info().String()}
+			break
+		}
+		vals = q.infoValues(v, t.tag)
+		vals = subsetAlt(vals, v.Header.infoNumber(t.tag), alt)
+	case `FMT`:
+		vals = q.formatValues(v, g, t.tag)
+		if f, found := v.Header.SampleFormats[t.tag]; found {
+			vals = subsetAlt(vals, f.Number, alt)
+		}
+	}
+	if t.index >= 0 {
+		if t.index >= len(vals) {
+			return q.quote(q.Missing), nil
+		}
+		vals = vals[t.index : t.index+1]
+	}
+	return q.quote(strings.Join(vals, `,`)), nil
+}
+
+// sampleName returns the name of a sample.
+func (q *Query) sampleName(v *Variant, g *SampleGenotype) string {
+	for i, s := range v.Samples {
+		if s == g && i < len(v.Header.SampleNames) {
+			return v.Header.SampleNames[i]
+		}
+	}
+	return q.Missing
+}
+
+// infoNumber returns the Number of an INFO field or "" if it is not in
+// the Header.
+func (h *Header) infoNumber(tag string) string {
+	if i, found := h.Infos[tag]; found {
+		return i.Number
+	}
+	return ``
+}
+
+// infoValues returns the values of an INFO field as text.
+func (q *Query) infoValues(v *Variant, tag string) []string {
+	val, err := v.Info().Get(tag)
+	if b, ok := val.(bool); ok {
+		if b {
+			return []string{`1`}
+		}
+		return []string{`0`}
+	}
+	if err != nil || val == nil {
+		return []string{q.Missing}
+	}
+	vals := q.formatTyped(val)
+	// Get gives zero for missing numeric values in a list.
+	raw := strings.Split(string(NewInfoByte(v.Info().Bytes(), nil).SGet(tag)), `,`)
+	for i := range vals {
+		if i < len(raw) && raw[i] == `.` {
+			vals[i] = q.Missing
+		}
+	}
+	return vals
+}
+
+// queryMissingInt is passed to GetGenotypeField to spot missing Integer
+// values. Missing Float values are NaN.
+const queryMissingInt = math.MinInt32
+
+// formatValues returns the values of a FORMAT field as text.
+func (q *Query) formatValues(v *Variant, g *SampleGenotype, tag string) []string {
+	var missing interface{} = ``
+	if f, found := v.Header.SampleFormats[tag]; found {
+		switch f.Type {
+		case `Integer`:
+			missing = queryMissingInt
+		case `Float`:
+			missing = float32(math.NaN())
+		}
+	}
+	val, err := v.GetGenotypeField(g, tag, missing)
+	if err != nil || val == nil {
+		return []string{q.Missing}
+	}
+	if s, ok := val.(string); ok && (s == `` || s == `.`) {
+		return []string{q.Missing}
+	}
+	return q.formatTyped(val)
+}
+
+// formatTyped returns the values of a typed INFO or FORMAT value as text.
+func (q *Query) formatTyped(val interface{}) []string {
+	switch x := val.(type) {
+	case int:
+		if x == queryMissingInt {
+			return []string{q.Missing}
+		}
+		return []string{strconv.Itoa(x)}
+	case float32:
+		if math.IsNaN(float64(x)) {
+			return []string{q.Missing}
+		}
+		return []string{fmtFloat32(x)}
+	case float64:
+		if math.IsNaN(x) {
+			return []string{q.Missing}
+		}
+		return []string{fmtFloat32(float32(x))}
+	case string:
+		return []string{x}
+	case []int:
+		vals := make([]string, len(x))
+		for i, e := range x {
+			vals[i] = q.formatTyped(e)[0]
+		}
+		return vals
+	case []float32:
+		vals := make([]string, len(x))
+		for i, e := range x {
+			vals[i] = q.formatTyped(e)[0]
+		}
+		return vals
+	case []string:
+		return x
+	case []interface{}:
+		vals := make([]string, len(x))
+		for i, e := range x {
+			vals[i] = q.Missing
+			if e != nil {
+				vals[i] = q.formatTyped(e)[0]
+			}
+		}
+		return vals
+	}
+	return []string{fmt.Sprint(val)}
+}
+
+// subsetAlt returns the values of a Number=A or Number=R field for a
+// single ALT allele. Other values and alt -1 are returned unchanged.
+func subsetAlt(vals []string, number string, alt int) []string {
+	if alt < 0 {
+		return vals
+	}
+	switch number {
+	case NumberAltAlleles:
+		if alt < len(vals) {
+			return vals[alt : alt+1]
+		}
+	case NumberAlleles:
+		if alt+1 < len(vals) {
+			return []string{vals[0], vals[alt+1]}
+		}
+	}
+	return vals
+}
+
+// translateGT returns a GT with the allele indices replaced by the
+// alleles.
+func translateGT(gt string, alleles []string) string {
+	var b strings.Builder
+	start := 0
+	for i := 0; i <= len(gt); i++ {
+		if i < len(gt) && gt[i] != '|' && gt[i] != '/' {
+			continue
+		}
+		if tok := gt[start:i]; tok != `` {
+			if a, err := strconv.Atoi(tok); err == nil && a >= 0 && a < len(alleles) {
+				b.WriteString(alleles[a])
+			} else {
+				b.WriteString(tok)
+			}
+		}
+		if i < len(gt) {
+			b.WriteByte(gt[i])
+		}
+		start = i + 1
+	}
+	return b.String()
+}
+
+// quote quotes a value for CSV if needed.
+func (q *Query) quote(s string) string {
+	if !q.CSV || !strings.ContainsAny(s, ",\"\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,73 @@
+package vcfgo
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var queryStr = `##fileformat=VCFv4.2
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Depth">
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency">
+##INFO=<ID=DB,Number=0,Type=Flag,Description="dbSNP">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths">
+##FORMAT=<ID=GQ,Number=1,Type=Integer,Description="Genotype quality">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2
+1	100	rs1	A	C,G	50	PASS	DP=10;AF=0.5,.;DB	GT:AD:GQ	0/1:5,4,1:30	1/2:.:.
+1	200	.	T	A	.	.	.	GT:AD	0|0:9,0	./.:.
+`
+
+type QuerySuite struct{}
+
+var _ = Suite(&QuerySuite{})
+
+func (s *QuerySuite) TestQuery(c *C) {
+	rdr, err := NewReader(strings.NewReader(queryStr), true)
+	c.Assert(err, IsNil)
+	q, err := NewQuery(`%CHROM\t%POS\t%REF\t%ALT\t%INFO/DP\t%DB\t%AF{1}[\t%SAMPLE=%GT]\n`, rdr.Header)
+	c.Assert(err, IsNil)
+	c.Assert(q.HeaderRow(), Equals, "#CHROM\tPOS\tREF\tALT\tINFO/DP\tINFO/DB\tINFO/AF{1}\tS1:SAMPLE=S1:GT\tS2:SAMPLE=S2:GT\n")
+
+	v := rdr.Read()
+	out, err := q.Format(v)
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "1\t100\tA\tC,G\t10\t1\t.\tS1=0/1\tS2=1/2\n")
+	v = rdr.Read()
+	out, err = q.Format(v)
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "1\t200\tT\tA\t.\t0\t.\tS1=0|0\tS2=./.\n")
+}
+
+func (s *QuerySuite) TestPerAlt(c *C) {
+	rdr, err := NewReader(strings.NewReader(queryStr), false)
+	c.Assert(err, IsNil)
+	q, err := NewQuery("%POS,%ALT,%AF,%QUAL[,%TGT,%AD,%GQ]\n", rdr.Header)
+	c.Assert(err, IsNil)
+	q.PerAlt = true
+	q.CSV = true
+	q.Missing = "NA"
+	out, err := q.Format(rdr.Read())
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "100,C,0.5,50,A/C,\"5,4\",30,C/G,NA,NA\n100,G,NA,50,A/C,\"5,1\",30,C/G,NA,NA\n")
+}
+
+func (s *QuerySuite) TestErrors(c *C) {
+	rdr, err := NewReader(strings.NewReader(queryStr), true)
+	c.Assert(err, IsNil)
+	for _, f := range []string{`%XX`, `%FMT/AD`, `[%SAMPLE`, `%SAMPLE`, `[%FOO]`, `%AF{x}`, `%`, `[[%GT]]`} {
+		_, err := NewQuery(f, rdr.Header)
+		c.Assert(err, ErrorMatches, "vcfgo: bad query format.*", Commentf(f))
+	}
+}
+
+func (s *QuerySuite) TestFirstAltMissing(c *C) {
+	rdr, err := NewReader(strings.NewReader(queryStr), true)
+	c.Assert(err, IsNil)
+	q, err := NewQuery("%POS\t%FIRST_ALT\n", rdr.Header)
+	c.Assert(err, IsNil)
+	v := &Variant{Chromosome: "1", Pos: 5, Reference: "A", Header: rdr.Header, Info_: NewInfoByte(nil, rdr.Header)}
+	out, err := q.Format(v)
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "5\t.\n")
+}
@@ -45,12 +45,16 @@ type Reader struct {
 	LineNumber  int
 	lazySamples bool
 	r           io.Reader
+	renamer     *ContigRenamer
+	regions     *regionFilter
+	bgzf        *bgzfReader
+	index       Index
 }
 
 func NewWithHeader(r io.Reader, h *Header, lazySamples bool) (*Reader, error) {
 	buf := bufio.NewReaderSize(r, 32768*2)
 	var verr = NewVCFError()
-	return &Reader{buf, h, verr, 1, lazySamples, r}, nil
+	return &Reader{buf: buf, Header: h, verr: verr, LineNumber: 1, lazySamples: lazySamples, r: r}, nil
 }
 
 // NewReader returns a Reader.
@@ -158,8 +162,12 @@ func NewReader(r io.Reader, lazySamples bool) (*Reader, error) {
 		}
 	}
 
+	for _, err := range h.parseLines() {
+		verr.Add(err, LineNumber)
+	}
+
     // Construct and return *Reader
-	reader := &Reader{buffered, h, verr, LineNumber, lazySamples, r}
+	reader := &Reader{buf: buffered, Header: h, verr: verr, LineNumber: LineNumber, lazySamples: lazySamples, r: r}
 	return reader, reader.Error()
 }
 
@@ -190,6 +198,54 @@ func makeFields(line []byte) [][]byte {
 // Read returns a pointer to a Variant. Upon reading the caller is assumed
 // to check Reader.Err()
 func (vr *Reader) Read() *Variant {
+	if f := vr.regions; f != nil && f.jumps != nil && f.cur == -1 && !vr.nextRegion() {
+		return nil
+	}
+	for {
+		v := vr.read()
+		if v == nil {
+			if f := vr.regions; f != nil && f.jumps != nil && vr.nextRegion() {
+				continue
+			}
+			return nil
+		}
+		if vr.renamer != nil {
+			keep, err := vr.renamer.RenameVariant(v)
+			vr.verr.Add(err, vr.LineNumber)
+			if !keep || err != nil {
+				continue
+			}
+		}
+		if vr.regions != nil {
+			keep, past := vr.regions.keep(v)
+			if past {
+				if !vr.nextRegion() {
+					return nil
+				}
+				continue
+			}
+			if !keep {
+				continue
+			}
+		}
+		return v
+	}
+}
+
+// SetContigRenamer renames the contigs in the Reader's Header and causes
+// all subsequent calls to Read() to rename the contigs of each Variant.
+// Variants on unmapped contigs are skipped if the renamer Policy is
+// DropUnmapped and are skipped and reported via Reader.Error() if it is
+// ErrorUnmapped.
+func (vr *Reader) SetContigRenamer(cr *ContigRenamer) error {
+	if err := cr.RenameHeader(vr.Header); err != nil {
+		return err
+	}
+	vr.renamer = cr
+	return nil
+}
+
+func (vr *Reader) read() *Variant {
 
 	line, err := vr.buf.ReadBytes('\n')
 	if err != nil {
@@ -256,6 +312,18 @@ func (vr *Reader) Parse(fields [][]byte) *Variant {
 	return v
 }
 
+// parseLines populates Infos, SampleFormats, Filters, Contigs, Samples
+// and Pedigree from Lines.
+func (h *Header) parseLines() []error {
+	errs := h.parseFieldLines()
+	contigs, err := NewContigDictFromHeader(h)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	h.Contigs = contigs
+	return append(errs, h.parseSampleMetadata()...)
+}
+
 // Force parsing of the sample fields.
 func (h *Header) ParseSamples(v *Variant) error {
 	if v.Format == nil || v.sampleString == "" || v.Samples != nil {
@@ -186,6 +186,26 @@ func (s *ReaderSuite) TestSampleParsingErrors(c *C) {
 	c.Assert(rdr.Error(), ErrorMatches, ".*E.* invalid syntax.*")
 }
 
+func (s *ReaderSuite) TestReaderHeaderFieldErrors(c *C) {
+	// Infos, SampleFormats and Filters are parsed from Lines for every
+	// version, and an invalid Number is reported with a usable Reader.
+	sr := strings.NewReader(`##fileformat=VCFv4.2
+##INFO=<ID=X,Number=Q,Type=Integer,Description="x">
+##FORMAT=<ID=Y,Number=-1,Type=Integer,Description="y">
+##FILTER=<ID=q10,Description="Quality below 10">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S-1
+1	100000	.	C	G	.	q10	X=1	Y	2
+`)
+	rdr, err := NewReader(sr, false)
+	c.Assert(err, ErrorMatches, "(?s)INFO/X has invalid Number=Q.*FORMAT/Y has invalid Number=-1.*")
+	c.Assert(rdr, NotNil)
+	c.Assert(rdr.Header.Infos["X"].Number, Equals, "Q")
+	c.Assert(rdr.Header.SampleFormats["Y"].Number, Equals, "-1")
+	c.Assert(rdr.Header.Filters["q10"], Equals, "Quality below 10")
+	rdr.Clear()
+	c.Assert(rdr.Read(), NotNil)
+}
+
 func (s *ReaderSuite) TestSampleParsingErrors2(c *C) {
 	f, err := os.Open("test-dp.vcf")
 	c.Assert(err, IsNil)
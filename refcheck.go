@@ -0,0 +1,140 @@
+package vcfgo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RefStatus is the result of comparing REF with the reference genome.
+type RefStatus int
+
+const (
+	// RefOK means REF matches the reference, ignoring case.
+	RefOK RefStatus = iota // EnumIndex = 0
+	// RefMismatch means REF does not match the reference.
+	RefMismatch // EnumIndex = 1
+	// RefAmbiguous means REF or the reference has an IUPAC ambiguity
+	// code, such as N or R, where they differ.
+	RefAmbiguous // EnumIndex = 2
+	// RefOutOfBounds means REF extends past the end of the contig or
+	// the contig is not in the reference.
+	RefOutOfBounds // EnumIndex = 3
+	// RefSwapped means REF does not match the reference but an ALT
+	// allele does.
+	RefSwapped // EnumIndex = 4
+)
+
+// String - Creating common behaviour - give the type a String function
+func (s RefStatus) String() string {
+	return [...]string{`ok`, `mismatch`, `ambiguous`, `out_of_bounds`, `swapped`}[s]
+}
+
+// isACGT reports whether every base is A, C, G or T in either case.
+func isACGT(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case 'A', 'C', 'G', 'T', 'a', 'c', 'g', 't':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// CheckRef compares REF with the reference genome. For RefSwapped, the
+// 0-based index of the ALT allele that matches the reference is also
+// returned. Symbolic, breakend and * alleles are never taken to match.
+// Errors other than an unknown contig or a region outside the contig
+// are returned.
+func (v *Variant) CheckRef(g Genome) (RefStatus, int, error) {
+	start := int(v.Pos) - 1
+	seq, err := g.Seq(v.Chromosome, start, start+len(v.Reference))
+	if errors.Is(err, ErrOutOfBounds) || errors.Is(err, ErrContigNotFound) {
+		return RefOutOfBounds, -1, nil
+	} else if err != nil {
+		return RefOK, -1, err
+	}
+	ref := string(seq)
+	if strings.EqualFold(ref, v.Reference) {
+		return RefOK, -1, nil
+	}
+	for i := range ref {
+		if !strings.EqualFold(ref[i:i+1], v.Reference[i:i+1]) && (!isACGT(ref[i:i+1]) || !isACGT(v.Reference[i:i+1])) {
+			return RefAmbiguous, -1, nil
+		}
+	}
+	for i, a := range v.Alternate {
+		if !IsSequenceAllele(a) {
+			continue
+		}
+		seq, err := g.Seq(v.Chromosome, start, start+len(a))
+		if err == nil && strings.EqualFold(string(seq), a) {
+			return RefSwapped, i, nil
+		}
+	}
+	return RefMismatch, -1, nil
+}
+
+// SwapRefAlt swaps REF with the ALT allele at the 0-based index alt.
+// GT is recoded and Number=R and Number=G values of INFO and FORMAT
+// fields are reordered. Number=A values move with their alleles; the
+// new ALT allele, which was REF, has a missing value. Fields with the
+// wrong number of values are left as they are and an error returned.
+func (v *Variant) SwapRefAlt(alt int) error {
+	if alt < 0 || alt >= len(v.Alternate) {
+		return fmt.Errorf("%s:%d has no ALT allele %d", v.Chromosome, v.Pos, alt)
+	}
+	m := make([]int, len(v.Alternate)+1)
+	for a := range m {
+		m[a] = a
+	}
+	m[0], m[alt+1] = alt+1, 0
+	err := v.remapAlleles(m, len(m))
+	v.Reference, v.Alternate[alt] = v.Alternate[alt], v.Reference
+	return err
+}
+
+// RefChecker reads Variants from a Reader and checks REF against the
+// reference genome. If Fix is true, swapped records have REF and ALT
+// swapped back and are reported as RefSwapped. Counts holds the number
+// of records with each RefStatus.
+type RefChecker struct {
+	Fix    bool
+	Counts map[RefStatus]int
+
+	rdr    *Reader
+	genome Genome
+	verr   *VCFError
+}
+
+// NewRefChecker returns a RefChecker that does not fix records.
+func NewRefChecker(rdr *Reader, g Genome) *RefChecker {
+	return &RefChecker{Counts: make(map[RefStatus]int), rdr: rdr, genome: g, verr: NewVCFError()}
+}
+
+// Read returns the next Variant and its RefStatus or nil at the end of
+// the input. Errors checking or fixing a Variant are added to those
+// returned by Error and the Variant is returned unchanged.
+func (c *RefChecker) Read() (*Variant, RefStatus) {
+	v := c.rdr.Read()
+	if v == nil {
+		return nil, RefOK
+	}
+	status, alt, err := v.CheckRef(c.genome)
+	c.verr.Add(err, v.LineNumber)
+	c.Counts[status]++
+	if status == RefSwapped && c.Fix {
+		c.verr.Add(v.SwapRefAlt(alt), v.LineNumber)
+	}
+	return v, status
+}
+
+// Error returns the errors checking and fixing Variants. Errors reading
+// the input are returned by the Reader.
+func (c *RefChecker) Error() error {
+	if c.verr.IsEmpty() {
+		return nil
+	}
+	return c.verr
+}
@@ -0,0 +1,66 @@
+package vcfgo
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var refCheckStr = `##fileformat=VCFv4.2
+##INFO=<ID=AC,Number=A,Type=Integer,Description="Allele count">
+##INFO=<ID=RD,Number=R,Type=Integer,Description="Read depths">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allele depths">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled likelihoods">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	s1	s2
+chr1	2	ok	C	A	.	PASS	AC=1;RD=7,3	GT:AD:PL	0/1:7,3:30,0,200	0/0:10,0:0,30,300
+chr1	2	swap	A	C	.	PASS	AC=1;RD=7,3	GT:AD:PL	0/1:7,3:30,0,200	0|0:10,0:0,30,300
+chr1	2	multi	A	G,C	.	PASS	AC=1,2;RD=5,3,2	GT:AD:PL	0/2:5,0,2:1,2,3,4,5,6	1/2:.:.
+chr1	5	ambig	A	T	.	PASS	.	GT	0/1	0/0
+chr1	9	oob	GTA	G	.	PASS	.	GT	0/1	0/0
+chr9	1	nocontig	A	T	.	PASS	.	GT	0/1	0/0
+chr1	1	mismatch	G	T	.	PASS	.	GT	0/1	0/0
+`
+
+var refGenome = mapGenome{"chr1": "ACGTNRACGT"}
+
+type RefCheckSuite struct{}
+
+var _ = Suite(&RefCheckSuite{})
+
+func (s *RefCheckSuite) TestCheck(c *C) {
+	rdr, err := NewReader(strings.NewReader(refCheckStr), false)
+	c.Assert(err, IsNil)
+	rc := NewRefChecker(rdr, refGenome)
+	var got []string
+	for v, status := rc.Read(); v != nil; v, status = rc.Read() {
+		got = append(got, v.Id()+":"+status.String())
+	}
+	c.Assert(got, DeepEquals, []string{"ok:ok", "swap:swapped", "multi:swapped", "ambig:ambiguous",
+		"oob:out_of_bounds", "nocontig:out_of_bounds", "mismatch:mismatch"})
+	c.Assert(rc.Counts, DeepEquals, map[RefStatus]int{RefOK: 1, RefSwapped: 2, RefAmbiguous: 1, RefOutOfBounds: 2, RefMismatch: 1})
+	c.Assert(rc.Error(), IsNil)
+}
+
+func (s *RefCheckSuite) TestFix(c *C) {
+	rdr, err := NewReader(strings.NewReader(refCheckStr), false)
+	c.Assert(err, IsNil)
+	rc := NewRefChecker(rdr, refGenome)
+	rc.Fix = true
+	rc.Read()
+	v, status := rc.Read()
+	c.Assert(status, Equals, RefSwapped)
+	c.Assert(v.String(), Equals, "chr1\t2\tswap\tC\tA\t.\tPASS\tAC=.;RD=3,7\tGT:AD:PL\t1/0:3,7:200,0,30\t1|1:0,10:300,30,0")
+	c.Assert(v.Samples[1].GT, DeepEquals, []int{1, 1})
+	c.Assert(v.Samples[1].Phased, Equals, true)
+	c.Assert(v.Samples[0].GL, DeepEquals, []float64{-20, 0, -3})
+
+	v, status = rc.Read()
+	c.Assert(status, Equals, RefSwapped)
+	// A/G,C becomes C/G,A: 0->2, 1->1, 2->0.
+	c.Assert(v.String(), Equals, "chr1\t2\tmulti\tC\tG,A\t.\tPASS\tAC=1,.;RD=2,3,5\tGT:AD:PL\t2/0:2,0,5:6,5,3,4,2,1\t1/0:.:.")
+	c.Assert(rc.Error(), IsNil)
+
+	v = &Variant{Chromosome: "chr1", Pos: 2, Reference: "A", Alternate: []string{"C"}}
+	c.Assert(v.SwapRefAlt(1), ErrorMatches, "chr1:2 has no ALT allele 1")
+}
@@ -0,0 +1,397 @@
+package vcfgo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoIndex is returned by Fetch for a Reader without an index.
+var ErrNoIndex = errors.New("vcfgo: reader has no index")
+
+// Interval is a 0-based, half-open interval as used by BED files.
+type Interval struct {
+	Start, End uint32
+	Name       string
+}
+
+// IntervalTree holds the intervals of one contig and finds those that
+// overlap a query. It is an augmented tree held in an array sorted by
+// start which is (re)built on the first query after an Add.
+type IntervalTree struct {
+	ivs    []Interval
+	maxEnd []uint32
+	dirty  bool
+}
+
+// Add adds an interval to the tree.
+func (t *IntervalTree) Add(iv Interval) {
+	t.ivs = append(t.ivs, iv)
+	t.dirty = true
+}
+
+// Len returns the number of intervals in the tree.
+func (t *IntervalTree) Len() int {
+	return len(t.ivs)
+}
+
+func (t *IntervalTree) build() {
+	if !t.dirty {
+		return
+	}
+	sort.SliceStable(t.ivs, func(i, j int) bool {
+		if t.ivs[i].Start != t.ivs[j].Start {
+			return t.ivs[i].Start < t.ivs[j].Start
+		}
+		return t.ivs[i].End < t.ivs[j].End
+	})
+	t.maxEnd = make([]uint32, len(t.ivs))
+	var build func(lo, hi int) uint32
+	build = func(lo, hi int) uint32 {
+		if lo >= hi {
+			return 0
+		}
+		mid := (lo + hi) / 2
+		m := t.ivs[mid].End
+		if l := build(lo, mid); l > m {
+			m = l
+		}
+		if r := build(mid+1, hi); r > m {
+			m = r
+		}
+		t.maxEnd[mid] = m
+		return m
+	}
+	build(0, len(t.ivs))
+	t.dirty = false
+}
+
+// Overlapping returns the intervals that overlap [start, end) in order
+// of start.
+func (t *IntervalTree) Overlapping(start, end uint32) []Interval {
+	var found []Interval
+	t.query(start, end, func(iv Interval) bool {
+		found = append(found, iv)
+		return true
+	})
+	return found
+}
+
+// Overlaps reports whether any interval overlaps [start, end).
+func (t *IntervalTree) Overlaps(start, end uint32) bool {
+	found := false
+	t.query(start, end, func(Interval) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// query calls f for each overlapping interval until f returns false.
+func (t *IntervalTree) query(start, end uint32, f func(Interval) bool) {
+	t.build()
+	var walk func(lo, hi int) bool
+	walk = func(lo, hi int) bool {
+		if lo >= hi {
+			return true
+		}
+		mid := (lo + hi) / 2
+		if t.maxEnd[mid] <= start {
+			return true
+		}
+		if !walk(lo, mid) {
+			return false
+		}
+		iv := t.ivs[mid]
+		if iv.Start >= end {
+			return true
+		}
+		if iv.End > start && !f(iv) {
+			return false
+		}
+		return walk(mid+1, hi)
+	}
+	walk(0, len(t.ivs))
+}
+
+// merged returns the intervals with overlapping and adjacent intervals
+// merged.
+func (t *IntervalTree) merged() []Interval {
+	t.build()
+	var m []Interval
+	for _, iv := range t.ivs {
+		if n := len(m); n > 0 && iv.Start <= m[n-1].End {
+			if iv.End > m[n-1].End {
+				m[n-1].End = iv.End
+			}
+			continue
+		}
+		m = append(m, Interval{Start: iv.Start, End: iv.End})
+	}
+	return m
+}
+
+// Regions holds an IntervalTree for each contig.
+type Regions struct {
+	Trees   map[string]*IntervalTree
+	contigs []string
+}
+
+// NewRegions returns an empty Regions.
+func NewRegions() *Regions {
+	return &Regions{Trees: make(map[string]*IntervalTree)}
+}
+
+// Add adds a 0-based, half-open interval.
+func (r *Regions) Add(chrom string, start, end uint32, name string) {
+	t, found := r.Trees[chrom]
+	if !found {
+		t = &IntervalTree{}
+		r.Trees[chrom] = t
+		r.contigs = append(r.contigs, chrom)
+	}
+	t.Add(Interval{start, end, name})
+}
+
+// Contigs returns the contigs in the order they were first added.
+func (r *Regions) Contigs() []string {
+	return r.contigs
+}
+
+// Overlaps reports whether any interval overlaps [start, end) on chrom.
+func (r *Regions) Overlaps(chrom string, start, end uint32) bool {
+	t, found := r.Trees[chrom]
+	return found && t.Overlaps(start, end)
+}
+
+// Overlapping returns the intervals that overlap [start, end) on chrom.
+func (r *Regions) Overlapping(chrom string, start, end uint32) []Interval {
+	if t, found := r.Trees[chrom]; found {
+		return t.Overlapping(start, end)
+	}
+	return nil
+}
+
+// ReadBED reads regions from a BED file, which may be gzip or BGZF
+// compressed. Only the chrom, start, end and optional name columns are
+// used. Blank lines and track, browser and # lines are skipped.
+func ReadBED(r io.Reader) (*Regions, error) {
+	br, err := textReader(r)
+	if err != nil {
+		return nil, err
+	}
+	regions := NewRegions()
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 && !bytes.HasPrefix(line, []byte(`#`)) &&
+			!bytes.HasPrefix(line, []byte(`track`)) && !bytes.HasPrefix(line, []byte(`browser`)) {
+			fields := strings.Split(string(line), "\t")
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("vcfgo: BED line %d has fewer than 3 columns", n)
+			}
+			start, err1 := strconv.ParseUint(fields[1], 10, 32)
+			end, err2 := strconv.ParseUint(fields[2], 10, 32)
+			if err1 != nil || err2 != nil || end < start {
+				return nil, fmt.Errorf("vcfgo: BED line %d has a bad interval %s-%s", n, fields[1], fields[2])
+			}
+			name := ``
+			if len(fields) > 3 {
+				name = fields[3]
+			}
+			regions.Add(fields[0], uint32(start), uint32(end), name)
+		}
+		if err == io.EOF {
+			return regions, nil
+		}
+	}
+}
+
+// textReader returns a reader of the text of r, which may be gzip or
+// BGZF compressed.
+func textReader(r io.Reader) (*bufio.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReader(gz), nil
+	}
+	return br, nil
+}
+
+// OpenBED reads regions from a BED or BED.gz file.
+func OpenBED(path string) (*Regions, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBED(f)
+}
+
+// variantSpan returns the 0-based, half-open span of a Variant. It is
+// at least one base long.
+func variantSpan(v *Variant) (uint32, uint32) {
+	s, e := v.Start(), v.End()
+	if e <= s {
+		e = s + 1
+	}
+	return s, e
+}
+
+// regionFilter restricts the Variants returned by Reader.Read.
+type regionFilter struct {
+	regions *Regions
+	exclude bool
+
+	// With an index, the merged regions are visited in turn.
+	jumps []regionJump
+	cur   int
+}
+
+type regionJump struct {
+	chrom string
+	Interval
+}
+
+// SetRegions restricts Read to Variants that overlap the regions or,
+// if exclude is true, to those that do not. Overlap is judged using
+// Variant.Start() and Variant.End() so the extent of a structural
+// variant is respected. Regions are matched against contig names after
+// any renaming by SetContigRenamer.
+//
+// If the Reader was made by NewIndexedReader, has no ContigRenamer and
+// exclude is false, the index is used to jump to each region in turn.
+// Variants are then returned in the order of the contigs in the regions
+// and each is returned once even if it overlaps several regions.
+func (vr *Reader) SetRegions(regions *Regions, exclude bool) {
+	f := &regionFilter{regions: regions, exclude: exclude, cur: -1}
+	if vr.index != nil && vr.renamer == nil && !exclude {
+		for _, chrom := range regions.Contigs() {
+			for _, iv := range regions.Trees[chrom].merged() {
+				f.jumps = append(f.jumps, regionJump{chrom, iv})
+			}
+		}
+	}
+	vr.regions = f
+}
+
+// nextRegion moves the Reader to the start of the next region that the
+// index has records for. It returns false when there are none left.
+func (vr *Reader) nextRegion() bool {
+	f := vr.regions
+	for f.cur++; f.cur < len(f.jumps); f.cur++ {
+		j := f.jumps[f.cur]
+		offset, found := vr.index.Offset(j.chrom, j.Start, j.End)
+		if !found {
+			continue
+		}
+		if err := vr.bgzf.Seek(offset); err != nil {
+			vr.verr.Add(err, vr.LineNumber)
+			return false
+		}
+		vr.buf.Reset(vr.bgzf)
+		return true
+	}
+	return false
+}
+
+// keep reports whether to return a Variant and, when jumping, whether
+// the Variant is past the current region.
+func (f *regionFilter) keep(v *Variant) (keep bool, past bool) {
+	s, e := variantSpan(v)
+	if f.jumps == nil {
+		return f.regions.Overlaps(v.Chromosome, s, e) != f.exclude, false
+	}
+	j := f.jumps[f.cur]
+	if v.Chromosome != j.chrom || s >= j.End {
+		return false, true
+	}
+	if e <= j.Start {
+		return false, false
+	}
+	// A Variant that overlaps the previous region was returned then.
+	if f.cur > 0 {
+		p := f.jumps[f.cur-1]
+		if p.chrom == v.Chromosome && s < p.End && e > p.Start {
+			return false, false
+		}
+	}
+	return true, false
+}
+
+// Fetch uses the index to return the Variants that overlap the 0-based,
+// half-open region. Contig names are not renamed and the regions given to
+// SetRegions are ignored. Fetch moves the Reader so calls to Read after
+// Fetch do not continue from where Read left off.
+func (vr *Reader) Fetch(chrom string, start, end uint32) ([]*Variant, error) {
+	if vr.index == nil {
+		return nil, ErrNoIndex
+	}
+	offset, found := vr.index.Offset(chrom, start, end)
+	if !found {
+		return nil, nil
+	}
+	if err := vr.bgzf.Seek(offset); err != nil {
+		return nil, err
+	}
+	vr.buf.Reset(vr.bgzf)
+	var vs []*Variant
+	for v := vr.read(); v != nil; v = vr.read() {
+		s, e := variantSpan(v)
+		if v.Chromosome != chrom || s >= end {
+			break
+		}
+		if e > start {
+			vs = append(vs, v)
+		}
+	}
+	return vs, nil
+}
+
+// NewIndexedReader returns a Reader for BGZF-compressed VCF that uses
+// idx to jump to the regions given to SetRegions.
+func NewIndexedReader(r io.ReadSeeker, idx Index, lazySamples bool) (*Reader, error) {
+	b := newBGZFReader(r)
+	vr, err := NewReader(b, lazySamples)
+	if vr == nil {
+		return nil, err
+	}
+	vr.bgzf, vr.index = b, idx
+	return vr, err
+}
+
+// OpenIndexed opens a BGZF-compressed VCF file and its tabix index,
+// which is path with .tbi appended.
+func OpenIndexed(path string, lazySamples bool) (*Reader, error) {
+	t, err := os.Open(path + `.tbi`)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := ReadTabix(t)
+	t.Close()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	vr, err := NewIndexedReader(f, idx, lazySamples)
+	if vr == nil {
+		f.Close()
+	}
+	return vr, err
+}
@@ -0,0 +1,205 @@
+package vcfgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var regionStr = `##fileformat=VCFv4.3
+##INFO=<ID=SVLEN,Number=1,Type=Integer,Description="SV length">
+##contig=<ID=chr1>
+##contig=<ID=chr2>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+chr1	100	a	A	T	.	PASS	.
+chr1	20000	b	C	G	.	PASS	.
+chr1	40001	c	GAAAAAAAAA	G	.	PASS	.
+chr1	50000	d	T	<DEL>	.	PASS	SVLEN=-2000
+chr2	500	e	G	A	.	PASS	.
+`
+
+var regionBed = "track name=test\n# comment\nchr1\t19990\t20010\tr1\n\nchr1\t40000\t40002\nchr1\t40008\t40010\nchr1\t51000\t51001\nchr2\t0\t1000\nchr3\t0\t10\n"
+
+type RegionSuite struct{}
+
+var _ = Suite(&RegionSuite{})
+
+func variantIds(vs []*Variant) string {
+	var ids []string
+	for _, v := range vs {
+		ids = append(ids, v.Id())
+	}
+	return strings.Join(ids, ",")
+}
+
+func (s *RegionSuite) TestIntervalTree(c *C) {
+	t := &IntervalTree{}
+	for _, iv := range []Interval{{10, 20, "a"}, {15, 40, "b"}, {50, 60, "c"}, {0, 5, "d"}, {30, 31, "e"}} {
+		t.Add(iv)
+	}
+	c.Assert(t.Len(), Equals, 5)
+	c.Assert(t.Overlapping(18, 32), DeepEquals, []Interval{{10, 20, "a"}, {15, 40, "b"}, {30, 31, "e"}})
+	c.Assert(t.Overlapping(20, 21), DeepEquals, []Interval{{15, 40, "b"}})
+	c.Assert(t.Overlaps(40, 50), Equals, false)
+	c.Assert(t.Overlaps(5, 10), Equals, false)
+	c.Assert(t.Overlaps(59, 100), Equals, true)
+	t.Add(Interval{42, 45, "f"})
+	c.Assert(t.Overlaps(40, 50), Equals, true)
+	c.Assert(t.merged(), DeepEquals, []Interval{{0, 5, ""}, {10, 40, ""}, {42, 45, ""}, {50, 60, ""}})
+}
+
+func (s *RegionSuite) TestReadBED(c *C) {
+	r, err := ReadBED(strings.NewReader(regionBed))
+	c.Assert(err, IsNil)
+	c.Assert(r.Contigs(), DeepEquals, []string{"chr1", "chr2", "chr3"})
+	c.Assert(r.Trees["chr1"].Len(), Equals, 4)
+	c.Assert(r.Overlapping("chr1", 20000, 20001), DeepEquals, []Interval{{19990, 20010, "r1"}})
+	c.Assert(r.Overlaps("chr4", 0, 10), Equals, false)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(regionBed))
+	w.Close()
+	r, err = ReadBED(&gz)
+	c.Assert(err, IsNil)
+	c.Assert(r.Trees["chr1"].Len(), Equals, 4)
+
+	_, err = ReadBED(strings.NewReader("chr1\t10\n"))
+	c.Assert(err, ErrorMatches, ".*fewer than 3 columns")
+	_, err = ReadBED(strings.NewReader("chr1\t10\t5\n"))
+	c.Assert(err, ErrorMatches, ".*bad interval 10-5")
+}
+
+func (s *RegionSuite) TestFilter(c *C) {
+	regions, err := ReadBED(strings.NewReader(regionBed))
+	c.Assert(err, IsNil)
+
+	rdr, err := NewReader(strings.NewReader(regionStr), false)
+	c.Assert(err, IsNil)
+	rdr.SetRegions(regions, false)
+	// d is a deletion whose End() reaches 51001.
+	c.Assert(variantIds(readAll(rdr)), Equals, "b,c,d,e")
+
+	rdr, err = NewReader(strings.NewReader(regionStr), false)
+	c.Assert(err, IsNil)
+	rdr.SetRegions(regions, true)
+	c.Assert(variantIds(readAll(rdr)), Equals, "a")
+}
+
+// writeBGZF compresses each chunk as a BGZF block, adds the end-of-file
+// block and returns the virtual offset of the start of each block.
+func writeBGZF(chunks []string) ([]byte, []uint64) {
+	var out bytes.Buffer
+	var offsets []uint64
+	for _, chunk := range append(chunks, "") {
+		offsets = append(offsets, uint64(out.Len())<<16)
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		w.Header.Extra = []byte{'B', 'C', 2, 0, 0, 0}
+		w.Write([]byte(chunk))
+		w.Close()
+		block := b.Bytes()
+		binary.LittleEndian.PutUint16(block[16:18], uint16(len(block)-1))
+		out.Write(block)
+	}
+	return out.Bytes(), offsets
+}
+
+type tabixRec struct {
+	beg, end  uint32
+	off, next uint64
+}
+
+// writeTabix builds a tabix index for records of less than 16kb.
+func writeTabix(names []string, recs map[string][]tabixRec) []byte {
+	var raw bytes.Buffer
+	le := func(v interface{}) { binary.Write(&raw, binary.LittleEndian, v) }
+	raw.WriteString("TBI\x01")
+	nm := strings.Join(names, "\x00") + "\x00"
+	le([]int32{int32(len(names)), 2, 1, 2, 0, '#', 0, int32(len(nm))})
+	raw.WriteString(nm)
+	for _, name := range names {
+		bins := map[uint32][]uint64{}
+		var order []uint32
+		var linear []uint64
+		for _, r := range recs[name] {
+			bin := 4681 + r.beg>>14
+			if _, ok := bins[bin]; !ok {
+				order = append(order, bin)
+			}
+			bins[bin] = append(bins[bin], r.off, r.next)
+			for w := int(r.beg >> 14); w <= int((r.end-1)>>14); w++ {
+				for len(linear) <= w {
+					linear = append(linear, 0)
+				}
+				if linear[w] == 0 {
+					linear[w] = r.off
+				}
+			}
+		}
+		le(int32(len(order)))
+		for _, bin := range order {
+			le(bin)
+			le(int32(len(bins[bin]) / 2))
+			le(bins[bin])
+		}
+		for i := 1; i < len(linear); i++ {
+			if linear[i] == 0 {
+				linear[i] = linear[i-1]
+			}
+		}
+		le(int32(len(linear)))
+		le(linear)
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(raw.Bytes())
+	w.Close()
+	return gz.Bytes()
+}
+
+func (s *RegionSuite) TestIndexed(c *C) {
+	lines := strings.SplitAfter(regionStr, "\n")
+	header := strings.Join(lines[:5], "")
+	records := lines[5 : len(lines)-1]
+	data, offsets := writeBGZF(append([]string{header}, records...))
+
+	// Record i is in block i+1.
+	recs := map[string][]tabixRec{}
+	add := func(chrom string, beg, end uint32, i int) {
+		recs[chrom] = append(recs[chrom], tabixRec{beg, end, offsets[i+1], offsets[i+2]})
+	}
+	add("chr1", 99, 100, 0)
+	add("chr1", 19999, 20000, 1)
+	add("chr1", 40000, 40010, 2)
+	add("chr1", 49999, 50000, 3)
+	add("chr2", 499, 500, 4)
+	idx, err := ReadTabix(bytes.NewReader(writeTabix([]string{"chr1", "chr2"}, recs)))
+	c.Assert(err, IsNil)
+	c.Assert(idx.Names, DeepEquals, []string{"chr1", "chr2"})
+
+	off, ok := idx.Offset("chr1", 19990, 20010)
+	c.Assert(ok, Equals, true)
+	c.Assert(off, Equals, offsets[2])
+	_, ok = idx.Offset("chr3", 0, 10)
+	c.Assert(ok, Equals, false)
+	_, ok = idx.Offset("chr2", 20000, 20010)
+	c.Assert(ok, Equals, false)
+
+	regions, err := ReadBED(strings.NewReader(regionBed))
+	c.Assert(err, IsNil)
+	rdr, err := NewIndexedReader(bytes.NewReader(data), idx, false)
+	c.Assert(err, IsNil)
+	rdr.SetRegions(regions, false)
+	// c overlaps two regions but is returned once.
+	c.Assert(variantIds(readAll(rdr)), Equals, "b,c,d,e")
+	c.Assert(rdr.Error(), IsNil)
+
+	// Without regions the whole file is read.
+	rdr, err = NewIndexedReader(bytes.NewReader(data), idx, false)
+	c.Assert(err, IsNil)
+	c.Assert(variantIds(readAll(rdr)), Equals, "a,b,c,d,e")
+}
@@ -0,0 +1,197 @@
+package vcfgo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Reheader rewrites the header and sample columns of a stream of
+// Variants. Samples can be renamed (e.g. to de-identify them before
+// sharing), reordered or subset, or the whole header can be replaced by
+// a template. Renaming also fixes up the IDs in ##SAMPLE and ##PEDIGREE
+// lines. The input Header is never modified - the Reheader holds a new
+// output Header and Apply() points each Variant at it.
+
+// Reheader maps the sample columns of an input Header to an output
+// Header.
+type Reheader struct {
+	Header *Header
+
+	in    *Header
+	index []int // output column i comes from input column index[i]
+}
+
+// NewReheader returns a *Reheader whose output Header starts as a copy
+// of the input Header.
+func NewReheader(in *Header) *Reheader {
+	out := in.shallowCopy()
+	out.Samples = make(map[string]*SampleMeta, len(in.Samples))
+	for id, s := range in.Samples {
+		out.Samples[id] = s
+	}
+	index := make([]int, len(in.SampleNames))
+	for i := range index {
+		index[i] = i
+	}
+	return &Reheader{Header: out, in: in, index: index}
+}
+
+// NewReheaderFromTemplate returns a *Reheader whose output Header is a
+// copy of the template Header. If the template has sample columns, they
+// rename the input samples by position so the template must have the
+// same number of samples as the input. If the template has no sample
+// columns, the input sample names are kept.
+func NewReheaderFromTemplate(in *Header, template *Header) (*Reheader, error) {
+	if len(template.SampleNames) > 0 && len(template.SampleNames) != len(in.SampleNames) {
+		return nil, fmt.Errorf("template has %d samples but input has %d", len(template.SampleNames), len(in.SampleNames))
+	}
+	rh := NewReheader(template)
+	rh.in = in
+	if len(template.SampleNames) == 0 {
+		rh.Header.SampleNames = append(rh.Header.SampleNames, in.SampleNames...)
+		rh.index = make([]int, len(in.SampleNames))
+		for i := range rh.index {
+			rh.index[i] = i
+		}
+	}
+	return rh, nil
+}
+
+// NewSampleMapFromReader reads a two-column, whitespace separated sample
+// renaming file with the old name in the first column and the new name
+// in the second. Blank lines and lines starting with # are ignored.
+func NewSampleMapFromReader(r io.Reader) (map[string]string, error) {
+	return readNameMap(r)
+}
+
+// Rename renames the output samples. Names that are not in the map are
+// left unchanged. The IDs (and Father, Mother and Original) of ##SAMPLE
+// and ##PEDIGREE lines are renamed as well. It is an error for two
+// samples to end up with the same name.
+func (rh *Reheader) Rename(m map[string]string) error {
+	h := rh.Header
+	names := make([]string, len(h.SampleNames))
+	seen := make(map[string]bool, len(names))
+	for i, s := range h.SampleNames {
+		if n, found := m[s]; found {
+			s = n
+		}
+		if seen[s] {
+			return fmt.Errorf("renaming samples gives duplicate sample name %s", s)
+		}
+		seen[s] = true
+		names[i] = s
+	}
+
+	rename := func(s string) string {
+		if n, found := m[s]; found {
+			return n
+		}
+		return s
+	}
+
+	samples := make(map[string]*SampleMeta, len(h.Samples))
+	for id, s := range h.Samples {
+		ns := *s
+		ns.ID = rename(id)
+		if _, found := samples[ns.ID]; found {
+			return fmt.Errorf("renaming samples gives duplicate SAMPLE line for %s", ns.ID)
+		}
+		samples[ns.ID] = &ns
+	}
+
+	p := NewPedigree()
+	if h.Pedigree != nil {
+		for _, e := range h.Pedigree.Entries {
+			ne := *e
+			ne.ID = rename(e.ID)
+			ne.Father = rename(e.Father)
+			ne.Mother = rename(e.Mother)
+			ne.Original = rename(e.Original)
+			if err := p.Add(&ne); err != nil {
+				return err
+			}
+		}
+	}
+
+	h.SampleNames = names
+	if len(h.Samples) > 0 {
+		h.SetSamples(samples)
+	}
+	if h.Pedigree != nil && len(h.Pedigree.Entries) > 0 {
+		h.SetPedigree(p)
+	}
+	return nil
+}
+
+// Reorder sets the order of the output sample columns. Every name must
+// be a current output sample name but not every sample needs to be
+// named so Reorder can also be used to subset the samples.
+func (rh *Reheader) Reorder(names []string) error {
+	h := rh.Header
+	pos := make(map[string]int, len(h.SampleNames))
+	for i, s := range h.SampleNames {
+		pos[s] = i
+	}
+	index := make([]int, len(names))
+	seen := make(map[string]bool, len(names))
+	for i, s := range names {
+		p, found := pos[s]
+		if !found {
+			return fmt.Errorf("%w - %s", ErrSampleNotFound, s)
+		}
+		if seen[s] {
+			return fmt.Errorf("sample %s requested more than once", s)
+		}
+		seen[s] = true
+		index[i] = rh.index[p]
+	}
+	h.SampleNames = append([]string{}, names...)
+	rh.index = index
+	return nil
+}
+
+// Apply rewrites the sample columns of the Variant to match the output
+// Header and sets Variant.Header to the output Header. Both parsed
+// Samples and lazily parsed sample strings are handled.
+func (rh *Reheader) Apply(v *Variant) error {
+	v.Header = rh.Header
+	if rh.isIdentity() {
+		return nil
+	}
+	if v.Samples != nil {
+		if len(v.Samples) != len(rh.in.SampleNames) {
+			return fmt.Errorf("variant at %s:%d has %d samples but header has %d", v.Chromosome, v.Pos, len(v.Samples), len(rh.in.SampleNames))
+		}
+		samples := make([]*SampleGenotype, len(rh.index))
+		for i, j := range rh.index {
+			samples[i] = v.Samples[j]
+		}
+		v.Samples = samples
+	}
+	if v.sampleString != `` {
+		cols := strings.Split(v.sampleString, "\t")
+		if len(cols) != len(rh.in.SampleNames) {
+			return fmt.Errorf("variant at %s:%d has %d samples but header has %d", v.Chromosome, v.Pos, len(cols), len(rh.in.SampleNames))
+		}
+		out := make([]string, len(rh.index))
+		for i, j := range rh.index {
+			out[i] = cols[j]
+		}
+		v.sampleString = strings.Join(out, "\t")
+	}
+	return nil
+}
+
+func (rh *Reheader) isIdentity() bool {
+	if len(rh.index) != len(rh.in.SampleNames) {
+		return false
+	}
+	for i, j := range rh.index {
+		if i != j {
+			return false
+		}
+	}
+	return true
+}
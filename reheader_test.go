@@ -0,0 +1,108 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var reheaderStr = `##fileformat=VCFv4.3
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Read Depth">
+##SAMPLE=<ID=Normal,Genomes=Germline,Mixture=1,Description="Blood">
+##SAMPLE=<ID=Tumour,Genomes=Germline;Tumor,Mixture=.3;.7,Description="Germline;Tumour">
+##PEDIGREE=<ID=Tumour,Original=Normal>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	Normal	Tumour	Other
+1	100	.	A	G	.	PASS	.	GT:DP	0/0:10	0/1:20	1/1:30
+`
+
+type ReheaderSuite struct{}
+
+var _ = Suite(&ReheaderSuite{})
+
+func (s *ReheaderSuite) read(c *C, lazy bool) (*Reader, *Variant) {
+	rdr, err := NewReader(strings.NewReader(reheaderStr), lazy)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	c.Assert(v, NotNil)
+	return rdr, v
+}
+
+func (s *ReheaderSuite) TestRename(c *C) {
+	rdr, v := s.read(c, false)
+	m, err := NewSampleMapFromReader(strings.NewReader("Normal\tP001_N\nTumour\tP001_T\n"))
+	c.Assert(err, IsNil)
+	rh := NewReheader(rdr.Header)
+	c.Assert(rh.Rename(m), IsNil)
+	c.Assert(rh.Apply(v), IsNil)
+
+	c.Assert(rh.Header.SampleNames, DeepEquals, []string{"P001_N", "P001_T", "Other"})
+	og, err := rh.Header.Pedigree.Original("P001_T")
+	c.Assert(err, IsNil)
+	c.Assert(og, Equals, "P001_N")
+	c.Assert(v.Header, Equals, rh.Header)
+
+	var buf bytes.Buffer
+	wtr, err := NewWriter(&buf, rh.Header)
+	c.Assert(err, IsNil)
+	wtr.WriteVariant(v)
+	c.Assert(buf.String(), Equals, `##fileformat=VCFv4.3
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Read Depth">
+##SAMPLE=<ID=P001_N,Genomes=Germline,Mixture=1,Description="Blood">
+##SAMPLE=<ID=P001_T,Genomes=Germline;Tumor,Mixture=0.3;0.7,Description="Germline;Tumour">
+##PEDIGREE=<ID=P001_T,Original=P001_N>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	P001_N	P001_T	Other
+1	100	.	A	G	.	PASS	.	GT:DP	0/0:10	0/1:20	1/1:30
+`)
+
+	// The input header is untouched.
+	c.Assert(rdr.Header.SampleNames, DeepEquals, []string{"Normal", "Tumour", "Other"})
+	_, found := rdr.Header.Samples["Normal"]
+	c.Assert(found, Equals, true)
+
+	rh = NewReheader(rdr.Header)
+	c.Assert(rh.Rename(map[string]string{"Normal": "Other"}), ErrorMatches, ".*duplicate sample name Other")
+}
+
+func (s *ReheaderSuite) TestReorder(c *C) {
+	for _, lazy := range []bool{false, true} {
+		rdr, v := s.read(c, lazy)
+		rh := NewReheader(rdr.Header)
+		c.Assert(rh.Rename(map[string]string{"Other": "X"}), IsNil)
+		c.Assert(rh.Reorder([]string{"X", "Normal"}), IsNil)
+		c.Assert(rh.Apply(v), IsNil)
+		c.Assert(rh.Header.SampleNames, DeepEquals, []string{"X", "Normal"})
+		c.Assert(v.String(), Equals, "1\t100\t.\tA\tG\t.\tPASS\t.\tGT:DP\t1/1:30\t0/0:10")
+		if !lazy {
+			c.Assert(v.Samples[0].DP, Equals, 30)
+		}
+
+		c.Assert(rh.Reorder([]string{"Other"}), ErrorMatches, ".*sample not found.*")
+		c.Assert(rh.Reorder([]string{"X", "X"}), ErrorMatches, ".*more than once")
+	}
+}
+
+func (s *ReheaderSuite) TestTemplate(c *C) {
+	rdr, v := s.read(c, true)
+	tmpl, err := NewReader(strings.NewReader("##fileformat=VCFv4.2\n##source=template\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tA\tB\tC\n"), true)
+	c.Assert(err, IsNil)
+	rh, err := NewReheaderFromTemplate(rdr.Header, tmpl.Header)
+	c.Assert(err, IsNil)
+	c.Assert(rh.Apply(v), IsNil)
+	c.Assert(rh.Header.FileFormat, Equals, "4.2")
+	c.Assert(rh.Header.SampleNames, DeepEquals, []string{"A", "B", "C"})
+
+	tmpl, _ = NewReader(strings.NewReader("##fileformat=VCFv4.2\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tA\n"), true)
+	_, err = NewReheaderFromTemplate(rdr.Header, tmpl.Header)
+	c.Assert(err, ErrorMatches, "template has 1 samples but input has 3")
+
+	tmpl, _ = NewReader(strings.NewReader("##fileformat=VCFv4.2\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"), true)
+	rh, err = NewReheaderFromTemplate(rdr.Header, tmpl.Header)
+	c.Assert(err, IsNil)
+	c.Assert(rh.Header.SampleNames, DeepEquals, []string{"Normal", "Tumour", "Other"})
+	c.Assert(rh.Reorder([]string{"Tumour"}), IsNil)
+	c.Assert(rh.Apply(v), IsNil)
+	c.Assert(v.String(), Equals, "1\t100\t.\tA\tG\t.\tPASS\t.\tGT:DP\t0/1:20")
+}
@@ -0,0 +1,276 @@
+package vcfgo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Contig renaming is mostly about the two naming conventions for the
+// human reference - UCSC style (chr1, chrM) and Ensembl/NCBI style (1,
+// MT). A ContigRenamer holds a mapping between names and a policy for
+// contigs that are not in the mapping. It can be attached to a Reader
+// (Reader.SetContigRenamer) or a Writer (NewRenamingWriter) and it
+// rewrites Variant.Chromosome, the mate chromosome in breakend ALT
+// alleles and the ID of ##contig lines in Header.Lines.
+
+// UnmappedPolicy controls what happens to contigs that are not in the
+// mapping of a ContigRenamer.
+type UnmappedPolicy int
+
+const (
+	KeepUnmapped  UnmappedPolicy = iota // EnumIndex = 0
+	DropUnmapped                        // EnumIndex = 1
+	ErrorUnmapped                       // EnumIndex = 2
+)
+
+// String - Creating common behaviour - give the type a String function
+func (p UnmappedPolicy) String() string {
+	return [...]string{"KeepUnmapped", "DropUnmapped", "ErrorUnmapped"}[p]
+}
+
+// Names of the built-in contig mappings for NewContigRenamerFromPreset.
+const (
+	PresetGRCh37UcscToEnsembl = `GRCh37-ucsc-to-ensembl`
+	PresetGRCh37EnsemblToUcsc = `GRCh37-ensembl-to-ucsc`
+	PresetGRCh38UcscToEnsembl = `GRCh38-ucsc-to-ensembl`
+	PresetGRCh38EnsemblToUcsc = `GRCh38-ensembl-to-ucsc`
+)
+
+var ErrUnmappedContig = errors.New("vcfgo: contig not in renaming map")
+
+// UCSC names for unlocalized, unplaced and alt contigs embed the
+// GenBank accession, e.g. chr1_KI270706v1_random (GRCh38) or
+// chrUn_gl000211 (GRCh37). Ensembl uses the accession itself.
+var ucscAccessionRegexp = regexp.MustCompile(`^chr[0-9XYMUn]+_([A-Za-z]{2}\d+)(?:v(\d+))?(?:_random|_alt|_fix)?$`)
+
+// ContigRenamer renames contigs according to a mapping.
+type ContigRenamer struct {
+	names  map[string]string
+	rule   func(string) (string, bool)
+	Policy UnmappedPolicy
+}
+
+// NewContigRenamer returns a *ContigRenamer for the supplied mapping of
+// old name to new name.
+func NewContigRenamer(m map[string]string, p UnmappedPolicy) *ContigRenamer {
+	names := make(map[string]string, len(m))
+	for k, v := range m {
+		names[k] = v
+	}
+	return &ContigRenamer{names: names, Policy: p}
+}
+
+// NewContigRenamerFromReader reads a mapping from a two-column, whitespace
+// separated file with the old name in the first column and the new name
+// in the second. Blank lines and lines starting with # are ignored.
+func NewContigRenamerFromReader(r io.Reader, p UnmappedPolicy) (*ContigRenamer, error) {
+	m, err := readNameMap(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewContigRenamer(m, p), nil
+}
+
+// readNameMap reads a two-column, whitespace separated old name to new
+// name mapping. Blank lines and lines starting with # are ignored.
+func readNameMap(r io.Reader) (map[string]string, error) {
+	m := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == `` || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("name map error at line %d: expected 2 columns but found %d", n, len(fields))
+		}
+		if _, found := m[fields[0]]; found {
+			return nil, fmt.Errorf("name map error at line %d: %s mapped more than once", n, fields[0])
+		}
+		m[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewContigRenamerFromPreset returns a *ContigRenamer for one of the
+// built-in mappings (PresetGRCh37UcscToEnsembl etc). The presets cover
+// the autosomes, X, Y and the mitochondrion. The UCSC to Ensembl presets
+// also convert UCSC names for unplaced, unlocalized and alt contigs to
+// their accessions. Note that hg19 chrM is not the same sequence as the
+// GRCh37 MT so renaming does not make an hg19 file a GRCh37 file.
+func NewContigRenamerFromPreset(name string, p UnmappedPolicy) (*ContigRenamer, error) {
+	m := make(map[string]string)
+	for i := 1; i <= 22; i++ {
+		n := strconv.Itoa(i)
+		m[`chr`+n] = n
+	}
+	m[`chrX`] = `X`
+	m[`chrY`] = `Y`
+	m[`chrM`] = `MT`
+
+	switch name {
+	case PresetGRCh37UcscToEnsembl, PresetGRCh38UcscToEnsembl:
+		cr := NewContigRenamer(m, p)
+		cr.rule = ucscToAccession
+		return cr, nil
+	case PresetGRCh37EnsemblToUcsc, PresetGRCh38EnsemblToUcsc:
+		inv := make(map[string]string, len(m))
+		for k, v := range m {
+			inv[v] = k
+		}
+		return NewContigRenamer(inv, p), nil
+	}
+	return nil, fmt.Errorf("unknown contig renaming preset: %s", name)
+}
+
+func ucscToAccession(name string) (string, bool) {
+	res := ucscAccessionRegexp.FindStringSubmatch(name)
+	if res == nil {
+		return ``, false
+	}
+	version := res[2]
+	if version == `` {
+		version = `1`
+	}
+	return strings.ToUpper(res[1]) + `.` + version, true
+}
+
+// Rename returns the new name for a contig and true, or the original
+// name and false if the contig is not in the mapping.
+func (cr *ContigRenamer) Rename(name string) (string, bool) {
+	if n, found := cr.names[name]; found {
+		return n, true
+	}
+	if cr.rule != nil {
+		if n, ok := cr.rule(name); ok {
+			return n, true
+		}
+	}
+	return name, false
+}
+
+// rename applies the Policy to an unmapped contig. keep is false if the
+// record holding the contig should be dropped.
+func (cr *ContigRenamer) rename(name string) (newName string, keep bool, err error) {
+	n, ok := cr.Rename(name)
+	if ok {
+		return n, true, nil
+	}
+	switch cr.Policy {
+	case DropUnmapped:
+		return name, false, nil
+	case ErrorUnmapped:
+		return name, true, fmt.Errorf("%w - %s", ErrUnmappedContig, name)
+	}
+	return name, true, nil
+}
+
+// RenameVariant renames the Chromosome of the Variant and the mate
+// chromosome of any breakend ALT alleles. It returns false if the
+// Policy is DropUnmapped and any contig was not mapped. If the Policy
+// is ErrorUnmapped, an error is returned and the Variant is unchanged.
+func (cr *ContigRenamer) RenameVariant(v *Variant) (bool, error) {
+	chrom, keep, err := cr.rename(v.Chromosome)
+	if !keep || err != nil {
+		return keep, err
+	}
+	alts := make([]string, len(v.Alternate))
+	for i, a := range v.Alternate {
+		alts[i] = a
+		mate, ok := breakendMateChrom(a)
+		if !ok {
+			continue
+		}
+		newMate, keep, err := cr.rename(mate)
+		if !keep || err != nil {
+			return keep, err
+		}
+		alts[i] = replaceBreakendMateChrom(a, newMate)
+	}
+	v.Chromosome = chrom
+	v.Alternate = alts
+	return true, nil
+}
+
+// RenameHeader renames the ID of every ##contig line in Header.Lines
+// and rebuilds Header.Contigs. Unmapped contig lines are removed if the
+// Policy is DropUnmapped. If the Policy is ErrorUnmapped and a contig is
+// not mapped, or if two contigs are renamed to the same name, an error is
+// returned and the Header is unchanged.
+func (cr *ContigRenamer) RenameHeader(h *Header) error {
+	lines := make([]*MetaLine, 0, len(h.Lines))
+	for _, m := range h.Lines {
+		if m.MetaType != Structured || m.LineKey != `contig` {
+			lines = append(lines, m)
+			continue
+		}
+		name, keep, err := cr.rename(m.GetValue(`ID`))
+		if err != nil {
+			return err
+		}
+		if keep {
+			m = m.Clone()
+			m.KVs[`ID`].Value = name
+			lines = append(lines, m)
+		}
+	}
+	contigs, err := newContigDictFromLines(lines)
+	if err != nil {
+		return err
+	}
+	h.Lines = lines
+	h.Contigs = contigs
+	return nil
+}
+
+// breakendMateChrom returns the mate chromosome from a breakend ALT
+// allele such as G]17:198982] or [<ctg1>:7[T.
+func breakendMateChrom(alt string) (string, bool) {
+	s, e := breakendMatePositions(alt)
+	if s == -1 {
+		return ``, false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(alt[s:e], `<`), `>`), true
+}
+
+func replaceBreakendMateChrom(alt string, chrom string) string {
+	s, e := breakendMatePositions(alt)
+	if s == -1 {
+		return alt
+	}
+	if alt[s] == '<' {
+		chrom = `<` + chrom + `>`
+	}
+	return alt[:s] + chrom + alt[e:]
+}
+
+// breakendMatePositions returns the start and end of the mate chromosome
+// within a breakend ALT allele or -1, -1 if the allele is not a paired
+// breakend.
+func breakendMatePositions(alt string) (int, int) {
+	open := strings.IndexAny(alt, `[]`)
+	if open == -1 {
+		return -1, -1
+	}
+	close := strings.IndexByte(alt[open+1:], alt[open])
+	if close == -1 {
+		return -1, -1
+	}
+	close += open + 1
+	colon := strings.LastIndexByte(alt[open+1:close], ':')
+	if colon == -1 {
+		return -1, -1
+	}
+	return open + 1, open + 1 + colon
+}
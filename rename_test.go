@@ -0,0 +1,162 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var renameStr = `##fileformat=VCFv4.3
+##contig=<ID=chr1,length=248956422>
+##contig=<ID=chr2,length=242193529>
+##contig=<ID=chr1_KI270706v1_random,length=175055>
+##contig=<ID=chrEBV,length=171823>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+chr1	100	bnd1	A	A]chr2:321681]	.	PASS	SVTYPE=BND
+chr2	321681	bnd2	G	[chr1:100[G	.	PASS	SVTYPE=BND
+chr1_KI270706v1_random	5	.	C	T	.	PASS	.
+chrEBV	10	.	G	A	.	PASS	.
+chr2	200	bnd3	T	T[<chrEBV>:7[	.	PASS	SVTYPE=BND
+`
+
+type RenameSuite struct{}
+
+var _ = Suite(&RenameSuite{})
+
+func readAll(rdr *Reader) []*Variant {
+	var vs []*Variant
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func (s *RenameSuite) TestPreset(c *C) {
+	cr, err := NewContigRenamerFromPreset(PresetGRCh38UcscToEnsembl, KeepUnmapped)
+	c.Assert(err, IsNil)
+	for in, exp := range map[string]string{"chr1": "1", "chrX": "X", "chrM": "MT",
+		"chr1_KI270706v1_random": "KI270706.1", "chrUn_gl000211": "GL000211.1"} {
+		out, ok := cr.Rename(in)
+		c.Assert(ok, Equals, true)
+		c.Assert(out, Equals, exp)
+	}
+	_, ok := cr.Rename("chrEBV")
+	c.Assert(ok, Equals, false)
+
+	cr, err = NewContigRenamerFromPreset(PresetGRCh37EnsemblToUcsc, KeepUnmapped)
+	c.Assert(err, IsNil)
+	out, ok := cr.Rename("MT")
+	c.Assert(ok, Equals, true)
+	c.Assert(out, Equals, "chrM")
+
+	_, err = NewContigRenamerFromPreset("hg18", KeepUnmapped)
+	c.Assert(err, NotNil)
+}
+
+func (s *RenameSuite) TestFromReader(c *C) {
+	cr, err := NewContigRenamerFromReader(strings.NewReader("# ucsc\tensembl\nchr1\t1\n\nchr2 2\n"), KeepUnmapped)
+	c.Assert(err, IsNil)
+	out, _ := cr.Rename("chr2")
+	c.Assert(out, Equals, "2")
+
+	_, err = NewContigRenamerFromReader(strings.NewReader("chr1\t1\textra\n"), KeepUnmapped)
+	c.Assert(err, NotNil)
+	_, err = NewContigRenamerFromReader(strings.NewReader("chr1\t1\nchr1\t01\n"), KeepUnmapped)
+	c.Assert(err, NotNil)
+}
+
+func (s *RenameSuite) TestReaderKeep(c *C) {
+	rdr, err := NewReader(strings.NewReader(renameStr), false)
+	c.Assert(err, IsNil)
+	cr, _ := NewContigRenamerFromPreset(PresetGRCh38UcscToEnsembl, KeepUnmapped)
+	c.Assert(rdr.SetContigRenamer(cr), IsNil)
+	c.Assert(rdr.Header.Contigs.Names(), DeepEquals, []string{"1", "2", "KI270706.1", "chrEBV"})
+
+	vs := readAll(rdr)
+	c.Assert(rdr.Error(), IsNil)
+	c.Assert(len(vs), Equals, 5)
+	c.Assert(vs[0].Chromosome, Equals, "1")
+	c.Assert(vs[0].Alt(), DeepEquals, []string{"A]2:321681]"})
+	c.Assert(vs[1].Alt(), DeepEquals, []string{"[1:100[G"})
+	c.Assert(vs[2].Chromosome, Equals, "KI270706.1")
+	c.Assert(vs[3].Chromosome, Equals, "chrEBV")
+	c.Assert(vs[4].Alt(), DeepEquals, []string{"T[<chrEBV>:7["})
+}
+
+func (s *RenameSuite) TestReaderDrop(c *C) {
+	rdr, err := NewReader(strings.NewReader(renameStr), false)
+	c.Assert(err, IsNil)
+	cr, _ := NewContigRenamerFromPreset(PresetGRCh38UcscToEnsembl, DropUnmapped)
+	c.Assert(rdr.SetContigRenamer(cr), IsNil)
+	c.Assert(rdr.Header.Contigs.Names(), DeepEquals, []string{"1", "2", "KI270706.1"})
+
+	vs := readAll(rdr)
+	c.Assert(len(vs), Equals, 3)
+	c.Assert(vs[2].Chromosome, Equals, "KI270706.1")
+}
+
+func (s *RenameSuite) TestReaderError(c *C) {
+	rdr, err := NewReader(strings.NewReader(renameStr), false)
+	c.Assert(err, IsNil)
+	cr := NewContigRenamer(map[string]string{"chr1": "1", "chr2": "2"}, ErrorUnmapped)
+	c.Assert(rdr.SetContigRenamer(cr), ErrorMatches, ".*not in renaming map.*")
+	c.Assert(rdr.Header.Contigs.Names()[0], Equals, "chr1")
+
+	// A record on a contig missing from the header is skipped.
+	rdr, err = NewReader(strings.NewReader(`##fileformat=VCFv4.3
+##contig=<ID=chr1,length=248956422>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+chr1	100	.	A	G	.	PASS	.
+chrEBV	10	.	G	A	.	PASS	.
+chr1	200	.	C	T	.	PASS	.
+`), false)
+	c.Assert(err, IsNil)
+	c.Assert(rdr.SetContigRenamer(cr), IsNil)
+	vs := readAll(rdr)
+	c.Assert(len(vs), Equals, 2)
+	c.Assert(vs[0].Chromosome, Equals, "1")
+	c.Assert(vs[1].Chromosome, Equals, "1")
+	c.Assert(vs[1].Pos, Equals, uint64(200))
+	c.Assert(rdr.Error(), ErrorMatches, "(?s).*not in renaming map - chrEBV.*")
+}
+
+func (s *RenameSuite) TestReaderDuplicate(c *C) {
+	// Two contigs renamed to the same name leave the Header unchanged.
+	rdr, err := NewReader(strings.NewReader(renameStr), false)
+	c.Assert(err, IsNil)
+	before, err := rdr.Header.MetaLineStrings()
+	c.Assert(err, IsNil)
+	cr := NewContigRenamer(map[string]string{"chr1": "1", "chr2": "1"}, KeepUnmapped)
+	c.Assert(rdr.SetContigRenamer(cr), NotNil)
+	after, err := rdr.Header.MetaLineStrings()
+	c.Assert(err, IsNil)
+	c.Assert(after, DeepEquals, before)
+	c.Assert(rdr.Header.Contigs.Names()[:2], DeepEquals, []string{"chr1", "chr2"})
+	v := rdr.Read()
+	c.Assert(v.Chromosome, Equals, "chr1")
+}
+
+func (s *RenameSuite) TestWriter(c *C) {
+	rdr, err := NewReader(strings.NewReader(renameStr), false)
+	c.Assert(err, IsNil)
+	cr := NewContigRenamer(map[string]string{"chr1": "1", "chr2": "2"}, DropUnmapped)
+
+	var buf bytes.Buffer
+	wtr, err := NewRenamingWriter(&buf, rdr.Header, cr)
+	c.Assert(err, IsNil)
+	for _, v := range readAll(rdr) {
+		wtr.WriteVariant(v)
+	}
+	c.Assert(wtr.Error(), IsNil)
+	c.Assert(buf.String(), Equals, `##fileformat=VCFv4.3
+##contig=<ID=1,length=248956422>
+##contig=<ID=2,length=242193529>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT
+1	100	bnd1	A	A]2:321681]	.	PASS	SVTYPE=BND
+2	321681	bnd2	G	[1:100[G	.	PASS	SVTYPE=BND
+`)
+	// The reader's header is left untouched.
+	c.Assert(rdr.Header.Contigs.Names()[0], Equals, "chr1")
+	c.Assert(rdr.Header.Lines[0].GetValue("ID"), Equals, "chr1")
+}
@@ -0,0 +1,500 @@
+package vcfgo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ##SAMPLE and ##PEDIGREE lines describe the samples in a VCF and how
+// they relate to each other. A ##SAMPLE line describes the genomes that
+// make up a sample (section 1.4.8 of the VCFv4.3 spec), e.g.:
+//
+//  ##SAMPLE=<ID=Tumour,Genomes=Germline;Tumor,Mixture=.3;.7,Description="Germline;Tumour">
+//
+// A ##PEDIGREE line either relates a genome to its parents or relates a
+// derived genome (such as a tumour) to the original genome it came
+// from, e.g.:
+//
+//  ##PEDIGREE=<ID=ChildID,Father=FatherID,Mother=MotherID>
+//  ##PEDIGREE=<ID=TumourID,Original=GermlineID>
+//
+// VCFv4.1 used Child= and Derived= instead of ID= and both forms are
+// understood. Pedigrees can also be loaded from PLINK .ped/.fam files.
+
+var ErrSampleNotFound = errors.New("vcfgo: sample not found")
+
+// SampleMeta holds a parsed ##SAMPLE line. Genomes, Mixture and
+// Description are the semicolon-separated lists from the line. Any other
+// keys are kept in Extra in the order in which they were observed.
+type SampleMeta struct {
+	ID          string
+	Genomes     []string
+	Mixture     []float64
+	Description []string
+	Extra       []*KV
+}
+
+// NewSampleMetaFromMetaLine creates a SampleMeta from a ##SAMPLE MetaLine.
+func NewSampleMetaFromMetaLine(m *MetaLine) (*SampleMeta, error) {
+	if m.MetaType != Structured || m.LineKey != `SAMPLE` {
+		return nil, fmt.Errorf("%w - not a SAMPLE line: %s", ErrLinePattern, m.OgString)
+	}
+	s := &SampleMeta{}
+	for _, k := range m.Order {
+		kv := m.KVs[k]
+		switch k {
+		case `ID`:
+			s.ID = kv.Value
+		case `Genomes`:
+			s.Genomes = strings.Split(kv.Value, `;`)
+		case `Mixture`:
+			for _, v := range strings.Split(kv.Value, `;`) {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil, fmt.Errorf("bad Mixture for sample %s: %w", m.GetValue(`ID`), err)
+				}
+				s.Mixture = append(s.Mixture, f)
+			}
+		case `Description`:
+			s.Description = strings.Split(kv.Value, `;`)
+		default:
+			s.Extra = append(s.Extra, &KV{Key: kv.Key, Value: kv.Value, Quote: kv.Quote})
+		}
+	}
+	if s.ID == `` {
+		return nil, fmt.Errorf("SAMPLE line has no ID: %s", m.OgString)
+	}
+	if len(s.Mixture) > 0 && len(s.Genomes) != len(s.Mixture) {
+		return nil, fmt.Errorf("sample %s has %d Genomes but %d Mixture values", s.ID, len(s.Genomes), len(s.Mixture))
+	}
+	return s, nil
+}
+
+// MetaLine returns a ##SAMPLE MetaLine for the SampleMeta.
+func (s *SampleMeta) MetaLine() *MetaLine {
+	m := NewMetaLine()
+	m.LineKey = `SAMPLE`
+	m.AddKV(`ID`, s.ID, 0)
+	if len(s.Genomes) > 0 {
+		m.AddKV(`Genomes`, strings.Join(s.Genomes, `;`), 0)
+	}
+	if len(s.Mixture) > 0 {
+		vals := make([]string, len(s.Mixture))
+		for i, f := range s.Mixture {
+			vals[i] = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		m.AddKV(`Mixture`, strings.Join(vals, `;`), 0)
+	}
+	if len(s.Description) > 0 {
+		m.AddKV(`Description`, strings.Join(s.Description, `;`), '"')
+	}
+	for _, kv := range s.Extra {
+		m.AddKV(kv.Key, kv.Value, kv.Quote)
+	}
+	return m
+}
+
+// PedigreeEntry holds a single pedigree relationship, either from a
+// ##PEDIGREE line or from a line of a PLINK .ped/.fam file. Father,
+// Mother and Original are empty if not known. Family, Sex and Phenotype
+// are only set for PLINK files and are not written to the VCF header.
+// Any other keys (such as the Name_N ancestor keys) are kept in Extra.
+type PedigreeEntry struct {
+	ID        string
+	Father    string
+	Mother    string
+	Original  string
+	Family    string
+	Sex       string
+	Phenotype string
+	Extra     []*KV
+}
+
+// NewPedigreeEntryFromMetaLine creates a PedigreeEntry from a ##PEDIGREE
+// MetaLine. Lines without an ID (or Child or Derived) key are allowed
+// but such entries cannot be placed in the pedigree graph.
+func NewPedigreeEntryFromMetaLine(m *MetaLine) (*PedigreeEntry, error) {
+	if m.MetaType != Structured || m.LineKey != `PEDIGREE` {
+		return nil, fmt.Errorf("%w - not a PEDIGREE line: %s", ErrLinePattern, m.OgString)
+	}
+	p := &PedigreeEntry{}
+	for _, k := range m.Order {
+		kv := m.KVs[k]
+		switch k {
+		case `ID`, `Child`, `Derived`:
+			p.ID = kv.Value
+		case `Father`:
+			p.Father = kv.Value
+		case `Mother`:
+			p.Mother = kv.Value
+		case `Original`:
+			p.Original = kv.Value
+		default:
+			p.Extra = append(p.Extra, &KV{Key: kv.Key, Value: kv.Value, Quote: kv.Quote})
+		}
+	}
+	return p, nil
+}
+
+// MetaLine returns a ##PEDIGREE MetaLine for the PedigreeEntry using the
+// VCFv4.3 ID= form.
+func (p *PedigreeEntry) MetaLine() *MetaLine {
+	m := NewMetaLine()
+	m.LineKey = `PEDIGREE`
+	if p.ID != `` {
+		m.AddKV(`ID`, p.ID, 0)
+	}
+	if p.Father != `` {
+		m.AddKV(`Father`, p.Father, 0)
+	}
+	if p.Mother != `` {
+		m.AddKV(`Mother`, p.Mother, 0)
+	}
+	if p.Original != `` {
+		m.AddKV(`Original`, p.Original, 0)
+	}
+	for _, kv := range p.Extra {
+		m.AddKV(kv.Key, kv.Value, kv.Quote)
+	}
+	return m
+}
+
+// PedigreeNode is a genome in the pedigree graph.
+type PedigreeNode struct {
+	ID       string
+	Father   *PedigreeNode
+	Mother   *PedigreeNode
+	Children []*PedigreeNode
+	Original *PedigreeNode
+	Derived  []*PedigreeNode
+}
+
+// Pedigree holds the pedigree entries in the order in which they were
+// observed plus the graph of relationships between genomes.
+type Pedigree struct {
+	Entries []*PedigreeEntry
+	nodes   map[string]*PedigreeNode
+	order   []string
+	entries map[string]bool
+}
+
+// NewPedigree returns an empty *Pedigree.
+func NewPedigree() *Pedigree {
+	return &Pedigree{Entries: make([]*PedigreeEntry, 0),
+		nodes:   make(map[string]*PedigreeNode),
+		entries: make(map[string]bool)}
+}
+
+// NewPedigreeFromHeader builds a Pedigree from the ##PEDIGREE lines in
+// Header.Lines.
+func NewPedigreeFromHeader(h *Header) (*Pedigree, error) {
+	p := NewPedigree()
+	for _, m := range h.Lines {
+		if m.MetaType != Structured || m.LineKey != `PEDIGREE` {
+			continue
+		}
+		e, err := NewPedigreeEntryFromMetaLine(m)
+		if err != nil {
+			return p, err
+		}
+		if err = p.Add(e); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// NewPedigreeFromPlink builds a Pedigree from a PLINK .ped or .fam file.
+// Only the first 6 columns (family, individual, father, mother, sex and
+// phenotype) are used and a parent of 0 is treated as unknown.
+func NewPedigreeFromPlink(r io.Reader) (*Pedigree, error) {
+	p := NewPedigree()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == `` || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 6 {
+			return p, fmt.Errorf("plink error at line %d: expected at least 6 columns but found %d", n, len(f))
+		}
+		e := &PedigreeEntry{Family: f[0], ID: f[1], Sex: f[4], Phenotype: f[5]}
+		if f[2] != `0` {
+			e.Father = f[2]
+		}
+		if f[3] != `0` {
+			e.Mother = f[3]
+		}
+		if err := p.Add(e); err != nil {
+			return p, fmt.Errorf("plink error at line %d: %w", n, err)
+		}
+	}
+	return p, scanner.Err()
+}
+
+// Add appends a PedigreeEntry and links it into the graph. An ID may
+// only have one entry.
+func (p *Pedigree) Add(e *PedigreeEntry) error {
+	if e.ID != `` {
+		if p.entries[e.ID] {
+			return fmt.Errorf("pedigree has more than one entry for %s", e.ID)
+		}
+		p.entries[e.ID] = true
+		n := p.node(e.ID)
+		if e.Father != `` {
+			n.Father = p.node(e.Father)
+			n.Father.Children = append(n.Father.Children, n)
+		}
+		if e.Mother != `` {
+			n.Mother = p.node(e.Mother)
+			n.Mother.Children = append(n.Mother.Children, n)
+		}
+		if e.Original != `` {
+			n.Original = p.node(e.Original)
+			n.Original.Derived = append(n.Original.Derived, n)
+		}
+	}
+	p.Entries = append(p.Entries, e)
+	return nil
+}
+
+func (p *Pedigree) node(id string) *PedigreeNode {
+	if n, found := p.nodes[id]; found {
+		return n
+	}
+	n := &PedigreeNode{ID: id}
+	p.nodes[id] = n
+	p.order = append(p.order, id)
+	return n
+}
+
+// Node returns the PedigreeNode for a genome.
+func (p *Pedigree) Node(id string) (*PedigreeNode, bool) {
+	n, found := p.nodes[id]
+	return n, found
+}
+
+// IDs returns every genome in the graph in the order in which it was
+// first seen.
+func (p *Pedigree) IDs() []string {
+	ids := make([]string, len(p.order))
+	copy(ids, p.order)
+	return ids
+}
+
+// Parents returns the IDs of the father and mother of a genome. Either
+// may be empty if not known.
+func (p *Pedigree) Parents(id string) (string, string, error) {
+	n, found := p.nodes[id]
+	if !found {
+		return ``, ``, fmt.Errorf("%w - %s", ErrSampleNotFound, id)
+	}
+	var father, mother string
+	if n.Father != nil {
+		father = n.Father.ID
+	}
+	if n.Mother != nil {
+		mother = n.Mother.ID
+	}
+	return father, mother, nil
+}
+
+// Children returns the IDs of the children of a genome.
+func (p *Pedigree) Children(id string) ([]string, error) {
+	n, found := p.nodes[id]
+	if !found {
+		return nil, fmt.Errorf("%w - %s", ErrSampleNotFound, id)
+	}
+	return nodeIDs(n.Children), nil
+}
+
+// Derived returns the IDs of the genomes derived from a genome, e.g. the
+// tumours derived from a germline genome.
+func (p *Pedigree) Derived(id string) ([]string, error) {
+	n, found := p.nodes[id]
+	if !found {
+		return nil, fmt.Errorf("%w - %s", ErrSampleNotFound, id)
+	}
+	return nodeIDs(n.Derived), nil
+}
+
+// Original returns the ID of the genome that a genome was derived from
+// following Original links to the root, so the original of a metastasis
+// derived from a primary tumour is the germline genome. The ID itself is
+// returned if it was not derived from anything.
+func (p *Pedigree) Original(id string) (string, error) {
+	n, found := p.nodes[id]
+	if !found {
+		return ``, fmt.Errorf("%w - %s", ErrSampleNotFound, id)
+	}
+	seen := map[*PedigreeNode]bool{n: true}
+	for n.Original != nil {
+		n = n.Original
+		if seen[n] {
+			return ``, fmt.Errorf("pedigree has a cycle of Original links at %s", n.ID)
+		}
+		seen[n] = true
+	}
+	return n.ID, nil
+}
+
+// Founders returns the IDs of the genomes with no known parents and
+// that are not derived from another genome.
+func (p *Pedigree) Founders() []string {
+	var ids []string
+	for _, id := range p.order {
+		n := p.nodes[id]
+		if n.Father == nil && n.Mother == nil && n.Original == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func nodeIDs(nodes []*PedigreeNode) []string {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+// parseSampleMetadata fills Header.Samples and Header.Pedigree from
+// Header.Lines.
+func (h *Header) parseSampleMetadata() []error {
+	var errs []error
+	h.Samples = make(map[string]*SampleMeta)
+	for _, m := range h.Lines {
+		if m.MetaType != Structured || m.LineKey != `SAMPLE` {
+			continue
+		}
+		s, err := NewSampleMetaFromMetaLine(m)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, found := h.Samples[s.ID]; found {
+			errs = append(errs, fmt.Errorf("duplicate SAMPLE line for %s", s.ID))
+			continue
+		}
+		h.Samples[s.ID] = s
+	}
+	p, err := NewPedigreeFromHeader(h)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	h.Pedigree = p
+	h.Pedigrees = h.pedigreeLines()
+	return errs
+}
+
+// pedigreeLines returns the ##PEDIGREE lines in Lines as strings for the
+// deprecated Pedigrees.
+func (h *Header) pedigreeLines() []string {
+	var lines []string
+	for _, m := range h.Lines {
+		if m.LineKey != `PEDIGREE` {
+			continue
+		}
+		if s, err := m.String(); err == nil {
+			lines = append(lines, s)
+		}
+	}
+	return lines
+}
+
+// SetSamples replaces the ##SAMPLE lines in Header.Lines with lines
+// generated from the supplied SampleMetas (in ID order) and makes them
+// the Header's Samples. The new lines are placed where the first existing
+// ##SAMPLE line was or, if there were none, at the end of Lines.
+func (h *Header) SetSamples(samples map[string]*SampleMeta) {
+	ids := make([]string, 0, len(samples))
+	for id := range samples {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	lines := make([]*MetaLine, len(ids))
+	for i, id := range ids {
+		lines[i] = samples[id].MetaLine()
+	}
+	h.replaceLines(`SAMPLE`, lines)
+	h.Samples = samples
+}
+
+// SetPedigree replaces the ##PEDIGREE lines in Header.Lines with lines
+// generated from the Pedigree entries and makes it the Header's
+// Pedigree.
+func (h *Header) SetPedigree(p *Pedigree) {
+	lines := make([]*MetaLine, len(p.Entries))
+	for i, e := range p.Entries {
+		lines[i] = e.MetaLine()
+	}
+	h.replaceLines(`PEDIGREE`, lines)
+	h.Pedigree = p
+	h.Pedigrees = h.pedigreeLines()
+}
+
+// replaceLines swaps all Lines of type t for the supplied lines which are
+// placed where the first line of type t was or, if there were none, at
+// the end of Lines.
+func (h *Header) replaceLines(t string, add []*MetaLine) {
+	pos := -1
+	lines := make([]*MetaLine, 0, len(h.Lines)+len(add))
+	for _, m := range h.Lines {
+		if m.LineKey == t {
+			if pos == -1 {
+				pos = len(lines)
+			}
+			continue
+		}
+		lines = append(lines, m)
+	}
+	if pos == -1 {
+		pos = len(lines)
+	}
+	h.Lines = append(lines[:pos], append(add, lines[pos:]...)...)
+}
+
+// ValidateSamples cross-checks the ##SAMPLE and ##PEDIGREE metadata
+// against Header.SampleNames. It reports ##SAMPLE lines for samples that
+// are not in the #CHROM line and pedigree genomes that are neither
+// sample columns nor described by a ##SAMPLE line.
+func (h *Header) ValidateSamples() []error {
+	var errs []error
+	columns := make(map[string]bool, len(h.SampleNames))
+	for _, s := range h.SampleNames {
+		columns[s] = true
+	}
+	known := make(map[string]bool)
+	ids := make([]string, 0, len(h.Samples))
+	for id, s := range h.Samples {
+		ids = append(ids, id)
+		known[id] = true
+		for _, g := range s.Genomes {
+			known[g] = true
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if !columns[id] {
+			errs = append(errs, fmt.Errorf("%w - SAMPLE %s is not a sample column", ErrSampleNotFound, id))
+		}
+	}
+	if h.Pedigree != nil {
+		for _, id := range h.Pedigree.order {
+			if !columns[id] && !known[id] {
+				errs = append(errs, fmt.Errorf("%w - PEDIGREE genome %s is not a sample column or SAMPLE genome", ErrSampleNotFound, id))
+			}
+		}
+	}
+	return errs
+}
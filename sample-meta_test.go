@@ -0,0 +1,147 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var pedigreeStr = `##fileformat=VCFv4.3
+##SAMPLE=<ID=Mum,Genomes=Germline,Mixture=1,Description="Mother">
+##SAMPLE=<ID=Kid,Genomes=Germline;Tumor,Mixture=.3;.7,Description="Germline;Tumour",Assay=WGS>
+##PEDIGREE=<ID=Kid,Father=Dad,Mother=Mum>
+##PEDIGREE=<ID=KidTumour,Original=Kid>
+##PEDIGREE=<Derived=KidMet,Original=KidTumour>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	Dad	Mum	Kid
+`
+
+var famStr = `FAM1	dad	0	0	1	1
+FAM1	mum	0	0	2	1
+FAM1	kid1	dad	mum	1	2
+FAM1	kid2	dad	mum	2	1
+`
+
+type SampleMetaSuite struct{}
+
+var _ = Suite(&SampleMetaSuite{})
+
+func (s *SampleMetaSuite) TestSampleMeta(c *C) {
+	rdr, err := NewReader(strings.NewReader(pedigreeStr), false)
+	c.Assert(err, IsNil)
+	kid := rdr.Header.Samples["Kid"]
+	c.Assert(kid.Genomes, DeepEquals, []string{"Germline", "Tumor"})
+	c.Assert(kid.Mixture, DeepEquals, []float64{0.3, 0.7})
+	c.Assert(kid.Description, DeepEquals, []string{"Germline", "Tumour"})
+	c.Assert(kid.Extra, DeepEquals, []*KV{{Key: "Assay", Value: "WGS"}})
+
+	str, _ := kid.MetaLine().String()
+	c.Assert(str, Equals, `##SAMPLE=<ID=Kid,Genomes=Germline;Tumor,Mixture=0.3;0.7,Description="Germline;Tumour",Assay=WGS>`)
+
+	m, _ := NewMetaLineFromString(`##SAMPLE=<ID=X,Genomes=A;B,Mixture=1>`)
+	_, err = NewSampleMetaFromMetaLine(m)
+	c.Assert(err, ErrorMatches, ".*2 Genomes but 1 Mixture.*")
+}
+
+func (s *SampleMetaSuite) TestPedigreeGraph(c *C) {
+	rdr, err := NewReader(strings.NewReader(pedigreeStr), false)
+	c.Assert(err, IsNil)
+	p := rdr.Header.Pedigree
+	c.Assert(len(p.Entries), Equals, 3)
+	c.Assert(rdr.Header.Pedigrees, DeepEquals, []string{
+		`##PEDIGREE=<ID=Kid,Father=Dad,Mother=Mum>`,
+		`##PEDIGREE=<ID=KidTumour,Original=Kid>`,
+		`##PEDIGREE=<Derived=KidMet,Original=KidTumour>`,
+	})
+
+	f, m, err := p.Parents("Kid")
+	c.Assert(err, IsNil)
+	c.Assert(f, Equals, "Dad")
+	c.Assert(m, Equals, "Mum")
+
+	kids, err := p.Children("Mum")
+	c.Assert(err, IsNil)
+	c.Assert(kids, DeepEquals, []string{"Kid"})
+
+	derived, err := p.Derived("Kid")
+	c.Assert(err, IsNil)
+	c.Assert(derived, DeepEquals, []string{"KidTumour"})
+
+	og, err := p.Original("KidMet")
+	c.Assert(err, IsNil)
+	c.Assert(og, Equals, "Kid")
+
+	c.Assert(p.Founders(), DeepEquals, []string{"Dad", "Mum"})
+
+	_, _, err = p.Parents("Nobody")
+	c.Assert(err, ErrorMatches, ".*sample not found.*")
+}
+
+func (s *SampleMetaSuite) TestPlink(c *C) {
+	p, err := NewPedigreeFromPlink(strings.NewReader(famStr))
+	c.Assert(err, IsNil)
+	kids, _ := p.Children("dad")
+	c.Assert(kids, DeepEquals, []string{"kid1", "kid2"})
+	c.Assert(p.Entries[2].Family, Equals, "FAM1")
+	c.Assert(p.Entries[2].Phenotype, Equals, "2")
+
+	str, _ := p.Entries[2].MetaLine().String()
+	c.Assert(str, Equals, `##PEDIGREE=<ID=kid1,Father=dad,Mother=mum>`)
+
+	_, err = NewPedigreeFromPlink(strings.NewReader("FAM1\tdad\t0\t0\n"))
+	c.Assert(err, NotNil)
+	_, err = NewPedigreeFromPlink(strings.NewReader(famStr + famStr))
+	c.Assert(err, ErrorMatches, ".*more than one entry for dad")
+}
+
+func (s *SampleMetaSuite) TestValidate(c *C) {
+	rdr, err := NewReader(strings.NewReader(pedigreeStr), false)
+	c.Assert(err, IsNil)
+	errs := rdr.Header.ValidateSamples()
+	c.Assert(len(errs), Equals, 2)
+	c.Assert(errs[0], ErrorMatches, ".*KidTumour.*")
+	c.Assert(errs[1], ErrorMatches, ".*KidMet.*")
+
+	rdr.Header.SampleNames = []string{"Dad", "Kid"}
+	errs = rdr.Header.ValidateSamples()
+	c.Assert(errs[0], ErrorMatches, ".*SAMPLE Mum is not a sample column")
+}
+
+func (s *SampleMetaSuite) TestSetPedigree(c *C) {
+	rdr, err := NewReader(strings.NewReader(pedigreeStr), false)
+	c.Assert(err, IsNil)
+	p, _ := NewPedigreeFromPlink(strings.NewReader(famStr))
+	rdr.Header.SetPedigree(p)
+	c.Assert(len(rdr.Header.Pedigrees), Equals, 4)
+	c.Assert(rdr.Header.Pedigrees[0], Equals, `##PEDIGREE=<ID=dad>`)
+	delete(rdr.Header.Samples, "Kid")
+	rdr.Header.SetSamples(rdr.Header.Samples)
+
+	var buf bytes.Buffer
+	_, err = NewWriter(&buf, rdr.Header)
+	c.Assert(err, IsNil)
+	c.Assert(buf.String(), Equals, `##fileformat=VCFv4.3
+##SAMPLE=<ID=Mum,Genomes=Germline,Mixture=1,Description="Mother">
+##PEDIGREE=<ID=dad>
+##PEDIGREE=<ID=mum>
+##PEDIGREE=<ID=kid1,Father=dad,Mother=mum>
+##PEDIGREE=<ID=kid2,Father=dad,Mother=mum>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	Dad	Mum	Kid
+`)
+}
+
+func (s *SampleMetaSuite) TestPedigreeRoundTrip(c *C) {
+	// Lines with keys out of order or with Child= are written once.
+	in := `##fileformat=VCFv4.2
+##PEDIGREE=<ID=C,Mother=M,Father=F>
+##PEDIGREE=<Child=D,Mother=M>
+##PEDIGREE=<Derived=T,Original=C>
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+`
+	rdr, err := NewReader(strings.NewReader(in), false)
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	_, err = NewWriter(&buf, rdr.Header)
+	c.Assert(err, IsNil)
+	c.Assert(buf.String(), Equals, strings.Replace(in, "INFO\n", "INFO\tFORMAT\n", 1))
+}
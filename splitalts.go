@@ -3,117 +3,99 @@ package vcfgo
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
-func SplitAlts(v *Variant) []*Variant {
-	vars := make([]*Variant, len(v.Alt()))
-	for i := range v.Alt() {
-		vars[i] = &Variant{Chromosome: v.Chromosome, Pos: v.Pos, Id_: v.Id_,
-			Reference: v.Ref(), Alternate: []string{v.Alt()[i]}, Quality: v.Quality, Filter: v.Filter,
-			Info_: v.Info_, Samples: v.Samples, sampleString: v.sampleString,
-			Header: v.Header, LineNumber: v.LineNumber}
+// OldMultiallelic is the INFO field that SplitAlts adds to each record
+// split from a multiallelic record. It holds CHROM:POS:REF/ALT1/ALT2...
+// of the original record.
+const OldMultiallelic = `OLD_MULTIALLELIC`
 
-		split(vars[i], i, len(v.Alt()))
-	}
+// SplitAlts splits a multiallelic Variant into one biallelic Variant for
+// each ALT allele. Errors are ignored and fields with the wrong number
+// of values are left as they are; use Split to see the errors.
+func SplitAlts(v *Variant) []*Variant {
+	vars, _ := v.Split()
 	return vars
 }
 
-func split(v *Variant, i int, nAlts int) error {
-	// extract the appropriate
-	var err error
-	for _, k := range v.Info_.Keys() {
-		h := v.Header.Infos[k]
-		switch h.Number {
-		case "A":
-			var s interface{}
-			s, err = splitA(v, i, nAlts)
-			v.Info_.Set(k, s)
-		case "G":
-			var s []interface{}
-			var val interface{}
-			val, err = v.Info_.Get(k)
-			s, err = splitG(val, i, nAlts)
-			v.Info_.Set(k, s)
-		case "R":
-			var s []interface{}
-			var val interface{}
-			val, err = v.Info_.Get(k)
-			s, err = splitR(val, i, nAlts)
-			v.Info_.Set(k, s)
-		}
+// Split splits a multiallelic Variant into one biallelic Variant for each
+// ALT allele. Each new Variant is a copy that shares only the Header
+// with v. Number=A, R and G values of INFO and FORMAT fields are subset
+// to the REF and the ALT allele. GT is recoded so that the ALT allele is
+// 1 and other ALT alleles are missing, and PL is rescaled so that the
+// most likely genotype is 0. If v has more than one ALT allele, the
+// OLD_MULTIALLELIC INFO field is set on each new Variant and added to
+// the Header. Lazily parsed samples are parsed first. Fields with the
+// wrong number of values are left as they are and the first such error
+// is returned with the new Variants.
+func (v *Variant) Split() ([]*Variant, error) {
+	var first error
+	if v.Header != nil {
+		first = v.Header.ParseSamples(v)
+	}
+	nAlts := len(v.Alternate)
+	var old string
+	if nAlts > 1 && v.Header != nil {
+		v.Header.AddInfoLine(OldMultiallelic, `1`, `String`,
+			`Original CHROM:POS:REF/ALT of a split multiallelic record`)
+		old = fmt.Sprintf("%s:%d:%s/%s", v.Chromosome, v.Pos, v.Reference, strings.Join(v.Alternate, `/`))
 	}
-	v.Header.ParseSamples(v)
-	for _, h := range v.Header.SampleFormats {
-		if h.Number != "A" && h.Number != "G" && h.Number != "R" {
+	vars := make([]*Variant, nAlts)
+	for i, alt := range v.Alternate {
+		s := v.Clone()
+		s.Alternate = []string{alt}
+		vars[i] = s
+		if nAlts == 1 || s.Header == nil {
 			continue
 		}
-		/*
-			for j, samp := range v.Samples {
-				switch h.Number {
-				case "A":
-					// TODO: should this still be a list?
-					var s interface{}
-					s, err = splitA(samp.Fields[k], i, nAlts)
-					//samp.Fields[k] = s
-
-				case "G":
-
-				case "R":
-				}
+		m := make([]int, nAlts+1)
+		for a := range m {
+			m[a] = -1
+		}
+		m[0], m[i+1] = 0, 1
+		if err := s.remapAlleles(m, 2); err != nil && first == nil {
+			first = err
+		}
+		for _, g := range s.Samples {
+			if pl, found := g.Fields[`PL`]; found {
+				g.Fields[`PL`] = rescalePL(pl)
+				s.Header.setSampleGL(g, g.Fields[`PL`], true)
 			}
-		*/
-	}
-	// TODO: v.Samples
-	return err
-}
-
-func splitG(m interface{}, i int, nAlts int) ([]interface{}, error) {
-	pairs := [][]int{{0, 0}, {0, i}, {i, i}}
-	G := make([]interface{}, 3)
-	for o, jk := range pairs {
-		order := (jk[1] * (jk[1] + 1) / 2) + jk[0]
-		G[o] = m.([]interface{})[order]
-	}
-	return G, nil
-}
-
-func splitGT(m interface{}, i int) ([]interface{}, error) {
-	ml := m.([]interface{})
-	out := make([]interface{}, len(ml))
-	ai := strconv.Itoa(i)
-	for i, allelei := range ml {
-		allele := allelei.(string)
-		if allele == "0" {
-			out[i] = "0"
 		}
-		if ai == allele {
-			out[i] = "1"
-		} else {
-			out[i] = "."
+		if err := s.Info().Set(OldMultiallelic, old); err != nil && first == nil {
+			first = err
 		}
 	}
-	return out, nil
+	return vars, first
 }
 
-func splitR(m interface{}, i int, nAlts int) ([]interface{}, error) {
-	ml := m.([]interface{})
-	if len(ml) != nAlts+1 {
-		return nil, fmt.Errorf("incorrect number of alts in splitR: %v", m)
+// rescalePL subtracts the smallest value from each value of PL. Missing
+// values are kept and PL is returned unchanged if a value is not an
+// integer.
+func rescalePL(pl string) string {
+	vals := splitValues(pl)
+	min := -1
+	for _, s := range vals {
+		if s == `.` {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return pl
+		}
+		if min == -1 || n < min {
+			min = n
+		}
 	}
-	if i+1 >= len(ml) {
-		return nil, fmt.Errorf("requested bad alt in splitR: %v", m)
+	if min <= 0 {
+		return pl
 	}
-	out := make([]interface{}, 2)
-	out[0] = ml[0]
-	out[1] = ml[i+1]
-	return out, nil
-}
-
-// when we split on alt, it returns a single int since we are decomposing.
-func splitA(m interface{}, i int, nAlts int) (interface{}, error) {
-	ml := m.([]interface{})
-	if len(ml) != nAlts {
-		return nil, fmt.Errorf("incorrect number of alts in splitA: %v", m)
+	for i, s := range vals {
+		if s != `.` {
+			n, _ := strconv.Atoi(s)
+			vals[i] = strconv.Itoa(n - min)
+		}
 	}
-	return ml[i], nil
+	return strings.Join(vals, `,`)
 }
@@ -1,6 +1,10 @@
 package vcfgo
 
-import . "gopkg.in/check.v1"
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
 
 type SplitAltSuite struct {
 }
@@ -10,72 +14,105 @@ var _ = Suite(&SplitAltSuite{})
 func (s *SplitAltSuite) SetUpTest(c *C) {
 }
 
-func (s *SplitAltSuite) TestSplitA(c *C) {
-
-	in := []interface{}{"AA", "BB", "CC"}
-	out, err := splitA(in, 0, 3)
-	c.Assert(err, IsNil)
-	c.Assert(out, Equals, "AA")
-
-	out, err = splitA(in, 0, 4)
-	c.Assert(err, Not(IsNil))
-
-	out, err = splitA(in, 2, 3)
+var splitFreebayes = `##fileformat=VCFv4.2
+##INFO=<ID=AC,Number=A,Type=Integer,Description="Total number of alternate alleles in called genotypes">
+##INFO=<ID=AF,Number=A,Type=Float,Description="Estimated allele frequency in the range (0,1]">
+##INFO=<ID=AN,Number=1,Type=Integer,Description="Total number of alleles in called genotypes">
+##INFO=<ID=AO,Number=A,Type=Integer,Description="Alternate allele observations">
+##INFO=<ID=CIGAR,Number=A,Type=String,Description="The extended CIGAR representation of each alternate allele">
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Total read depth at the locus">
+##INFO=<ID=RO,Number=1,Type=Integer,Description="Reference allele observation count">
+##INFO=<ID=TYPE,Number=A,Type=String,Description="The type of allele">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Read Depth">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Number of observation for each allele">
+##FORMAT=<ID=RO,Number=1,Type=Integer,Description="Reference allele observation count">
+##FORMAT=<ID=AO,Number=A,Type=Integer,Description="Alternate allele observation count">
+##FORMAT=<ID=GL,Number=G,Type=Float,Description="Genotype Likelihood">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	s1	s2
+chr20	1000	.	T	C,G	50	.	AC=1,1;AF=0.25,0.25;AN=4;AO=5,3;CIGAR=1X,1X;DP=18;RO=10;TYPE=snp,snp	GT:DP:AD:RO:AO:GL	1/2:8:0,5,3:0:5,3:-30,-10,-20,-12,-1,-25	0/0:10:10,0,0:10:0,0:0,-3,-30,-3,-30,-30
+`
+
+var splitGATK = `##fileformat=VCFv4.2
+##INFO=<ID=AC,Number=A,Type=Integer,Description="Allele count in genotypes">
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele Frequency">
+##INFO=<ID=AN,Number=1,Type=Integer,Description="Total number of alleles in called genotypes">
+##INFO=<ID=DP,Number=1,Type=Integer,Description="Approximate read depth">
+##INFO=<ID=MLEAC,Number=A,Type=Integer,Description="Maximum likelihood expectation for the allele counts">
+##INFO=<ID=MLEAF,Number=A,Type=Float,Description="Maximum likelihood expectation for the allele frequency">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=AD,Number=R,Type=Integer,Description="Allelic depths">
+##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Approximate read depth">
+##FORMAT=<ID=GQ,Number=1,Type=Integer,Description="Genotype Quality">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled genotype likelihoods">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	NA12878
+chr20	2000	rs1	A	AT,ATT	300	PASS	AC=1,1;AF=0.5,0.5;AN=2;DP=20;MLEAC=1,1;MLEAF=0.5,0.5	GT:AD:DP:GQ:PL	1/2:0,8,9:17:99:600,250,230,260,0,240
+chr20	2100	rs2	G	C	50	PASS	AC=1;AF=0.5;AN=2;DP=10;MLEAC=1;MLEAF=0.5	GT:AD:DP:GQ:PL	0/1:5,5:10:99:100,0,100
+`
+
+func splitStrings(c *C, text string) ([]string, *Header) {
+	rdr, err := NewReader(strings.NewReader(text), true)
 	c.Assert(err, IsNil)
-	c.Assert(out, Equals, "CC")
-
-	in2 := []interface{}{}
-	out, err = splitA(in2, 0, 3)
-	c.Assert(err, Not(IsNil))
-
+	var out []string
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		vars, err := v.Split()
+		c.Assert(err, IsNil)
+		for _, s := range vars {
+			out = append(out, s.String())
+		}
+	}
+	c.Assert(rdr.Error(), IsNil)
+	return out, rdr.Header
 }
 
-func (s *SplitAltSuite) TestSplitR(c *C) {
-
-	in := []interface{}{"ref", "alt1", "alt2"}
-	out, err := splitR(in, 0, 3)
-	c.Assert(err, Not(IsNil))
-	c.Assert(out, IsNil)
-
-	out, err = splitR(in, 0, 2)
-	c.Assert(err, IsNil)
-	c.Assert(out, DeepEquals, []interface{}{"ref", "alt1"})
-
-	out, err = splitR(in, 1, 2)
-	c.Assert(err, IsNil)
-	c.Assert(out, DeepEquals, []interface{}{"ref", "alt2"})
-
-	out, err = splitR(in, 2, 2)
-	c.Assert(err, Not(IsNil))
+func (s *SplitAltSuite) TestSplitFreebayes(c *C) {
+	out, h := splitStrings(c, splitFreebayes)
+	c.Assert(out, DeepEquals, []string{
+		"chr20\t1000\t.\tT\tC\t50.0\t.\tAC=1;AF=0.25;AN=4;AO=5;CIGAR=1X;DP=18;RO=10;TYPE=snp;OLD_MULTIALLELIC=chr20:1000:T/C/G" +
+			"\tGT:DP:AD:RO:AO:GL\t1/.:8:0,5:0:5:-30,-10,-20\t0/0:10:10,0:10:0:0,-3,-30",
+		"chr20\t1000\t.\tT\tG\t50.0\t.\tAC=1;AF=0.25;AN=4;AO=3;CIGAR=1X;DP=18;RO=10;TYPE=snp;OLD_MULTIALLELIC=chr20:1000:T/C/G" +
+			"\tGT:DP:AD:RO:AO:GL\t./1:8:0,3:0:3:-30,-12,-25\t0/0:10:10,0:10:0:0,-3,-30",
+	})
+	c.Assert(h.Infos[OldMultiallelic].Number, Equals, "1")
 }
 
-func (s *SplitAltSuite) TestSplitG(c *C) {
-
-	in := []interface{}{281, 5, 9, 58, 0, 115, 338, 46, 116, 809}
-	out, err := splitG(in, 1, 3)
-	c.Assert(err, IsNil)
-	c.Assert(out, DeepEquals, []interface{}{281, 5, 9})
-
-	out, err = splitG(in, 2, 3)
-	c.Assert(err, IsNil)
-	c.Assert(out, DeepEquals, []interface{}{281, 58, 115})
-
-	in = []interface{}{0, 30, 323, 31, 365, 483, 38, 291, 325, 567}
-	out, err = splitG(in, 3, 3)
-	c.Assert(err, IsNil)
-	c.Assert(out, DeepEquals, []interface{}{0, 38, 567})
-
+func (s *SplitAltSuite) TestSplitGATK(c *C) {
+	out, _ := splitStrings(c, splitGATK)
+	c.Assert(out, DeepEquals, []string{
+		"chr20\t2000\trs1\tA\tAT\t300.0\tPASS\tAC=1;AF=0.5;AN=2;DP=20;MLEAC=1;MLEAF=0.5;OLD_MULTIALLELIC=chr20:2000:A/AT/ATT" +
+			"\tGT:AD:DP:GQ:PL\t1/.:0,8:17:99:370,20,0",
+		"chr20\t2000\trs1\tA\tATT\t300.0\tPASS\tAC=1;AF=0.5;AN=2;DP=20;MLEAC=1;MLEAF=0.5;OLD_MULTIALLELIC=chr20:2000:A/AT/ATT" +
+			"\tGT:AD:DP:GQ:PL\t./1:0,9:17:99:360,20,0",
+		"chr20\t2100\trs2\tG\tC\t50.0\tPASS\tAC=1;AF=0.5;AN=2;DP=10;MLEAC=1;MLEAF=0.5\tGT:AD:DP:GQ:PL\t0/1:5,5:10:99:100,0,100",
+	})
 }
-func (s *SplitAltSuite) TestSplitGT(c *C) {
 
-	in := []interface{}{"1", "2"}
-	out, err := splitGT(in, 3)
+func (s *SplitAltSuite) TestSplitIndependent(c *C) {
+	rdr, err := NewReader(strings.NewReader(splitGATK), false)
 	c.Assert(err, IsNil)
-	c.Assert(out, DeepEquals, []interface{}{".", "."})
+	v := rdr.Read()
+	vars := SplitAlts(v)
+	c.Assert(vars, HasLen, 2)
+	c.Assert(vars[0].Info().Set("DP", 5), IsNil)
+	vars[0].Samples[0].Fields["DP"] = "3"
+	c.Assert(vars[0].Samples[0].GT, DeepEquals, []int{1, -1})
+	c.Assert(vars[0].Samples[0].GL, DeepEquals, []float64{-37, -2, 0})
+
+	dp, _ := vars[1].Info().Get("DP")
+	c.Assert(dp, Equals, 20)
+	c.Assert(vars[1].Samples[0].Fields["DP"], Equals, "17")
+	dp, _ = v.Info().Get("DP")
+	c.Assert(dp, Equals, 20)
+	c.Assert(v.Alt(), DeepEquals, []string{"AT", "ATT"})
+	c.Assert(v.Samples[0].Fields["PL"], Equals, "600,250,230,260,0,240")
+}
 
-	in = []interface{}{"1", "2"}
-	out, err = splitGT(in, 2)
+func (s *SplitAltSuite) TestSplitBadNumber(c *C) {
+	text := strings.Replace(splitGATK, "MLEAC=1,1;", "MLEAC=1;", 1)
+	rdr, err := NewReader(strings.NewReader(text), false)
 	c.Assert(err, IsNil)
-	c.Assert(out, DeepEquals, []interface{}{".", "1"})
-
+	vars, err := rdr.Read().Split()
+	c.Assert(err, ErrorMatches, "chr20:2000 INFO MLEAC .*")
+	c.Assert(vars, HasLen, 2)
+	c.Assert(vars[1].Info().String(), Equals, "AC=1;AF=0.5;AN=2;DP=20;MLEAC=1;MLEAF=0.5;OLD_MULTIALLELIC=chr20:2000:A/AT/ATT")
 }
@@ -0,0 +1,198 @@
+package vcfgo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrSVInterval is returned by SVInterval for a bad END, SVLEN, CIPOS or CIEND.
+var ErrSVInterval = errors.New("vcfgo: bad structural variant interval")
+
+// The reference interval of a record is given by REF unless it has a
+// symbolic ALT allele. Then, as in the VCF specification, INFO/END is the
+// last base of the interval if it is present. Otherwise SVLEN gives the
+// length of a <DEL>, <DUP>, <INV> or <CNV> allele, and of copy number
+// alleles such as <CN0>, starting after the padding base at POS. An
+// insertion, <INS>, replaces only the REF bases, so its SVLEN, the length
+// of the inserted sequence, does not change the interval. Breakends and
+// the * allele also cover only REF.
+
+// SVInterval is the reference interval of a Variant. Start and End are
+// 0-based and half-open, as for Variant.Start and Variant.End, and CIPos
+// and CIEnd are the INFO/CIPOS and INFO/CIEND confidence intervals
+// around POS and END, or 0,0 if they are not set.
+type SVInterval struct {
+	Start, End   uint32
+	CIPos, CIEnd [2]int
+}
+
+// StartInterval returns the 0-based, half-open interval of POS widened
+// by CIPos.
+func (i SVInterval) StartInterval() (uint32, uint32) {
+	return ciBounds(i.Start, i.CIPos)
+}
+
+// EndInterval returns the 0-based, half-open interval of the last base
+// widened by CIEnd.
+func (i SVInterval) EndInterval() (uint32, uint32) {
+	if i.End == 0 {
+		return ciBounds(0, i.CIEnd)
+	}
+	return ciBounds(i.End-1, i.CIEnd)
+}
+
+// ciBounds returns the 0-based, half-open interval of the base at p
+// widened by ci. The start is not less than 0.
+func ciBounds(p uint32, ci [2]int) (uint32, uint32) {
+	s, e := int64(p)+int64(ci[0]), int64(p)+int64(ci[1])+1
+	if s < 0 {
+		s = 0
+	}
+	if e < s {
+		e = s
+	}
+	return uint32(s), uint32(e)
+}
+
+// SVInterval returns the reference interval of the Variant and its
+// confidence intervals. A bad END, SVLEN, CIPOS or CIEND, or a <DEL>,
+// <DUP>, <INV>, <CNV> or copy number allele with neither END nor SVLEN,
+// is an error; the interval is then the one given by REF and any values
+// that could be read. SVInterval does not change the Variant or its
+// Header.
+func (v *Variant) SVInterval() (SVInterval, error) {
+	var iv SVInterval
+	var first error
+	iv.Start = v.Start()
+	iv.End, first = v.svEnd(false)
+	for _, ci := range []struct {
+		key string
+		dst *[2]int
+	}{{`CIPOS`, &iv.CIPos}, {`CIEND`, &iv.CIEnd}} {
+		pair, found, err := v.infoPair(ci.key)
+		if err != nil && first == nil {
+			first = err
+		}
+		if found && err == nil {
+			*ci.dst = pair
+		}
+	}
+	return iv, first
+}
+
+// svEnd returns the 0-based, exclusive end of the Variant. If ins is
+// true the SVLEN of an insertion is added to POS, as End has always done.
+func (v *Variant) svEnd(ins bool) (uint32, error) {
+	refEnd := v.Start() + uint32(len(v.Reference))
+	symbolic, sized := false, false
+	for _, a := range v.Alternate {
+		switch c := ClassifyAllele(v.Reference, a); c.Type {
+		case AlleleSymbolic:
+			symbolic = true
+			if hasSVLength(c.SVType) && (ins || c.SVType != `INS`) {
+				sized = true
+			}
+		case AlleleRefBlock:
+			symbolic = true
+		}
+	}
+	if !symbolic || v.Info_ == nil {
+		return refEnd, nil
+	}
+
+	if vals, found := infoValues(v, `END`); found {
+		end, err := strconv.ParseUint(vals[0], 10, 32)
+		if err != nil || len(vals) != 1 {
+			return refEnd, fmt.Errorf("%w - %s:%d has invalid END %s", ErrSVInterval, v.Chromosome, v.Pos, strings.Join(vals, `,`))
+		}
+		if end < v.Pos {
+			return refEnd, fmt.Errorf("%w - %s:%d has END %d before POS", ErrSVInterval, v.Chromosome, v.Pos, end)
+		}
+		return uint32(end), nil
+	}
+	if !sized {
+		return refEnd, nil
+	}
+
+	lens, err := v.svLengths()
+	if err != nil {
+		return refEnd, err
+	}
+	longest := -1
+	for i, a := range v.Alternate {
+		c := ClassifyAllele(v.Reference, a)
+		if c.Type != AlleleSymbolic || !hasSVLength(c.SVType) || (!ins && c.SVType == `INS`) {
+			continue
+		}
+		if lens != nil && lens[i] > longest {
+			longest = lens[i]
+		}
+	}
+	if longest < 0 {
+		return refEnd, fmt.Errorf("%w - %s:%d has no END or SVLEN", ErrSVInterval, v.Chromosome, v.Pos)
+	}
+	return uint32(v.Pos) + uint32(longest), nil
+}
+
+// svLengths returns the absolute INFO/SVLEN of each ALT allele, with -1
+// for missing values, or nil if SVLEN is not set. Unlike SVLengths it
+// reads the raw value, so does not need SVLEN in the Header.
+func (v *Variant) svLengths() ([]int, error) {
+	vals, found := infoValues(v, `SVLEN`)
+	if !found {
+		return nil, nil
+	}
+	if len(vals) == 1 {
+		for len(vals) < len(v.Alternate) {
+			vals = append(vals, vals[0])
+		}
+	}
+	if len(vals) != len(v.Alternate) {
+		return nil, fmt.Errorf("%w - %s:%d has %d SVLEN values for %d ALT alleles", ErrSVInterval, v.Chromosome, v.Pos, len(vals), len(v.Alternate))
+	}
+	lens := make([]int, len(vals))
+	for i, s := range vals {
+		if s == `.` || s == `` {
+			lens[i] = -1
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w - %s:%d has invalid SVLEN %s", ErrSVInterval, v.Chromosome, v.Pos, s)
+		}
+		if n < 0 {
+			n = -n
+		}
+		lens[i] = n
+	}
+	return lens, nil
+}
+
+// infoPair returns a Number=2 Integer INFO field such as CIPOS.
+func (v *Variant) infoPair(key string) ([2]int, bool, error) {
+	var pair [2]int
+	if v.Info_ == nil {
+		return pair, false, nil
+	}
+	vals, found := infoValues(v, key)
+	if !found {
+		return pair, false, nil
+	}
+	bad := fmt.Errorf("%w - %s:%d has invalid %s %s", ErrSVInterval, v.Chromosome, v.Pos, key, strings.Join(vals, `,`))
+	if len(vals) != 2 {
+		return pair, true, bad
+	}
+	for i, s := range vals {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return pair, true, bad
+		}
+		pair[i] = n
+	}
+	if pair[0] > pair[1] {
+		return pair, true, bad
+	}
+	return pair, true, nil
+}
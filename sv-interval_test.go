@@ -0,0 +1,107 @@
+package vcfgo
+
+import (
+	"errors"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var svIntervalStr = `##fileformat=VCFv4.2
+##INFO=<ID=END,Number=1,Type=Integer,Description="End position of the variant described in this record">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+1	100	snp	A	G	.	PASS	.
+1	100	del	ACG	A	.	PASS	.
+1	100	end	A	<DEL>	.	PASS	END=200;SVLEN=-50
+1	100	svlen	A	<DEL>	.	PASS	SVLEN=-50
+1	100	ins	A	<INS>	.	PASS	SVLEN=300
+1	100	multi	A	<DUP>,<DEL>	.	PASS	SVLEN=30,-60
+1	100	block	A	<*>	.	PASS	END=500
+1	100	bnd	N	N[2:300[	.	PASS	.
+1	100	ci	A	<DEL>	.	PASS	END=200;CIPOS=-10,20;CIEND=-5,5
+1	100	nolen	A	<DEL>	.	PASS	.
+1	100	before	A	<DEL>	.	PASS	END=50
+1	100	badlen	A	<CN0>	.	PASS	SVLEN=x
+1	100	badci	A	<DEL>	.	PASS	END=200;CIPOS=-10,20;CIEND=5
+`
+
+type SVIntervalSuite struct{}
+
+var _ = Suite(&SVIntervalSuite{})
+
+func (s *SVIntervalSuite) TestSVInterval(c *C) {
+	rdr, err := NewReader(strings.NewReader(svIntervalStr), true)
+	c.Assert(err, IsNil)
+	for _, t := range []struct {
+		id           string
+		start, end   uint32
+		ciPos, ciEnd [2]int
+		bad          bool
+	}{
+		{"snp", 99, 100, [2]int{}, [2]int{}, false},
+		{"del", 99, 102, [2]int{}, [2]int{}, false},
+		{"end", 99, 200, [2]int{}, [2]int{}, false},
+		{"svlen", 99, 150, [2]int{}, [2]int{}, false},
+		{"ins", 99, 100, [2]int{}, [2]int{}, false},
+		{"multi", 99, 160, [2]int{}, [2]int{}, false},
+		{"block", 99, 500, [2]int{}, [2]int{}, false},
+		{"bnd", 99, 100, [2]int{}, [2]int{}, false},
+		{"ci", 99, 200, [2]int{-10, 20}, [2]int{-5, 5}, false},
+		{"nolen", 99, 100, [2]int{}, [2]int{}, true},
+		{"before", 99, 100, [2]int{}, [2]int{}, true},
+		{"badlen", 99, 100, [2]int{}, [2]int{}, true},
+		{"badci", 99, 200, [2]int{-10, 20}, [2]int{}, true},
+	} {
+		v := rdr.Read()
+		c.Assert(v, NotNil)
+		c.Assert(v.Id(), Equals, t.id)
+		iv, err := v.SVInterval()
+		if t.bad {
+			c.Check(errors.Is(err, ErrSVInterval), Equals, true, Commentf(t.id))
+		} else {
+			c.Check(err, IsNil, Commentf(t.id))
+		}
+		c.Check(iv, DeepEquals, SVInterval{t.start, t.end, t.ciPos, t.ciEnd}, Commentf(t.id))
+	}
+	_, found := rdr.Header.Infos["SVLEN"]
+	c.Check(found, Equals, false)
+}
+
+func (s *SVIntervalSuite) TestEnd(c *C) {
+	rdr, err := NewReader(strings.NewReader(svIntervalStr), true)
+	c.Assert(err, IsNil)
+	ends := map[string]uint32{}
+	for v := rdr.Read(); v != nil; v = rdr.Read() {
+		ends[v.Id()] = v.End()
+		if v.Id() == "ci" {
+			iv, err := v.SVInterval()
+			c.Assert(err, IsNil)
+			l, r, ok := v.CIPos()
+			c.Check(ok, Equals, true)
+			sl, sr := iv.StartInterval()
+			c.Check([]uint32{l, r}, DeepEquals, []uint32{sl, sr})
+			c.Check([]uint32{l, r}, DeepEquals, []uint32{89, 120})
+			l, r, ok = v.CIEnd()
+			c.Check(ok, Equals, true)
+			el, er := iv.EndInterval()
+			c.Check([]uint32{l, r}, DeepEquals, []uint32{el, er})
+			c.Check([]uint32{l, r}, DeepEquals, []uint32{194, 205})
+		}
+		if v.Id() == "badci" {
+			e := v.End()
+			l, r, ok := v.CIEnd()
+			c.Check(ok, Equals, false)
+			c.Check([]uint32{l, r}, DeepEquals, []uint32{e - 1, e})
+		}
+	}
+	// End keeps adding the SVLEN of an insertion and falls back to REF.
+	c.Check(ends["ins"], Equals, uint32(400))
+	c.Check(ends["nolen"], Equals, uint32(100))
+	c.Check(ends["before"], Equals, uint32(100))
+	c.Check(ends["badlen"], Equals, uint32(100))
+	c.Check(ends["multi"], Equals, uint32(160))
+	for _, k := range []string{"CIPOS", "CIEND"} {
+		_, found := rdr.Header.Infos[k]
+		c.Check(found, Equals, false, Commentf(k))
+	}
+}
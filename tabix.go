@@ -0,0 +1,161 @@
+package vcfgo
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrTabix is returned for an index that is not a valid tabix index.
+var ErrTabix = errors.New("vcfgo: bad tabix index")
+
+// An Index finds where to start reading a BGZF-compressed file for a
+// region. Offset returns the smallest virtual offset of a record that
+// may overlap the 0-based, half-open region or false if no record can.
+// Tabix implements Index; other index formats, such as CSI, can be used
+// by implementing it.
+type Index interface {
+	Offset(chrom string, start, end uint32) (uint64, bool)
+}
+
+// Tabix is a tabix (.tbi) index.
+type Tabix struct {
+	// Names holds the sequence names in the order of the index.
+	Names []string
+
+	Format, ColSeq, ColBeg, ColEnd int32
+	Meta                           byte
+	Skip                           int32
+
+	refs  []tabixRef
+	names map[string]int
+}
+
+type tabixRef struct {
+	bins   map[uint32][]tabixChunk
+	linear []uint64
+}
+
+// tabixChunk fields are exported for binary.Read.
+type tabixChunk struct {
+	Beg, End uint64
+}
+
+// tabix uses 16kb windows for the linear index.
+const tabixShift = 14
+
+// ReadTabix reads a tabix index, which is itself BGZF-compressed.
+func ReadTabix(r io.Reader) (*Tabix, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w - %v", ErrTabix, err)
+	}
+	defer gz.Close()
+	var magic [4]byte
+	if _, err := io.ReadFull(gz, magic[:]); err != nil || string(magic[:]) != "TBI\x01" {
+		return nil, fmt.Errorf("%w - bad magic", ErrTabix)
+	}
+	var head [8]int32
+	if err := binary.Read(gz, binary.LittleEndian, &head); err != nil {
+		return nil, fmt.Errorf("%w - %v", ErrTabix, err)
+	}
+	t := &Tabix{Format: head[1], ColSeq: head[2], ColBeg: head[3], ColEnd: head[4],
+		Meta: byte(head[5]), Skip: head[6], names: make(map[string]int)}
+	nRef := int(head[0])
+	names := make([]byte, head[7])
+	if _, err := io.ReadFull(gz, names); err != nil {
+		return nil, fmt.Errorf("%w - %v", ErrTabix, err)
+	}
+	start := 0
+	for i, c := range names {
+		if c == 0 {
+			t.names[string(names[start:i])] = len(t.Names)
+			t.Names = append(t.Names, string(names[start:i]))
+			start = i + 1
+		}
+	}
+	if len(t.Names) != nRef {
+		return nil, fmt.Errorf("%w - %d names for %d sequences", ErrTabix, len(t.Names), nRef)
+	}
+
+	t.refs = make([]tabixRef, nRef)
+	for i := range t.refs {
+		var nBin int32
+		if err := binary.Read(gz, binary.LittleEndian, &nBin); err != nil {
+			return nil, fmt.Errorf("%w - %v", ErrTabix, err)
+		}
+		ref := tabixRef{bins: make(map[uint32][]tabixChunk, nBin)}
+		for j := int32(0); j < nBin; j++ {
+			var bin struct {
+				Bin    uint32
+				NChunk int32
+			}
+			if err := binary.Read(gz, binary.LittleEndian, &bin); err != nil {
+				return nil, fmt.Errorf("%w - %v", ErrTabix, err)
+			}
+			chunks := make([]tabixChunk, bin.NChunk)
+			if err := binary.Read(gz, binary.LittleEndian, chunks); err != nil {
+				return nil, fmt.Errorf("%w - %v", ErrTabix, err)
+			}
+			ref.bins[bin.Bin] = chunks
+		}
+		var nIntv int32
+		if err := binary.Read(gz, binary.LittleEndian, &nIntv); err != nil {
+			return nil, fmt.Errorf("%w - %v", ErrTabix, err)
+		}
+		ref.linear = make([]uint64, nIntv)
+		if err := binary.Read(gz, binary.LittleEndian, ref.linear); err != nil {
+			return nil, fmt.Errorf("%w - %v", ErrTabix, err)
+		}
+		t.refs[i] = ref
+	}
+	return t, nil
+}
+
+// Offset returns the smallest virtual offset of a record that may
+// overlap the 0-based, half-open region.
+func (t *Tabix) Offset(chrom string, start, end uint32) (uint64, bool) {
+	i, found := t.names[chrom]
+	if !found || end <= start {
+		return 0, false
+	}
+	ref := t.refs[i]
+	var min uint64
+	if w := int(start >> tabixShift); w < len(ref.linear) {
+		min = ref.linear[w]
+	} else if len(ref.linear) > 0 {
+		min = ref.linear[len(ref.linear)-1]
+	}
+	var offsets []uint64
+	for _, bin := range regionBins(start, end) {
+		for _, c := range ref.bins[bin] {
+			if c.End > min {
+				offsets = append(offsets, c.Beg)
+			}
+		}
+	}
+	if len(offsets) == 0 {
+		return 0, false
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	if offsets[0] < min {
+		return min, true
+	}
+	return offsets[0], true
+}
+
+// regionBins returns the bins of the UCSC binning scheme, as used by
+// tabix, that may hold records overlapping the region.
+func regionBins(start, end uint32) []uint32 {
+	end--
+	bins := []uint32{0}
+	for _, l := range []struct{ offset, shift uint32 }{{1, 26}, {9, 23}, {73, 20}, {585, 17}, {4681, 14}} {
+		for k := l.offset + start>>l.shift; k <= l.offset+end>>l.shift; k++ {
+			bins = append(bins, k)
+		}
+	}
+	return bins
+}
@@ -3,7 +3,6 @@ package vcfgo
 import (
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 
@@ -56,131 +55,40 @@ func (v *Variant) Start() uint32 {
 
 // CIPos reports the Left and Right end of an SV using the CIPOS tag. It is in
 // bed format so the end is +1'ed. E.g. If there is not CIPOS, the return value
-// is v.Start(), v.Start() + 1
+// is v.Start(), v.Start() + 1. A bad CIPOS is treated as missing; use
+// SVInterval to see the error.
 func (v *Variant) CIPos() (uint32, uint32, bool) {
-	if v.Header == nil {
-		v.Header = NewHeader()
-	}
 	s := v.Start()
-	v.Header.RLock()
-	if _, ok := v.Header.Infos["CIPOS"]; !ok {
-		v.Header.RUnlock()
-		v.Header.Lock()
-		v.Header.Infos["CIPOS"] = &Info{Id: "CIPOS", Number: "2", Description: "CIPOS", Type: "Integer"}
-		v.Header.Unlock()
-	} else {
-		v.Header.RUnlock()
-	}
-
-	ipair, err := v.Info().Get("CIPOS")
-	if ipair == nil && err != nil {
-		return s, s + 1, false
-	}
-	pair, ok := ipair.([]int)
-	if !ok {
+	pair, found, err := v.infoPair("CIPOS")
+	if !found || err != nil {
 		return s, s + 1, false
 	}
-	left := pair[0]
-	right := pair[1]
-	return uint32(int(s) + left), uint32(int(s) + right + 1), true
+	left, right := ciBounds(s, pair)
+	return left, right, true
 }
 
 // CIEnd reports the Left and Right end of an SV using the CIEND tag. It is in
 // bed format so the end is +1'ed. E.g. If there is no CIEND, the return value
-// is v.End() - 1, v.End()
+// is v.End() - 1, v.End(). A bad CIEND is treated as missing; use
+// SVInterval to see the error.
 func (v *Variant) CIEnd() (uint32, uint32, bool) {
-	if v.Header == nil {
-		v.Header = NewHeader()
-	}
 	e := v.End()
-	v.Header.RLock()
-	if _, ok := v.Header.Infos["CIEND"]; !ok {
-		v.Header.RUnlock()
-		v.Header.Lock()
-		v.Header.Infos["CIEND"] = &Info{Id: "CIEND", Number: "2", Description: "CIEND", Type: "Integer"}
-		v.Header.Unlock()
-	} else {
-		v.Header.RUnlock()
-	}
-	ipair, err := v.Info().Get("CIEND")
-	if ipair == nil && err != nil {
+	pair, found, err := v.infoPair("CIEND")
+	if !found || err != nil {
 		return e - 1, e, false
 	}
-	pair, ok := ipair.([]int)
-	if !ok {
-		return e - 1, e, false
-	}
-	left := pair[0]
-	right := pair[1]
-	return uint32(int(e)+left) - 1, uint32(int(e) + right), true
+	left, right := ciBounds(e-1, pair)
+	return left, right, true
 }
 
-// End returns the 0-based start + the length of the reference allele.
+// End returns the 0-based, exclusive end of the Variant: the 0-based start +
+// the length of the reference allele or, for symbolic alleles, INFO/END or
+// POS + SVLEN. Unlike SVInterval, End adds the SVLEN of an <INS> to POS. If
+// END or SVLEN is bad or missing the length of the reference allele is used;
+// SVInterval returns the error.
 func (v *Variant) End() uint32 {
-	/*
-		if len(v.Alt()[0]) == 0 {
-			log.Println(v.Chrom(), v.Start()+1, v.Ref(), v.Alt())
-		}*/
-	a := v.Alt()[0]
-	if len(a) == 0 || a[0] != '<' {
-		return uint32(v.Pos-1) + uint32(len(v.Ref()))
-	}
-	if strings.HasPrefix(v.Alt()[0], "<DEL") || strings.HasPrefix(v.Alt()[0], "<DUP") || strings.HasPrefix(v.Alt()[0], "<IN") || strings.HasPrefix(v.Alt()[0], "<CN") {
-		if svlen, err := v.Info().Get("SVLEN"); err == nil || (strings.Contains(err.Error(), "not found in header") && svlen != nil) {
-			var slen int
-			err = nil
-			switch svlen.(type) {
-			case int:
-				slen = svlen.(int)
-			case string:
-				var e error
-				if svlen.(string) == "" {
-					return uint32(v.Pos)
-				}
-				slen, e = strconv.Atoi(svlen.(string))
-				if e != nil {
-					log.Fatalf("bad value for svlen: %s\n", svlen)
-				}
-			case float64:
-				slen = int(svlen.(float64))
-			case float32:
-				slen = int(svlen.(float32))
-			case []interface{}:
-				slen = svlen.([]interface{})[0].(int)
-			case interface{}:
-				slen = svlen.(int)
-			default:
-				log.Printf("non int type for SVLEN:%s at %s:%d\n", svlen, v.Chrom(), v.Pos)
-				slen = 1
-			}
-			if slen < 0 {
-				slen = -slen
-			}
-			return uint32(int(v.Pos) + slen)
-
-		} else if end, err := v.Info().Get("END"); err == nil || end != nil {
-			if end == nil || end == "" {
-				if a != "<CN0>" {
-					log.Printf("non int type for END and SVLEN:%s at %s:%d\n", svlen, v.Chrom(), v.Pos)
-				}
-				return uint32(v.Pos + 1)
-			}
-
-			if e, ok := end.(int); ok {
-				return uint32(e)
-			}
-			if s, ok := end.(string); ok {
-				if v, err := strconv.Atoi(s); err == nil {
-					return uint32(v)
-				} else {
-					log.Printf("error parsing INFO/END: %s", err)
-				}
-			}
-		}
-		log.Printf("no svlen for variant %s:%d\n%s\nUsing %d", v.Chromosome, v.Pos, v, v.Pos+1)
-	}
-	// <INS and BND's get handled by this.
-	return uint32(v.Pos-1) + uint32(len(v.Ref()))
+	end, _ := v.svEnd(true)
+	return end
 }
 
 func fmtFloat32(v float32) string {
@@ -257,7 +165,9 @@ func (s *SampleGenotype) AltDepths() ([]int, error) {
 	return vals, nil
 }
 
-// String returns the string representation of the sample field.
+// String returns the string representation of the sample field. Values
+// are written as they are held; if the Header has percent decoding
+// turned on, Variant.String encodes them again.
 func (sg *SampleGenotype) String(fields []string) string {
 	if len(fields) == 0 {
 		return "."
@@ -291,7 +201,11 @@ func (v *Variant) String() string {
 	if len(v.Samples) > 0 {
 		samps := make([]string, len(v.Samples))
 		for i, s := range v.Samples {
-			samps[i] = s.String(v.Format)
+			if v.Header.decoding() {
+				samps[i] = v.Header.encodedSampleString(v.Format, s)
+			} else {
+				samps[i] = s.String(v.Format)
+			}
 		}
 		s += fmt.Sprintf("\t%s\t%s", strings.Join(v.Format, ":"), strings.Join(samps, "\t"))
 	} else if v.sampleString != "" {
@@ -307,11 +221,26 @@ func (v *Variant) GetGenotypeField(g *SampleGenotype, field string, missing inte
 		return missing, fmt.Errorf("GetGenotypeField: empty genotype when requesting %s", field)
 	}
 	h := v.Header
-	format, ok := h.SampleFormats[field]
+	value, ok := g.Fields[field]
+	local := false
 	if !ok {
+		// GT, AD and PL can be computed from local alleles.
+		if lf, found := globalToLocal[field]; found && g.Fields[lf] != `` && g.Fields[`LAA`] != `` {
+			var err error
+			if value, err = g.GlobalValue(field, len(v.Alt())); err != nil {
+				return nil, fmt.Errorf("GetGenotypeField: %v", err)
+			}
+			ok, local = true, true
+		}
+	}
+	format, found := h.SampleFormats[field]
+	if !found && local {
+		d := localAlleleFormats[field]
+		format, found = &SampleFormat{Id: field, Number: d.number, Type: d.typ, Description: d.desc}, true
+	}
+	if !found {
 		return nil, fmt.Errorf("GetGenotypeField: field not found in formats: %s", field)
 	}
-	value, ok := g.Fields[field]
 	if !ok {
 		return nil, fmt.Errorf("GetGenotypeField: field not found in genotypes: %s", field)
 	}
@@ -322,7 +251,7 @@ func (v *Variant) GetGenotypeField(g *SampleGenotype, field string, missing inte
 		if mv, ok = missing.(int); !ok {
 			return nil, fmt.Errorf("GetGenotypeField: bad non-int missing value: %v", missing)
 		}
-		return handleNumberType(format.Number, value, len(v.Alt()), len(g.GT), true, mv)
+		return handleNumberType(format.Number, value, len(v.Alt()), len(g.GT), countValues(g.Fields[`LAA`]), true, mv)
 
 	case "Float":
 		var mv float32
@@ -330,7 +259,7 @@ func (v *Variant) GetGenotypeField(g *SampleGenotype, field string, missing inte
 		if mv, ok = missing.(float32); !ok {
 			return nil, fmt.Errorf("GetGenotypeField: bad non-float missing value: %v", missing)
 		}
-		return handleNumberType(format.Number, value, len(v.Alt()), len(g.GT), false, mv)
+		return handleNumberType(format.Number, value, len(v.Alt()), len(g.GT), countValues(g.Fields[`LAA`]), false, mv)
 
 	case "String", "Character", "Unknown":
 		return value, nil
@@ -343,7 +272,10 @@ func (v *Variant) GetGenotypeField(g *SampleGenotype, field string, missing inte
 	return nil, fmt.Errorf("unknown format: %s", format.Type)
 }
 
-func handleNumberType(number string, value string, nAlts int, nGTs int, isInt bool, mv interface{}) (interface{}, error) {
+// handleNumberType parses a FORMAT value according to its Number. nGTs
+// is the ploidy and nLocalAlts is the number of local ALT alleles in
+// LAA, which are used for the VCFv4.4 Numbers P, LA, LR and LG.
+func handleNumberType(number string, value string, nAlts int, nGTs int, nLocalAlts int, isInt bool, mv interface{}) (interface{}, error) {
 	if number == "1" || !strings.Contains(value, ",") || number == "." || number == "" {
 		if isInt {
 			if value == "" || value == "." {
@@ -356,18 +288,7 @@ func handleNumberType(number string, value string, nAlts int, nGTs int, isInt bo
 		}
 		return strconv.ParseFloat(value, 32)
 	}
-	if count, err := strconv.Atoi(number); err == nil || number == "G" || number == "A" || number == "R" {
-		if err != nil {
-			switch number {
-			case "G":
-				count = nGTs * (nGTs + 1) / 2
-			case "A":
-				count = nAlts
-			case "R":
-				count = nAlts + 1
-			}
-			err = nil
-		}
+	if count, ok := numberCount(number, nAlts, nGTs, nLocalAlts); ok {
 		var ret interface{}
 		split := strings.Split(value, ",")
 		if isInt {
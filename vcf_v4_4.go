@@ -0,0 +1,123 @@
+package vcfgo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VCFv4.4 made several changes that affect how records are read:
+//
+//  - <*> is the unspecified allele (GATK used <NON_REF>)
+//  - SVLEN is Number=A and always a positive length
+//  - INFO/SVCLAIM says whether an SV claim is based on read depth (D),
+//    on the junctions (J) or on both (DJ)
+//  - GT may start with a phasing indicator and may mix | and /
+//  - Number=P and the local-allele Numbers LA, LR and LG (see number.go)
+
+// UnspecifiedAllele is the VCFv4.4 symbolic allele that stands for any
+// allele not listed in ALT.
+const UnspecifiedAllele = `<*>`
+
+// Values of INFO/SVCLAIM.
+const (
+	SVClaimDepth    = `D`
+	SVClaimJunction = `J`
+	SVClaimBoth     = `DJ`
+)
+
+// IsUnspecifiedAllele reports whether an ALT allele is <*> or the
+// equivalent <NON_REF> used by GATK gVCFs.
+func IsUnspecifiedAllele(a string) bool {
+	return a == UnspecifiedAllele || a == `<NON_REF>`
+}
+
+// infoStrings returns the comma-separated values of an INFO field as
+// strings. It returns false if the field is not present.
+func (v *Variant) infoStrings(key string) ([]string, bool) {
+	if v.Info_ == nil {
+		return nil, false
+	}
+	val, _ := v.Info_.Get(key)
+	if val == nil {
+		return nil, false
+	}
+	if b, ok := val.(bool); ok && !b {
+		return nil, false
+	}
+	return strings.Split(ItoS(key, val), `,`), true
+}
+
+// SVClaims returns the INFO/SVCLAIM value for each ALT allele or nil if
+// the field is not present. Missing values are returned as ".".
+func (v *Variant) SVClaims() ([]string, error) {
+	claims, ok := v.infoStrings(`SVCLAIM`)
+	if !ok {
+		return nil, nil
+	}
+	if len(claims) != len(v.Alternate) {
+		return claims, fmt.Errorf("%s:%d has %d SVCLAIM values for %d ALT alleles", v.Chromosome, v.Pos, len(claims), len(v.Alternate))
+	}
+	for _, c := range claims {
+		switch c {
+		case SVClaimDepth, SVClaimJunction, SVClaimBoth, `.`:
+		default:
+			return claims, fmt.Errorf("%s:%d has invalid SVCLAIM value %s", v.Chromosome, v.Pos, c)
+		}
+	}
+	return claims, nil
+}
+
+// SVLengths returns INFO/SVLEN for each ALT allele using the VCFv4.4
+// semantics so lengths are always positive, even in older files where
+// deletions have a negative SVLEN. Missing values are returned as -1.
+// Files older than VCFv4.4 often have a single SVLEN for all ALT
+// alleles so a single value is repeated for every ALT allele.
+func (v *Variant) SVLengths() ([]int, error) {
+	vals, ok := v.infoStrings(`SVLEN`)
+	if !ok {
+		return nil, nil
+	}
+	if len(vals) == 1 && len(v.Alternate) > 1 {
+		for len(vals) < len(v.Alternate) {
+			vals = append(vals, vals[0])
+		}
+	}
+	lens := make([]int, len(vals))
+	for i, s := range vals {
+		if s == `.` || s == `` {
+			lens[i] = -1
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d has invalid SVLEN value %s", v.Chromosome, v.Pos, s)
+		}
+		if n < 0 {
+			n = -n
+		}
+		lens[i] = n
+	}
+	if len(lens) != len(v.Alternate) {
+		return lens, fmt.Errorf("%s:%d has %d SVLEN values for %d ALT alleles", v.Chromosome, v.Pos, len(lens), len(v.Alternate))
+	}
+	return lens, nil
+}
+
+// PhaseSet returns the FORMAT/PS phase set of a sample. It returns
+// false if the genotype is not phased or if PS is missing, in which case
+// the genotype cannot be phased with genotypes in other records.
+func (v *Variant) PhaseSet(g *SampleGenotype) (int, bool) {
+	if g == nil || !g.Phased {
+		return 0, false
+	}
+	ps, found := g.Fields[`PS`]
+	if !found || ps == `.` || ps == `` {
+		return 0, false
+	}
+	n, err := strconv.Atoi(ps)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,153 @@
+package vcfgo
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var VCFv4_4eg = `##fileformat=VCFv4.4
+##INFO=<ID=SVLEN,Number=A,Type=Integer,Description="Length of structural variant">
+##INFO=<ID=SVCLAIM,Number=A,Type=String,Description="Claim made by the structural variant call">
+##INFO=<ID=END,Number=1,Type=Integer,Description="End position">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=PS,Number=1,Type=Integer,Description="Phase set">
+##FORMAT=<ID=PL,Number=G,Type=Integer,Description="Phred-scaled genotype likelihoods">
+##FORMAT=<ID=HQ,Number=P,Type=Integer,Description="Haplotype qualities">
+##FORMAT=<ID=LAA,Number=.,Type=Integer,Description="Local alternate alleles">
+##FORMAT=<ID=LAD,Number=LR,Type=Integer,Description="Local allelic depths">
+##FORMAT=<ID=LPL,Number=LG,Type=Integer,Description="Local phred-scaled genotype likelihoods">
+##FORMAT=<ID=LAF,Number=LA,Type=Float,Description="Local allele frequencies">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2
+1	100	.	A	C,G,<*>	50	PASS	.	GT:PS:PL:HQ:LAA:LAD:LPL:LAF	|0|1:100:0,1,2,3,4,5,6,7,8,9:30,40:1:5,5:0,10,20:0.5	0/2:.:0,1,2,3,4,5,6,7,8,9:30,40:2:5,4:0,10,20:0.4
+1	200	.	A	<DEL>,<DUP>	50	PASS	SVLEN=100,200;SVCLAIM=D,DJ;END=300	GT	0|1/2	1
+`
+
+type V44Suite struct{}
+
+var _ = Suite(&V44Suite{})
+
+func (s *V44Suite) TestNumbers(c *C) {
+	rdr, err := NewReader(strings.NewReader(VCFv4_4eg), false)
+	c.Assert(err, IsNil)
+	c.Assert(rdr.Header.SampleFormats["HQ"].Number, Equals, NumberPloidy)
+	c.Assert(rdr.Header.Infos["SVLEN"].Number, Equals, NumberAltAlleles)
+
+	v := rdr.Read()
+	g := v.Samples[0]
+	for _, t := range []struct {
+		field string
+		exp   interface{}
+	}{
+		{"PL", []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"HQ", []int{30, 40}},
+		{"LAD", []int{5, 5}},
+		{"LPL", []int{0, 10, 20}},
+	} {
+		obs, err := v.GetGenotypeField(g, t.field, -1)
+		c.Assert(err, IsNil)
+		c.Assert(obs, DeepEquals, t.exp)
+	}
+	obs, err := v.GetGenotypeField(g, "LAF", float32(-1))
+	c.Assert(err, IsNil)
+	c.Assert(obs, Equals, 0.5)
+
+	c.Assert(genotypeCount(4, 2), Equals, 10)
+	c.Assert(genotypeCount(2, 3), Equals, 4)
+	c.Assert(genotypeCount(3, 1), Equals, 3)
+	c.Assert(validNumber("LG"), Equals, true)
+	c.Assert(validNumber("X"), Equals, false)
+
+	_, err = NewReader(strings.NewReader("##fileformat=VCFv4.4\n##INFO=<ID=X,Number=Q,Type=Integer,Description=\"x\">\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"), false)
+	c.Assert(err, ErrorMatches, "INFO/X has invalid Number=Q.*")
+}
+
+func (s *V44Suite) TestPhasing(c *C) {
+	rdr, err := NewReader(strings.NewReader(VCFv4_4eg), false)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	c.Assert(v.Samples[0].GT, DeepEquals, []int{0, 1})
+	c.Assert(v.Samples[0].Phased, Equals, true)
+	ps, ok := v.PhaseSet(v.Samples[0])
+	c.Assert(ok, Equals, true)
+	c.Assert(ps, Equals, 100)
+	_, ok = v.PhaseSet(v.Samples[1])
+	c.Assert(ok, Equals, false)
+	c.Assert(IsUnspecifiedAllele(v.Alternate[2]), Equals, true)
+
+	v = rdr.Read()
+	c.Assert(v.Samples[0].GT, DeepEquals, []int{0, 1, 2})
+	c.Assert(v.Samples[0].Phased, Equals, false)
+	c.Assert(v.Samples[1].Phased, Equals, false)
+	c.Assert(v.String(), Equals, "1\t200\t.\tA\t<DEL>,<DUP>\t50.0\tPASS\tSVLEN=100,200;SVCLAIM=D,DJ;END=300\tGT\t0|1/2\t1")
+
+	for gt, phased := range map[string]bool{"/0|1": false, "|0|1": true, "|0/1": false, "/1": false, "|1": true} {
+		g := &SampleGenotype{}
+		c.Assert(rdr.Header.setSampleGT(g, gt), IsNil)
+		c.Check(g.Phased, Equals, phased, Commentf(gt))
+	}
+}
+
+func (s *V44Suite) TestSV(c *C) {
+	rdr, err := NewReader(strings.NewReader(VCFv4_4eg), false)
+	c.Assert(err, IsNil)
+	v := rdr.Read()
+	claims, err := v.SVClaims()
+	c.Assert(err, IsNil)
+	c.Assert(claims, IsNil)
+
+	v = rdr.Read()
+	claims, err = v.SVClaims()
+	c.Assert(err, IsNil)
+	c.Assert(claims, DeepEquals, []string{"D", "DJ"})
+	lens, err := v.SVLengths()
+	c.Assert(err, IsNil)
+	c.Assert(lens, DeepEquals, []int{100, 200})
+	c.Assert(v.End(), Equals, uint32(300))
+
+	// A VCFv4.3 deletion has a negative SVLEN.
+	rdr, err = NewReader(strings.NewReader("##fileformat=VCFv4.3\n##INFO=<ID=SVLEN,Number=.,Type=Integer,Description=\"x\">\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n1\t200\t.\tA\t<DEL>,<DUP>\t.\t.\tSVLEN=-100\n"), false)
+	c.Assert(err, IsNil)
+	v = rdr.Read()
+	lens, err = v.SVLengths()
+	c.Assert(err, IsNil)
+	c.Assert(lens, DeepEquals, []int{100, 100})
+}
+
+func (s *V44Suite) TestWriterVersion(c *C) {
+	rdr, err := NewReader(strings.NewReader(VCFv4_4eg), false)
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	wtr, err := NewWriter(&buf, rdr.Header)
+	c.Assert(err, IsNil)
+	c.Assert(wtr.Error(), IsNil)
+
+	rdr.Header.FileFormat = "4.3"
+	wtr, err = NewWriter(&buf, rdr.Header)
+	c.Assert(err, IsNil)
+	c.Assert(wtr.Error(), ErrorMatches, "(?s)FORMAT/HQ has Number=P which needs VCFv4.4 but header is VCFv4.3.*")
+}
+
+func (s *V44Suite) TestConvert(c *C) {
+	rdr, err := NewReader(strings.NewReader(VCFv4_4eg), true)
+	c.Assert(err, IsNil)
+	vc, err := NewVersionConverter(rdr.Header, "4.3")
+	c.Assert(err, IsNil)
+	c.Assert(vc.Header.SampleFormats["LPL"].Number, Equals, ".")
+	c.Assert(vc.Header.hasLine("INFO", "SVCLAIM"), Equals, false)
+
+	rdr.Read()
+	v := rdr.Read()
+	keep, err := vc.Convert(v)
+	c.Assert(err, IsNil)
+	c.Assert(keep, Equals, true)
+	c.Assert(v.Info().String(), Equals, "SVLEN=-100,200;END=300")
+
+	// And back again.
+	vc, err = NewVersionConverter(vc.Header, "4.4")
+	c.Assert(err, IsNil)
+	_, err = vc.Convert(v)
+	c.Assert(err, IsNil)
+	c.Assert(v.Info().String(), Equals, "SVLEN=100,200;END=300")
+}
@@ -10,84 +10,179 @@ import (
 // Writer allows writing VCF files.
 type Writer struct {
 	io.Writer
-	Header *Header
+	Header  *Header
+	renamer *ContigRenamer
+	verr    *VCFError
 }
 
-// NewWriter returns a writer after writing the header.
+// NewWriter returns a writer after writing the header. The meta-information
+// lines are written in the order given by Header.MetaLineStrings().
 func NewWriter(w io.Writer, h *Header) (*Writer, error) {
+	lines, err := h.MetaLineStrings()
+	if err != nil {
+		return nil, err
+	}
 	fmt.Fprintf(w, "##fileformat=VCFv%s\n", h.FileFormat)
+	for _, s := range lines {
+		fmt.Fprintln(w, s)
+	}
+
+	fmt.Fprint(w, "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT")
+	var s string
+	if len(h.SampleNames) > 0 {
+		s = "\t" + strings.Join(h.SampleNames, "\t")
+	}
 
-	for _, imap := range h.Contigs {
-		fmt.Fprintf(w, "##contig=<ID=%s", imap["ID"])
+	fmt.Fprint(w, s+"\n")
 
-		for k, v := range imap {
-			if k == "ID" {
+	// Fields that need a later version than the header declares are
+	// still written but are reported via Writer.Error().
+	verr := NewVCFError()
+	for _, err := range h.versionErrors() {
+		verr.Add(err, 0)
+	}
+	return &Writer{w, h, nil, verr}, nil
+}
+
+// NewRenamingWriter returns a writer that renames contigs as it writes.
+// The header is written with renamed ##contig lines but the supplied
+// Header is not modified. Variants on unmapped contigs are not written
+// if the renamer Policy is DropUnmapped and are reported via
+// Writer.Error() if it is ErrorUnmapped.
+func NewRenamingWriter(w io.Writer, h *Header, cr *ContigRenamer) (*Writer, error) {
+	nh := h.shallowCopy()
+	for i, m := range nh.Lines {
+		if m.LineKey == `contig` {
+			nh.Lines[i] = m.Clone()
+		}
+	}
+	if err := cr.RenameHeader(nh); err != nil {
+		return nil, err
+	}
+	wtr, err := NewWriter(w, nh)
+	if err != nil {
+		return nil, err
+	}
+	wtr.renamer = cr
+	return wtr, nil
+}
+
+// WriteVariant writes a single variant
+func (w *Writer) WriteVariant(v *Variant) {
+	if w.renamer == nil {
+		fmt.Fprintln(w, v)
+		return
+	}
+	chrom, alts := v.Chromosome, v.Alternate
+	keep, err := w.renamer.RenameVariant(v)
+	if err != nil {
+		w.verr.Add(err, v.LineNumber)
+	}
+	if keep && err == nil {
+		fmt.Fprintln(w, v)
+	}
+	v.Chromosome, v.Alternate = chrom, alts
+}
+
+// Error aggregates any errors that occurred while writing variants.
+func (w *Writer) Error() error {
+	if w.verr.IsEmpty() {
+		return nil
+	}
+	return w.verr
+}
+
+// MetaLineStrings returns the meta-information lines of the Header, other
+// than ##fileformat, as they are written by NewWriter: the lines in
+// Header.Lines in their original order followed by any Contigs, Samples,
+// Pedigree entries, Filters, Infos, SampleFormats and Extras that were
+// added to the Header but do not have a line in Lines.
+func (h *Header) MetaLineStrings() ([]string, error) {
+	var lines []string
+	written := make(map[string]bool)
+	for _, m := range h.Lines {
+		s, err := m.String()
+		if err != nil {
+			return nil, err
+		}
+		written[s] = true
+		lines = append(lines, s)
+	}
+
+	if h.Contigs != nil {
+		for _, c := range h.Contigs.contigs {
+			if h.hasLine(`contig`, c.Name) {
 				continue
 			}
-
-			fmt.Fprintf(w, ",%s=%s", k, v)
+			s, _ := c.MetaLine().String()
+			lines = append(lines, s)
 		}
-		fmt.Fprintln(w, ">")
 	}
 
 	// Samples
 	keys := make([]string, 0, len(h.Samples))
 	for sampleId := range h.Samples {
-		keys = append(keys, sampleId)
+		if !h.hasLine(`SAMPLE`, sampleId) {
+			keys = append(keys, sampleId)
+		}
 	}
 	sort.Strings(keys)
 	for _, sampleId := range keys {
-		fmt.Fprintln(w, h.Samples[sampleId])
+		s, _ := h.Samples[sampleId].MetaLine().String()
+		lines = append(lines, s)
 	}
 
-	for i := range h.Pedigrees {
-		fmt.Fprintln(w, h.Pedigrees[i])
+	if h.Pedigree != nil {
+		for _, e := range h.Pedigree.Entries {
+			if e.ID != `` && h.hasPedigreeLine(e.ID) {
+				continue
+			}
+			s, _ := e.MetaLine().String()
+			if !written[s] {
+				lines = append(lines, s)
+			}
+		}
 	}
 
 	// Filters
 	keys = keys[:0]
 	for k := range h.Filters {
-		keys = append(keys, k)
+		if !h.hasLine(`FILTER`, k) {
+			keys = append(keys, k)
+		}
 	}
 	sort.Strings(keys)
 	for _, k := range keys {
-		fmt.Fprintf(w, "##FILTER=<ID=%s,Description=\"%s\">\n", k, h.Filters[k])
+		lines = append(lines, fmt.Sprintf("##FILTER=<ID=%s,Description=\"%s\">", k, h.Filters[k]))
 	}
 
 	// Infos
 	keys = keys[:0]
 	for k := range h.Infos {
-		keys = append(keys, k)
+		if !h.hasLine(`INFO`, k) {
+			keys = append(keys, k)
+		}
 	}
 	sort.Strings(keys)
 	for _, k := range keys {
-		fmt.Fprintf(w, "%s\n", h.Infos[k])
+		lines = append(lines, h.Infos[k].String())
 	}
 
 	// SampleFormats
 	keys = keys[:0]
 	for k := range h.SampleFormats {
-		keys = append(keys, k)
+		if !h.hasLine(`FORMAT`, k) {
+			keys = append(keys, k)
+		}
 	}
 	sort.Strings(keys)
 	for _, k := range keys {
-		fmt.Fprintf(w, "%s\n", h.SampleFormats[k])
+		lines = append(lines, h.SampleFormats[k].String())
 	}
 	for _, line := range h.Extras {
-		fmt.Fprintf(w, "%s\n", line)
-	}
-
-	fmt.Fprint(w, "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT")
-	var s string
-	if len(h.SampleNames) > 0 {
-		s = "\t" + strings.Join(h.SampleNames, "\t")
+		if !written[line] {
+			lines = append(lines, line)
+		}
 	}
-
-	fmt.Fprint(w, s+"\n")
-	return &Writer{w, h}, nil
-}
-
-// WriteVariant writes a single variant
-func (w *Writer) WriteVariant(v *Variant) {
-	fmt.Fprintln(w, v)
+	return lines, nil
 }